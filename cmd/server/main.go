@@ -1,40 +1,191 @@
-package server
+package main
 
 import (
+	"context"
+	"flag"
 	"github.com/rs/cors"
+	"github.com/sflewis2970/trivia-api/common"
 	"github.com/sflewis2970/trivia-api/config"
 	controllers "github.com/sflewis2970/trivia-api/controllers"
-	"log"
+	"github.com/sflewis2970/trivia-api/handlers"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 )
 
 func main() {
-	// Initialize logging
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	// Log with the default level and format until the config is loaded. The default slog logger is used by
+	// the components created without a logger, and output from the log package, including from the libraries
+	// used, is logged through it as info.
+	logger := common.NewLogger(os.Stderr, config.DEFAULT_LOG_LEVEL, config.DEFAULT_LOG_FORMAT)
+	slog.SetDefault(logger)
+
+	// Command line flags
+	configFile := flag.String("config", "", "path to a JSON or YAML config file, overrides "+config.CONFIG_FILE)
+	printConfig := flag.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+	flag.Parse()
 
 	// Get config data
-	cfgData := config.NewConfig().LoadCfgData()
+	cfgConfig := config.NewConfig()
+	cfgConfig.SetConfigFile(*configFile)
+
+	if *printConfig {
+		writeErr := writeConfig(os.Stdout, cfgConfig)
+		if writeErr != nil {
+			logger.Error("Config is invalid", common.ERROR_FIELD, writeErr)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfgData, cfgErr := cfgConfig.Load()
+	if cfgErr != nil {
+		logger.Error("Error loading config", common.ERROR_FIELD, cfgErr)
+		os.Exit(1)
+	}
+
+	// Structured logging with the level and format set in config
+	logger = common.NewLogger(os.Stderr, cfgData.LogLevel, cfgData.LogFormat)
+	slog.SetDefault(logger)
+
+	os.Exit(run(cfgData, logger))
+}
+
+// writeConfig loads the config and writes it to w with secrets redacted. An invalid config is written too,
+// so it can be checked, and the validation error is returned after it.
+func writeConfig(w io.Writer, cfgConfig *config.Config) error {
+	cfgData, cfgErr := cfgConfig.Load()
 
+	printErr := cfgData.Print(w)
+	if printErr != nil {
+		return printErr
+	}
+
+	return cfgErr
+}
+
+// run serves requests until SIGINT or SIGTERM is received, then drains requests in flight and stops the
+// controller. The exit code is returned: 1 when the service fails to start, 0 otherwise.
+func run(cfgData *config.CfgData, logger *slog.Logger) int {
 	// Create controllers
-	controller := controllers.NewController()
+	controller, controllerErr := controllers.NewController(logger)
+	if controllerErr != nil {
+		logger.Error("Error creating controller", common.ERROR_FIELD, controllerErr)
+		return 1
+	}
 
-	// setup Cors
-	log.Print("Setting up CORS...")
+	// setup Cors, letting clients read the request ID and rate limit headers
+	logger.Info("Setting up CORS")
+	exposedHeaders := []string{handlers.REQUEST_ID_HEADER, handlers.RATE_LIMIT_LIMIT_HEADER,
+		handlers.RATE_LIMIT_REMAINING_HEADER, handlers.RATE_LIMIT_RESET_HEADER, handlers.RETRY_AFTER_HEADER}
 	corsOptionsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{http.MethodPost, http.MethodGet},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   exposedHeaders,
 		AllowCredentials: false,
 	})
 	corsHandler := corsOptionsHandler.Handler(controller.Router)
 
 	// Server Address info
-	addr := cfgData.Host + ":" + cfgData.Port
-	log.Print("The address used by the service is: ", addr)
+	addr := cfgData.Host + ":" + strconv.Itoa(cfgData.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      corsHandler,
+		ReadTimeout:  cfgData.ServerReadTimeout,
+		WriteTimeout: cfgData.ServerWriteTimeout,
+		IdleTimeout:  cfgData.ServerIdleTimeout,
+	}
 
-	// Start Server
-	log.Print("Web service server is ready...")
+	// Stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Listen and Serve
-	log.Fatal(http.ListenAndServe(addr, corsHandler))
+	logger.Info("Starting web service server", "address", addr)
+	serveErrs := make(chan error, 1)
+	go func() {
+		serveErrs <- server.ListenAndServe()
+	}()
+
+	// The service is not ready until its dependencies respond
+	startupErrs := make(chan error, 1)
+	go func() {
+		startupErrs <- waitForDependencies(ctx, controller, cfgData.StartupTimeout, logger)
+	}()
+
+	exitCode := 0
+	for running := true; running; {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutdown signal received")
+			running = false
+		case serveErr := <-serveErrs:
+			logger.Error("Web service server failed", common.ERROR_FIELD, serveErr)
+			exitCode = 1
+			running = false
+		case startupErr := <-startupErrs:
+			if startupErr != nil {
+				logger.Error("Dependencies did not respond before the startup timeout", "startuptimeout",
+					cfgData.StartupTimeout)
+				exitCode = 1
+				running = false
+			}
+		}
+	}
+
+	// A second signal stops the service without waiting
+	stop()
+
+	shutdown(server, controller, cfgData.ShutdownTimeout, logger)
+
+	return exitCode
+}
+
+// shutdown stops accepting requests and waits up to shutdownTimeout for requests in flight to finish,
+// then stops the background workers and closes the data stores
+func shutdown(server *http.Server, controller *controllers.Controller, shutdownTimeout time.Duration,
+	logger *slog.Logger) {
+	logger.Info("Draining requests in flight", "shutdowntimeout", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	shutdownErr := server.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		logger.Error("Requests in flight did not finish, closing connections", common.ERROR_FIELD, shutdownErr)
+		_ = server.Close()
+	}
+
+	closeErr := controller.Close()
+	if closeErr != nil {
+		logger.Error("Error stopping controller", common.ERROR_FIELD, closeErr)
+	}
+
+	logger.Info("Web service server stopped")
+}
+
+// waitForDependencies waits up to startupTimeout for the dependencies of the service to respond, 0 waits
+// forever
+func waitForDependencies(ctx context.Context, controller *controllers.Controller, startupTimeout time.Duration,
+	logger *slog.Logger) error {
+	if startupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, startupTimeout)
+		defer cancel()
+	}
+
+	logger.Info("Waiting for dependencies")
+	waitErr := controller.WaitForDependencies(ctx)
+	if waitErr != nil {
+		return waitErr
+	}
+
+	logger.Info("Dependencies are ready")
+
+	return nil
 }
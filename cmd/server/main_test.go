@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/sflewis2970/trivia-api/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestConfig writes a config file holding settings and returns its path
+func writeTestConfig(t *testing.T, settings map[string]string) string {
+	byteStream, marshalErr := json.Marshal(settings)
+	if marshalErr != nil {
+		t.Fatalf("json.Marshal() error = %v", marshalErr)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if writeErr := os.WriteFile(configFile, byteStream, 0o600); writeErr != nil {
+		t.Fatalf("os.WriteFile() error = %v", writeErr)
+	}
+
+	return configFile
+}
+
+func TestWriteConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "valid config",
+			settings: map[string]string{"storetype": "memory", "rapidapikey": "secret-key"},
+			want:     map[string]string{"storetype": "memory", "rapidapikey": "********", "hostport": "8080"},
+		},
+		{
+			name:     "invalid config",
+			settings: map[string]string{"storetype": "paper", "rapidapikey": "secret-key"},
+			want:     map[string]string{"storetype": "paper"},
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfgConfig := new(config.Config)
+			cfgConfig.SetConfigFile(writeTestConfig(t, test.settings))
+
+			var output bytes.Buffer
+			writeErr := writeConfig(&output, cfgConfig)
+
+			var validationErr *config.ValidationError
+			if test.wantErr && !errors.As(writeErr, &validationErr) {
+				t.Errorf("writeConfig() error = %v, want a validation error", writeErr)
+			} else if !test.wantErr && writeErr != nil {
+				t.Errorf("writeConfig() error = %v", writeErr)
+			}
+
+			// The config is written even when it is invalid
+			var values map[string]string
+			if decodeErr := json.Unmarshal(output.Bytes(), &values); decodeErr != nil {
+				t.Fatalf("output is not JSON: %v", decodeErr)
+			}
+			for key, want := range test.want {
+				if values[key] != want {
+					t.Errorf("%s = %q, want %q", key, values[key], want)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,8 @@
 package common
 
 import (
+	"context"
 	"io"
-	"log"
 	"math/rand"
 	"net/http"
 	"os"
@@ -20,11 +20,10 @@ func GetFormattedTime(timeNow time.Time, timeFormat string) string {
 	return timeNow.Format(timeFormat)
 }
 
-// GetWorkingDir Get working directory
+// GetWorkingDir Get working directory, errors are left to the caller to log
 func GetWorkingDir() (string, error) {
 	workingDir, getErr := os.Getwd()
 	if getErr != nil {
-		log.Print("Error getting working directory...")
 		return "", getErr
 	}
 
@@ -56,11 +55,12 @@ func ShuffleList(strList []string) []string {
 	return strList
 }
 
-func CreateRequest(method string, url string, headers []HTTPHeader, httpBody io.Reader) (*http.Request, error) {
+// CreateRequest creates a http request that is cancelled when ctx is done
+func CreateRequest(ctx context.Context, method string, url string, headers []HTTPHeader, httpBody io.Reader) (*http.Request, error) {
 	// Create new http request
-	request, requestErr := http.NewRequest(method, url, httpBody)
+	request, requestErr := http.NewRequestWithContext(ctx, method, url, httpBody)
 	if requestErr != nil {
-		log.Print("A request error has occurred...")
+		LoggerFromContext(ctx, nil).Error("A request error has occurred", ERROR_FIELD, requestErr)
 		return nil, requestErr
 	}
 
@@ -71,14 +71,3 @@ func CreateRequest(method string, url string, headers []HTTPHeader, httpBody io.
 
 	return request, nil
 }
-
-func ExecuteRequest(request *http.Request) (*http.Response, error) {
-	// Get response from http request
-	response, responseErr := http.DefaultClient.Do(request)
-	if responseErr != nil {
-		log.Print("A response error has occurred...")
-		return nil, responseErr
-	}
-
-	return response, nil
-}
@@ -0,0 +1,262 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults used for HTTP client settings that are not set
+const (
+	DEFAULT_REQUEST_TIMEOUT time.Duration = 5 * time.Second
+	DEFAULT_MAX_BACKOFF     time.Duration = 2 * time.Second
+)
+
+// UpstreamError is returned when a request to an upstream service fails after every attempt.
+// Timeout is set when the last attempt failed because the request or the caller's context timed out.
+type UpstreamError struct {
+	URL        string
+	StatusCode int
+	Attempts   int
+	Timeout    bool
+	Err        error
+}
+
+func (ue *UpstreamError) Error() string {
+	if ue.StatusCode > 0 {
+		return fmt.Sprintf("upstream request failed after %d attempt(s): %s returned status %d", ue.Attempts,
+			ue.URL, ue.StatusCode)
+	}
+
+	return fmt.Sprintf("upstream request failed after %d attempt(s): %v", ue.Attempts, ue.Err)
+}
+
+func (ue *UpstreamError) Unwrap() error {
+	return ue.Err
+}
+
+// IsUpstreamError reports whether err is, or wraps, an *UpstreamError
+func IsUpstreamError(err error) bool {
+	var upstreamErr *UpstreamError
+	return errors.As(err, &upstreamErr)
+}
+
+// IsUpstreamTimeout reports whether err is, or wraps, an *UpstreamError caused by a timeout
+func IsUpstreamTimeout(err error) bool {
+	var upstreamErr *UpstreamError
+	return errors.As(err, &upstreamErr) && upstreamErr.Timeout
+}
+
+// HTTPClientConfig holds the settings of a HTTP client
+type HTTPClientConfig struct {
+	// Timeout limits each attempt
+	Timeout time.Duration
+
+	// MaxRetries is the number of times a failed request is retried
+	MaxRetries int
+
+	// Backoff is the wait before the first retry. The wait doubles for each retry, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// HTTPClient sends requests to upstream services. Each attempt has a timeout, and requests that fail with
+// a network error, a timeout, a 429 or a 5xx status are retried with exponential backoff and jitter.
+// Requests stop as soon as the caller's context is done.
+type HTTPClient struct {
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	randomMutex sync.Mutex
+	random      *rand.Rand
+}
+
+// Get sends a GET request and returns the response body. An *UpstreamError is returned when every
+// attempt fails.
+func (hc *HTTPClient) Get(ctx context.Context, url string, headers []HTTPHeader) ([]byte, error) {
+	logger := LoggerFromContext(ctx, nil)
+	var lastErr error
+	statusCode := 0
+
+	for attempt := 0; attempt <= hc.maxRetries; attempt++ {
+		if attempt > 0 {
+			wait := hc.retryWait(attempt, lastErr)
+			logger.Info("Retrying upstream request", "wait", wait, "attempt", attempt+1, "attempts", hc.maxRetries+1)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, hc.upstreamError(url, statusCode, attempt, ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		var body []byte
+		var retry bool
+		body, statusCode, retry, lastErr = hc.attempt(ctx, url, headers)
+		if lastErr == nil {
+			return body, nil
+		}
+
+		logger.Warn("Upstream request failed", ERROR_FIELD, lastErr)
+		if !retry || ctx.Err() != nil {
+			return nil, hc.upstreamError(url, statusCode, attempt+1, lastErr)
+		}
+	}
+
+	return nil, hc.upstreamError(url, statusCode, hc.maxRetries+1, lastErr)
+}
+
+// Ping checks that the upstream service at url is reachable, sending a single HEAD request without retries.
+// Any response counts, whatever its status, since the service answered. An *UpstreamError is returned when
+// the request fails.
+func (hc *HTTPClient) Ping(ctx context.Context, url string) error {
+	request, requestErr := CreateRequest(ctx, http.MethodHead, url, nil, nil)
+	if requestErr != nil {
+		return requestErr
+	}
+
+	response, responseErr := hc.client.Do(request)
+	if responseErr != nil {
+		return hc.upstreamError(url, 0, 1, responseErr)
+	}
+
+	closeErr := response.Body.Close()
+	if closeErr != nil {
+		LoggerFromContext(ctx, nil).Warn("Error closing response body", ERROR_FIELD, closeErr)
+	}
+
+	return nil
+}
+
+// unexported type methods
+// attempt sends a single request. retry reports whether a failed request may succeed when sent again.
+func (hc *HTTPClient) attempt(ctx context.Context, url string, headers []HTTPHeader) ([]byte, int, bool, error) {
+	request, requestErr := CreateRequest(ctx, http.MethodGet, url, headers, nil)
+	if requestErr != nil {
+		return nil, 0, false, requestErr
+	}
+
+	response, responseErr := hc.client.Do(request)
+	if responseErr != nil {
+		return nil, 0, true, responseErr
+	}
+	defer func(Body io.ReadCloser) {
+		closeErr := Body.Close()
+		if closeErr != nil {
+			LoggerFromContext(ctx, nil).Warn("Error closing response body", ERROR_FIELD, closeErr)
+		}
+	}(response.Body)
+
+	body, readErr := io.ReadAll(response.Body)
+	if readErr != nil {
+		return nil, response.StatusCode, true, readErr
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		statusErr := &statusError{statusCode: response.StatusCode, retryAfter: response.Header.Get("Retry-After")}
+		retry := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+		return nil, response.StatusCode, retry, statusErr
+	}
+
+	return body, response.StatusCode, false, nil
+}
+
+// retryWait returns how long to wait before a retry: the backoff for the attempt with up to half of it
+// taken off at random, or the wait asked for by a 429 or 503 response when that is longer
+func (hc *HTTPClient) retryWait(attempt int, lastErr error) time.Duration {
+	var backoff time.Duration
+	if hc.backoff > 0 {
+		// A backoff that overflowed is capped as well
+		backoff = hc.backoff << (attempt - 1)
+		if backoff > hc.maxBackoff || backoff <= 0 {
+			backoff = hc.maxBackoff
+		}
+	}
+
+	wait := backoff
+	if half := int64(backoff / 2); half > 0 {
+		hc.randomMutex.Lock()
+		wait = backoff - time.Duration(hc.random.Int63n(half+1))
+		hc.randomMutex.Unlock()
+	}
+
+	var statusErr *statusError
+	if errors.As(lastErr, &statusErr) {
+		seconds, convErr := strconv.Atoi(statusErr.retryAfter)
+		if convErr == nil && time.Duration(seconds)*time.Second > wait {
+			wait = time.Duration(seconds) * time.Second
+			if wait > hc.maxBackoff {
+				wait = hc.maxBackoff
+			}
+		}
+	}
+
+	return wait
+}
+
+func (hc *HTTPClient) upstreamError(url string, statusCode int, attempts int, err error) *UpstreamError {
+	return &UpstreamError{
+		URL:        url,
+		StatusCode: statusCode,
+		Attempts:   attempts,
+		Timeout:    isTimeout(err),
+		Err:        err,
+	}
+}
+
+// NewHTTPClient creates a HTTP client. A timeout or maximum backoff that is not set uses the default.
+func NewHTTPClient(clientConfig HTTPClientConfig) *HTTPClient {
+	httpClient := new(HTTPClient)
+
+	timeout := clientConfig.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_REQUEST_TIMEOUT
+	}
+	httpClient.client = &http.Client{Timeout: timeout}
+
+	httpClient.maxRetries = clientConfig.MaxRetries
+	if httpClient.maxRetries < 0 {
+		httpClient.maxRetries = 0
+	}
+
+	httpClient.backoff = clientConfig.Backoff
+	httpClient.maxBackoff = clientConfig.MaxBackoff
+	if httpClient.maxBackoff <= 0 {
+		httpClient.maxBackoff = DEFAULT_MAX_BACKOFF
+	}
+
+	httpClient.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	return httpClient
+}
+
+// statusError is the error for a response with a status other than 2xx
+type statusError struct {
+	statusCode int
+	retryAfter string
+}
+
+func (se *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", se.statusCode, http.StatusText(se.statusCode))
+}
+
+// unexported functions
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
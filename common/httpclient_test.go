@@ -0,0 +1,153 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestUpstream serves responses with the given statuses in turn, repeating the last one, and counts
+// the requests it gets
+func newTestUpstream(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		request := int(atomic.AddInt32(&requests, 1))
+		if request > len(statuses) {
+			request = len(statuses)
+		}
+		rw.WriteHeader(statuses[request-1])
+		rw.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestGetRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int32
+		wantStatus   int
+	}{
+		{"success", []int{http.StatusOK}, 1, 0},
+		{"success after retries", []int{http.StatusInternalServerError, http.StatusServiceUnavailable,
+			http.StatusOK}, 3, 0},
+		{"server errors", []int{http.StatusInternalServerError}, 3, http.StatusInternalServerError},
+		{"too many requests", []int{http.StatusTooManyRequests}, 3, http.StatusTooManyRequests},
+		{"bad request", []int{http.StatusBadRequest}, 1, http.StatusBadRequest},
+		{"not found", []int{http.StatusNotFound}, 1, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := newTestUpstream(t, test.statuses...)
+			httpClient := NewHTTPClient(HTTPClientConfig{MaxRetries: 2, Backoff: time.Millisecond})
+
+			body, getErr := httpClient.Get(context.Background(), server.URL, nil)
+			if got := atomic.LoadInt32(requests); got != test.wantRequests {
+				t.Errorf("requests = %d, want %d", got, test.wantRequests)
+			}
+
+			if test.wantStatus == 0 {
+				if getErr != nil || string(body) != "ok" {
+					t.Errorf("Get() = %q, %v, want %q", body, getErr, "ok")
+				}
+				return
+			}
+
+			var upstreamErr *UpstreamError
+			if !errors.As(getErr, &upstreamErr) {
+				t.Fatalf("Get() error = %v, want an *UpstreamError", getErr)
+			}
+			if upstreamErr.StatusCode != test.wantStatus || upstreamErr.Attempts != int(test.wantRequests) {
+				t.Errorf("Get() error status, attempts = %d, %d, want %d, %d", upstreamErr.StatusCode,
+					upstreamErr.Attempts, test.wantStatus, test.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryWaitBound(t *testing.T) {
+	httpClient := NewHTTPClient(HTTPClientConfig{Backoff: 100 * time.Millisecond,
+		MaxBackoff: 400 * time.Millisecond})
+
+	tests := []struct {
+		attempt     int
+		wantBackoff time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 400 * time.Millisecond},
+		{70, 400 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		// Jitter takes up to half of the backoff off the wait
+		for idx := 0; idx < 100; idx++ {
+			wait := httpClient.retryWait(test.attempt, nil)
+			if wait < test.wantBackoff/2 || wait > test.wantBackoff {
+				t.Fatalf("retryWait(%d) = %v, want between %v and %v", test.attempt, wait, test.wantBackoff/2,
+					test.wantBackoff)
+			}
+		}
+	}
+
+	// The wait asked for by the upstream service is capped as well
+	statusErr := &statusError{statusCode: http.StatusTooManyRequests, retryAfter: "60"}
+	if wait := httpClient.retryWait(1, statusErr); wait != 400*time.Millisecond {
+		t.Errorf("retryWait(Retry-After 60) = %v, want %v", wait, 400*time.Millisecond)
+	}
+}
+
+func TestGetTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	httpClient := NewHTTPClient(HTTPClientConfig{Timeout: 20 * time.Millisecond})
+	_, getErr := httpClient.Get(context.Background(), server.URL, nil)
+	if !IsUpstreamTimeout(getErr) {
+		t.Errorf("Get() error = %v, want an upstream timeout", getErr)
+	}
+}
+
+func TestGetContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The request is cancelled while waiting to retry
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		cancel()
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	httpClient := NewHTTPClient(HTTPClientConfig{MaxRetries: 3, Backoff: time.Minute, MaxBackoff: time.Minute})
+	started := time.Now()
+	_, getErr := httpClient.Get(ctx, server.URL, nil)
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("Get() returned after %v, want it to stop when the context is cancelled", elapsed)
+	}
+
+	var upstreamErr *UpstreamError
+	if !errors.As(getErr, &upstreamErr) || !errors.Is(getErr, context.Canceled) {
+		t.Fatalf("Get() error = %v, want an *UpstreamError for %v", getErr, context.Canceled)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 || upstreamErr.Attempts != 1 {
+		t.Errorf("requests, attempts = %d, %d, want 1, 1", got, upstreamErr.Attempts)
+	}
+}
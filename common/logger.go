@@ -0,0 +1,70 @@
+package common
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// Log levels, from most to least verbose
+const (
+	DEBUG_LEVEL string = "debug"
+	INFO_LEVEL  string = "info"
+	WARN_LEVEL  string = "warn"
+	ERROR_LEVEL string = "error"
+)
+
+// Log formats
+const (
+	TEXT_FORMAT string = "text"
+	JSON_FORMAT string = "json"
+)
+
+// Keys of the fields logged for requests
+const (
+	REQUEST_ID_FIELD  string = "request_id"
+	QUESTION_ID_FIELD string = "question_id"
+	CATEGORY_FIELD    string = "category"
+	STORE_FIELD       string = "store"
+	ERROR_FIELD       string = "error"
+	GAME_ID_FIELD     string = "game_id"
+	PLAYER_ID_FIELD   string = "player_id"
+)
+
+// loggerContextKey is the context key holding a request's logger
+type loggerContextKey struct{}
+
+// NewLogger creates a slog logger writing lines at level and above to writer, as JSON objects in the json
+// format and as key=value text otherwise. An unknown level logs at info level.
+func NewLogger(writer io.Writer, level string, format string) *slog.Logger {
+	var logLevel slog.Level
+	if unmarshalErr := logLevel.UnmarshalText([]byte(level)); unmarshalErr != nil {
+		logLevel = slog.LevelInfo
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	if format == JSON_FORMAT {
+		return slog.New(slog.NewJSONHandler(writer, options))
+	}
+
+	return slog.New(slog.NewTextHandler(writer, options))
+}
+
+// ContextWithLogger returns a copy of ctx holding logger, to be used for everything logged for the request
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the logger held by ctx. When ctx holds none, fallback is returned, or the
+// default slog logger when fallback is nil.
+func LoggerFromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, found := ctx.Value(loggerContextKey{}).(*slog.Logger); found && logger != nil {
+		return logger
+	}
+
+	if fallback != nil {
+		return fallback
+	}
+
+	return slog.Default()
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerFormat(t *testing.T) {
+	var jsonOutput bytes.Buffer
+	NewLogger(&jsonOutput, INFO_LEVEL, JSON_FORMAT).Info("Question served", QUESTION_ID_FIELD, "question-1")
+
+	var line map[string]any
+	if decodeErr := json.Unmarshal(jsonOutput.Bytes(), &line); decodeErr != nil {
+		t.Fatalf("json line %q error = %v", jsonOutput.String(), decodeErr)
+	}
+	if line["level"] != "INFO" || line["msg"] != "Question served" || line[QUESTION_ID_FIELD] != "question-1" {
+		t.Errorf("json line = %v, want an info line for question-1", line)
+	}
+
+	var textOutput bytes.Buffer
+	NewLogger(&textOutput, INFO_LEVEL, TEXT_FORMAT).Info("Question served", QUESTION_ID_FIELD, "question-1")
+	for _, want := range []string{"level=INFO", `msg="Question served"`, "question_id=question-1"} {
+		if !strings.Contains(textOutput.String(), want) {
+			t.Errorf("text line = %q, want %q in it", textOutput.String(), want)
+		}
+	}
+}
+
+func TestNewLoggerLevel(t *testing.T) {
+	tests := []struct {
+		level     string
+		wantDebug bool
+		wantInfo  bool
+		wantWarn  bool
+	}{
+		{DEBUG_LEVEL, true, true, true},
+		{INFO_LEVEL, false, true, true},
+		{WARN_LEVEL, false, false, true},
+		{ERROR_LEVEL, false, false, false},
+		{"verbose", false, true, true},
+	}
+
+	for _, test := range tests {
+		logger := NewLogger(io.Discard, test.level, JSON_FORMAT)
+		ctx := context.Background()
+		debug, info, warn := logger.Enabled(ctx, slog.LevelDebug), logger.Enabled(ctx, slog.LevelInfo),
+			logger.Enabled(ctx, slog.LevelWarn)
+		if debug != test.wantDebug || info != test.wantInfo || warn != test.wantWarn {
+			t.Errorf("NewLogger(%q) debug, info, warn = %t, %t, %t, want %t, %t, %t", test.level, debug, info, warn,
+				test.wantDebug, test.wantInfo, test.wantWarn)
+		}
+	}
+}
+
+func TestLoggerFromContext(t *testing.T) {
+	requestLogger := NewLogger(io.Discard, INFO_LEVEL, JSON_FORMAT)
+	fallback := NewLogger(io.Discard, INFO_LEVEL, JSON_FORMAT)
+	requestCtx := ContextWithLogger(context.Background(), requestLogger)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		fallback *slog.Logger
+		want     *slog.Logger
+	}{
+		{"request logger", requestCtx, fallback, requestLogger},
+		{"fallback", context.Background(), fallback, fallback},
+		{"default", context.Background(), nil, slog.Default()},
+	}
+
+	for _, test := range tests {
+		if got := LoggerFromContext(test.ctx, test.fallback); got != test.want {
+			t.Errorf("LoggerFromContext(%s) = %p, want %p", test.name, got, test.want)
+		}
+	}
+}
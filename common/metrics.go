@@ -0,0 +1,298 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// METRICS_CONTENT_TYPE is the content type of the Prometheus text exposition format
+const METRICS_CONTENT_TYPE string = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types
+const (
+	COUNTER_TYPE   string = "counter"
+	GAUGE_TYPE     string = "gauge"
+	HISTOGRAM_TYPE string = "histogram"
+)
+
+// LATENCY_BUCKETS are the histogram buckets, in seconds, used for latencies
+var LATENCY_BUCKETS = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics is the registry the service's metrics are kept in
+var Metrics = NewMetricsRegistry()
+
+// metric is a metric family written by a registry
+type metric interface {
+	write(writer *bufio.Writer)
+}
+
+// MetricsRegistry keeps metrics and writes them in the Prometheus text exposition format.
+// A registry is safe for concurrent use.
+type MetricsRegistry struct {
+	mutex   sync.Mutex
+	metrics map[string]metric
+}
+
+// NewCounterVec adds a counter with the label names to the registry
+func (mr *MetricsRegistry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	counterVec := &CounterVec{series: newSeries(name, help, labelNames)}
+	counterVec.values = make(map[string]*float64)
+	mr.register(name, counterVec)
+
+	return counterVec
+}
+
+// NewHistogramVec adds a histogram with the buckets and label names to the registry
+func (mr *MetricsRegistry) NewHistogramVec(name string, help string, buckets []float64,
+	labelNames ...string) *HistogramVec {
+	histogramVec := &HistogramVec{series: newSeries(name, help, labelNames)}
+	histogramVec.buckets = append([]float64(nil), buckets...)
+	sort.Float64s(histogramVec.buckets)
+	histogramVec.values = make(map[string]*histogramValue)
+	mr.register(name, histogramVec)
+
+	return histogramVec
+}
+
+// SetFunc adds a metric of metricType whose value is read from valueFn when the metrics are written.
+// A metric already registered with the name is replaced.
+func (mr *MetricsRegistry) SetFunc(name string, help string, metricType string, valueFn func() float64) {
+	mr.register(name, &funcMetric{name: name, help: help, metricType: metricType, valueFn: valueFn})
+}
+
+// WriteText writes every metric in the Prometheus text exposition format, ordered by name
+func (mr *MetricsRegistry) WriteText(writer io.Writer) error {
+	mr.mutex.Lock()
+	names := make([]string, 0, len(mr.metrics))
+	for name := range mr.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]metric, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, mr.metrics[name])
+	}
+	mr.mutex.Unlock()
+
+	bufWriter := bufio.NewWriter(writer)
+	for _, m := range metrics {
+		m.write(bufWriter)
+	}
+
+	return bufWriter.Flush()
+}
+
+// unexported type methods
+func (mr *MetricsRegistry) register(name string, m metric) {
+	mr.mutex.Lock()
+	defer mr.mutex.Unlock()
+
+	mr.metrics[name] = m
+}
+
+// series holds what counters and histograms have in common
+type series struct {
+	name       string
+	help       string
+	labelNames []string
+	mutex      sync.Mutex
+	labels     map[string][]string
+}
+
+// seriesKey returns the key of the series with the label values. The label values must match the label names.
+func (s *series) seriesKey(labelValues []string) string {
+	if len(labelValues) != len(s.labelNames) {
+		panic(fmt.Sprintf("metric %s has %d labels, %d label values given", s.name, len(s.labelNames),
+			len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	if _, found := s.labels[key]; !found {
+		s.labels[key] = append([]string(nil), labelValues...)
+	}
+
+	return key
+}
+
+// sortedKeys returns the keys of every series, ordered by label values
+func (s *series) sortedKeys() []string {
+	keys := make([]string, 0, len(s.labels))
+	for key := range s.labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// labelText returns the labels of the series with key, along with any extra label
+func (s *series) labelText(key string, extraName string, extraValue string) string {
+	var pairs []string
+	for idx, labelValue := range s.labels[key] {
+		pairs = append(pairs, s.labelNames[idx]+`="`+escapeLabelValue(labelValue)+`"`)
+	}
+	if len(extraName) > 0 {
+		pairs = append(pairs, extraName+`="`+escapeLabelValue(extraValue)+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a counter for each combination of label values
+type CounterVec struct {
+	*series
+	values map[string]*float64
+}
+
+// Inc adds one to the counter with the label values
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+// Add adds value to the counter with the label values. Counters only go up, negative values are ignored.
+func (cv *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+
+	cv.mutex.Lock()
+	defer cv.mutex.Unlock()
+
+	key := cv.seriesKey(labelValues)
+	if _, found := cv.values[key]; !found {
+		cv.values[key] = new(float64)
+	}
+	*cv.values[key] += value
+}
+
+func (cv *CounterVec) write(writer *bufio.Writer) {
+	cv.mutex.Lock()
+	defer cv.mutex.Unlock()
+
+	writeHeader(writer, cv.name, cv.help, COUNTER_TYPE)
+	for _, key := range cv.sortedKeys() {
+		writeSample(writer, cv.name, cv.labelText(key, "", ""), *cv.values[key])
+	}
+}
+
+// histogramValue holds the observations of one series of a histogram
+type histogramValue struct {
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+// HistogramVec is a histogram for each combination of label values
+type HistogramVec struct {
+	*series
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+// Observe adds value to the histogram with the label values
+func (hv *HistogramVec) Observe(value float64, labelValues ...string) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	key := hv.seriesKey(labelValues)
+	hValue, found := hv.values[key]
+	if !found {
+		hValue = &histogramValue{bucketCounts: make([]uint64, len(hv.buckets))}
+		hv.values[key] = hValue
+	}
+
+	for idx, upperBound := range hv.buckets {
+		if value <= upperBound {
+			hValue.bucketCounts[idx]++
+		}
+	}
+	hValue.count++
+	hValue.sum += value
+}
+
+// ObserveDuration adds the seconds elapsed since started to the histogram with the label values
+func (hv *HistogramVec) ObserveDuration(started time.Time, labelValues ...string) {
+	hv.Observe(time.Since(started).Seconds(), labelValues...)
+}
+
+func (hv *HistogramVec) write(writer *bufio.Writer) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	writeHeader(writer, hv.name, hv.help, HISTOGRAM_TYPE)
+	for _, key := range hv.sortedKeys() {
+		hValue := hv.values[key]
+		for idx, upperBound := range hv.buckets {
+			writeSample(writer, hv.name+"_bucket", hv.labelText(key, "le", formatFloat(upperBound)),
+				float64(hValue.bucketCounts[idx]))
+		}
+		writeSample(writer, hv.name+"_bucket", hv.labelText(key, "le", "+Inf"), float64(hValue.count))
+		writeSample(writer, hv.name+"_sum", hv.labelText(key, "", ""), hValue.sum)
+		writeSample(writer, hv.name+"_count", hv.labelText(key, "", ""), float64(hValue.count))
+	}
+}
+
+// funcMetric is a metric without labels whose value is read when it is written
+type funcMetric struct {
+	name       string
+	help       string
+	metricType string
+	valueFn    func() float64
+}
+
+func (fm *funcMetric) write(writer *bufio.Writer) {
+	writeHeader(writer, fm.name, fm.help, fm.metricType)
+	writeSample(writer, fm.name, "", fm.valueFn())
+}
+
+// NewMetricsRegistry creates an empty metrics registry
+func NewMetricsRegistry() *MetricsRegistry {
+	metricsRegistry := new(MetricsRegistry)
+	metricsRegistry.metrics = make(map[string]metric)
+
+	return metricsRegistry
+}
+
+// unexported functions
+func newSeries(name string, help string, labelNames []string) *series {
+	return &series{name: name, help: help, labelNames: labelNames, labels: make(map[string][]string)}
+}
+
+func writeHeader(writer *bufio.Writer, name string, help string, metricType string) {
+	helpText := strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	_, _ = writer.WriteString("# HELP " + name + " " + helpText + "\n")
+	_, _ = writer.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+func writeSample(writer *bufio.Writer, name string, labelText string, value float64) {
+	_, _ = writer.WriteString(name + labelText + " " + formatFloat(value) + "\n")
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
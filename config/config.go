@@ -1,8 +1,11 @@
 package config
 
 import (
-	"log"
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"log/slog"
 	"os"
+	"time"
 )
 
 // Config variable keys
@@ -14,10 +17,105 @@ const (
 	HOST string = "HOST"
 	PORT string = "PORT"
 
+	// Logging settings
+	LOG_LEVEL  string = "LOG_LEVEL"
+	LOG_FORMAT string = "LOG_FORMAT"
+
+	// Health check settings
+	HEALTH_CHECK_TIMEOUT string = "HEALTH_CHECK_TIMEOUT"
+	STARTUP_TIMEOUT      string = "STARTUP_TIMEOUT"
+
+	// HTTP server settings
+	SERVER_READ_TIMEOUT  string = "SERVER_READ_TIMEOUT"
+	SERVER_WRITE_TIMEOUT string = "SERVER_WRITE_TIMEOUT"
+	SERVER_IDLE_TIMEOUT  string = "SERVER_IDLE_TIMEOUT"
+	SHUTDOWN_TIMEOUT     string = "SHUTDOWN_TIMEOUT"
+
 	// Redis server settings
 	REDIS_TLS_URL string = "REDIS_TLS_URL"
 	REDIS_URL     string = "REDIS_URL"
 	REDIS_PORT    string = "REDIS_PORT"
+	QUESTION_TTL  string = "QUESTION_TTL"
+	STORE_TYPE    string = "STORE_TYPE"
+	GAME_TTL      string = "GAME_TTL"
+
+	// Answer timing settings
+	ANSWER_DEADLINE  string = "ANSWER_DEADLINE"
+	ANSWER_DEADLINES string = "ANSWER_DEADLINES"
+	SPEED_SCORING    string = "SPEED_SCORING"
+
+	// Answer matching settings
+	MAX_ANSWER_TYPOS  string = "MAX_ANSWER_TYPOS"
+	ALTERNATE_ANSWERS string = "ALTERNATE_ANSWERS"
+
+	// SQL store settings
+	SQL_DRIVER string = "SQL_DRIVER"
+	SQL_DSN    string = "SQL_DSN"
+
+	// Trivia provider settings
+	TRIVIA_PROVIDER    string = "TRIVIA_PROVIDER"
+	QUESTION_BANK_PATH string = "QUESTION_BANK_PATH"
+
+	// Provider fallback settings
+	FALLBACK_PROVIDERS        string = "FALLBACK_PROVIDERS"
+	BREAKER_FAILURE_THRESHOLD string = "BREAKER_FAILURE_THRESHOLD"
+	BREAKER_OPEN_TIMEOUT      string = "BREAKER_OPEN_TIMEOUT"
+	QUESTION_CACHE_SIZE       string = "QUESTION_CACHE_SIZE"
+
+	// Question prefetch settings
+	PREFETCH_WATERMARK  string = "PREFETCH_WATERMARK"
+	PREFETCH_STORE      string = "PREFETCH_STORE"
+	PREFETCH_CATEGORIES string = "PREFETCH_CATEGORIES"
+	PREFETCH_WORKERS    string = "PREFETCH_WORKERS"
+	PREFETCH_INTERVAL   string = "PREFETCH_INTERVAL"
+
+	// Leaderboard settings
+	LEADERBOARD_STORE string = "LEADERBOARD_STORE"
+
+	// Seen question settings
+	SEEN_QUESTION_WINDOW  string = "SEEN_QUESTION_WINDOW"
+	SEEN_QUESTION_RETRIES string = "SEEN_QUESTION_RETRIES"
+	SEEN_QUESTION_STORE   string = "SEEN_QUESTION_STORE"
+
+	// Rate limit settings
+	RATE_LIMIT_REQUESTS string = "RATE_LIMIT_REQUESTS"
+	RATE_LIMIT_PERIOD   string = "RATE_LIMIT_PERIOD"
+	RATE_LIMIT_BURST    string = "RATE_LIMIT_BURST"
+	RATE_LIMIT_STORE    string = "RATE_LIMIT_STORE"
+	TRUSTED_PROXIES     string = "TRUSTED_PROXIES"
+
+	// Upstream request settings, used by every trivia provider that calls an API
+	UPSTREAM_TIMEOUT     string = "UPSTREAM_TIMEOUT"
+	UPSTREAM_MAX_RETRIES string = "UPSTREAM_MAX_RETRIES"
+	UPSTREAM_BACKOFF     string = "UPSTREAM_BACKOFF"
+	UPSTREAM_MAX_BACKOFF string = "UPSTREAM_MAX_BACKOFF"
+
+	// Open Trivia DB settings
+	OPENTDB_URL        string = "OPENTDB_URL"
+	OPENTDB_DIFFICULTY string = "OPENTDB_DIFFICULTY"
+	OPENTDB_TYPE       string = "OPENTDB_TYPE"
+	OPENTDB_ENCODING   string = "OPENTDB_ENCODING"
+
+	// API-Ninjas (RapidAPI) settings
+	APININJAS_URL string = "APININJAS_URL"
+	RAPIDAPI_HOST string = "RAPIDAPI_HOST"
+	RAPIDAPI_KEY  string = "RAPIDAPI_KEY"
+
+	// Answer message settings
+	MESSAGE_CATALOG   string = "MESSAGE_CATALOG"
+	MESSAGE_LOCALE    string = "MESSAGE_LOCALE"
+	CONGRATS_MESSAGE  string = "CONGRATS_MESSAGE"
+	TRY_AGAIN_MESSAGE string = "TRY_AGAIN_MESSAGE"
+
+	// Config file locations
+	CONFIG_FILE  string = "CONFIG_FILE"
+	SECRETS_FILE string = "SECRETS_FILE"
+)
+
+// Default config file locations. The secrets file is where Docker and Kubernetes mount secrets.
+const (
+	DEFAULT_CONFIG_FILE  string = "config/config.json"
+	DEFAULT_SECRETS_FILE string = "/run/secrets/trivia-api.json"
 )
 
 // PRODUCTION Config variable values
@@ -25,56 +123,255 @@ const (
 	PRODUCTION string = "PROD"
 )
 
+// Config defaults, used for settings missing from every config source
+const (
+	DEFAULT_PORT                      int           = 8080
+	DEFAULT_LOG_LEVEL                 string        = "info"
+	DEFAULT_LOG_FORMAT                string        = "text"
+	DEFAULT_HEALTH_CHECK_TIMEOUT      time.Duration = 2 * time.Second
+	DEFAULT_STARTUP_TIMEOUT           time.Duration = 2 * time.Minute
+	DEFAULT_SERVER_READ_TIMEOUT       time.Duration = 15 * time.Second
+	DEFAULT_SERVER_WRITE_TIMEOUT      time.Duration = 30 * time.Second
+	DEFAULT_SERVER_IDLE_TIMEOUT       time.Duration = time.Minute
+	DEFAULT_SHUTDOWN_TIMEOUT          time.Duration = 30 * time.Second
+	DEFAULT_REDIS_URL                 string        = "localhost"
+	DEFAULT_REDIS_PORT                int           = 6379
+	DEFAULT_QUESTION_TTL              time.Duration = 5 * time.Minute
+	DEFAULT_GAME_TTL                  time.Duration = time.Hour
+	DEFAULT_STORE_TYPE                string        = "redis"
+	DEFAULT_TRIVIA_PROVIDER           string        = "apininjas"
+	DEFAULT_LOCALE                    string        = "en"
+	DEFAULT_MAX_ANSWER_TYPOS          int           = 2
+	DEFAULT_UPSTREAM_TIMEOUT          time.Duration = 5 * time.Second
+	DEFAULT_UPSTREAM_RETRIES          int           = 2
+	DEFAULT_UPSTREAM_BACKOFF          time.Duration = 200 * time.Millisecond
+	DEFAULT_UPSTREAM_MAX_BACKOFF      time.Duration = 2 * time.Second
+	DEFAULT_BREAKER_FAILURE_THRESHOLD int           = 5
+	DEFAULT_BREAKER_OPEN_TIMEOUT      time.Duration = 30 * time.Second
+	DEFAULT_QUESTION_CACHE_SIZE       int           = 100
+	DEFAULT_PREFETCH_STORE            string        = "memory"
+	DEFAULT_PREFETCH_WORKERS          int           = 2
+	DEFAULT_PREFETCH_INTERVAL         time.Duration = 30 * time.Second
+	DEFAULT_SEEN_QUESTION_WINDOW      time.Duration = 24 * time.Hour
+	DEFAULT_SEEN_QUESTION_RETRIES     int           = 3
+	DEFAULT_RATE_LIMIT_REQUESTS       int           = 60
+	DEFAULT_RATE_LIMIT_PERIOD         time.Duration = time.Minute
+	DEFAULT_RATE_LIMIT_STORE          string        = "memory"
+)
+
 type CfgData struct {
-	Env         string `json:"env"`
-	Host        string `json:"hostname"`
-	Port        string `json:"hostport"`
-	RedisTLSURL string `json:"redistlsurl"`
-	RedisURL    string `json:"redisurl"`
-	RedisPort   string `json:"redisport"`
+	Env         string        `json:"env"`
+	Host        string        `json:"hostname"`
+	Port        int           `json:"hostport"`
+	LogLevel    string        `json:"loglevel"`
+	LogFormat   string        `json:"logformat"`
+	RedisTLSURL string        `json:"redistlsurl"`
+	RedisURL    string        `json:"redisurl"`
+	RedisPort   int           `json:"redisport"`
+	QuestionTTL time.Duration `json:"questionttl"`
+	StoreType   string        `json:"storetype"`
+	GameTTL     time.Duration `json:"gamettl"`
+	SQLDriver   string        `json:"sqldriver"`
+	SQLDSN      string        `json:"sqldsn" secret:"true"`
+
+	AnswerDeadline  time.Duration            `json:"answerdeadline"`
+	AnswerDeadlines map[string]time.Duration `json:"answerdeadlines"`
+	SpeedScoring    bool                     `json:"speedscoring"`
+
+	MaxAnswerTypos       int    `json:"maxanswertypos"`
+	AlternateAnswersPath string `json:"alternateanswers"`
+
+	TriviaProvider   string `json:"triviaprovider"`
+	QuestionBankPath string `json:"questionbankpath"`
+
+	FallbackProviders       []string      `json:"fallbackproviders"`
+	BreakerFailureThreshold int           `json:"breakerfailurethreshold"`
+	BreakerOpenTimeout      time.Duration `json:"breakeropentimeout"`
+	QuestionCacheSize       int           `json:"questioncachesize"`
+
+	PrefetchWatermark  int           `json:"prefetchwatermark"`
+	PrefetchStore      string        `json:"prefetchstore"`
+	PrefetchCategories []string      `json:"prefetchcategories"`
+	PrefetchWorkers    int           `json:"prefetchworkers"`
+	PrefetchInterval   time.Duration `json:"prefetchinterval"`
+
+	SeenQuestionWindow  time.Duration `json:"seenquestionwindow"`
+	SeenQuestionRetries int           `json:"seenquestionretries"`
+
+	// Leaderboards and seen questions are kept in Redis or in memory. When no store is set they are kept in
+	// Redis if it is the trivia store, and in memory otherwise.
+	LeaderboardStore  string `json:"leaderboardstore"`
+	SeenQuestionStore string `json:"seenquestionstore"`
+
+	// Requests are limited to RateLimitRequests each RateLimitPeriod for each API key or client IP, with
+	// bursts of up to RateLimitBurst requests. A burst of 0 is the number of requests, 0 requests turns
+	// the limit off.
+	RateLimitRequests int           `json:"ratelimitrequests"`
+	RateLimitPeriod   time.Duration `json:"ratelimitperiod"`
+	RateLimitBurst    int           `json:"ratelimitburst"`
+	RateLimitStore    string        `json:"ratelimitstore"`
+
+	// Requests from the IP addresses or CIDR ranges in TrustedProxies are limited on the client IP they
+	// forward in X-Forwarded-For. No proxy is trusted by default.
+	TrustedProxies []string `json:"trustedproxies"`
+
+	UpstreamTimeout    time.Duration `json:"upstreamtimeout"`
+	UpstreamMaxRetries int           `json:"upstreammaxretries"`
+	UpstreamBackoff    time.Duration `json:"upstreambackoff"`
+	UpstreamMaxBackoff time.Duration `json:"upstreammaxbackoff"`
+
+	OpenTDBURL        string `json:"opentdburl"`
+	OpenTDBDifficulty string `json:"opentdbdifficulty"`
+	OpenTDBType       string `json:"opentdbtype"`
+	OpenTDBEncoding   string `json:"opentdbencoding"`
+
+	APINinjasURL string `json:"apininjasurl"`
+	RapidAPIHost string `json:"rapidapihost"`
+	RapidAPIKey  string `json:"rapidapikey" secret:"true"`
+
+	MessageCatalogPath string `json:"messagecatalog"`
+	DefaultLocale      string `json:"defaultlocale"`
+	CongratsMsg        string `json:"congrats"`
+	TryAgainMsg        string `json:"tryagain"`
+
+	// Dependencies are checked with a timeout. The service waits up to the startup timeout for its
+	// dependencies before it gives up, 0 waits forever.
+	HealthCheckTimeout time.Duration `json:"healthchecktimeout"`
+	StartupTimeout     time.Duration `json:"startuptimeout"`
+
+	// Requests must be read and answered within the server timeouts. Idle connections are closed after the
+	// idle timeout. On shutdown, requests in flight have up to the shutdown timeout to finish.
+	ServerReadTimeout  time.Duration `json:"serverreadtimeout"`
+	ServerWriteTimeout time.Duration `json:"serverwritetimeout"`
+	ServerIdleTimeout  time.Duration `json:"serveridletimeout"`
+	ShutdownTimeout    time.Duration `json:"shutdowntimeout"`
 }
 
 type Config struct {
-	cfgData *CfgData
+	cfgData    *CfgData
+	configFile string
+	loaded     bool
+	loadErr    error
 }
 
 var config *Config
 
-// Unexported type functions
-func (c *Config) loadConfigEnv() {
-	// Loading config environment variables
-	log.Print("loading config environment variables...")
+// SetConfigFile sets the config file to load, taking precedence over the CONFIG_FILE environment variable.
+// It must be called before the config is loaded.
+func (c *Config) SetConfigFile(configFile string) {
+	c.configFile = configFile
+}
 
-	// Load host config data
-	c.cfgData.Env = os.Getenv(ENV)
-	c.cfgData.Host = os.Getenv(HOST)
-	c.cfgData.Port = os.Getenv(PORT)
+// Load loads config data the first time it is called. Each layer overrides the settings of the one before:
+//
+//	defaults
+//	the JSON or YAML config file set with SetConfigFile or CONFIG_FILE, config/config.json otherwise
+//	the secrets file set with SECRETS_FILE, /run/secrets/trivia-api.json otherwise
+//	environment variables
+//
+// The returned error is a *ValidationError listing every problem found in the config.
+func (c *Config) Load() (*CfgData, error) {
+	if c.loaded {
+		return c.cfgData, c.loadErr
+	}
+
+	var problems []string
+	c.cfgData = NewDefaultCfgData()
 
-	// Load redis config data
-	c.cfgData.RedisTLSURL = os.Getenv(REDIS_TLS_URL)
-	c.cfgData.RedisURL = os.Getenv(REDIS_URL)
-	c.cfgData.RedisPort = os.Getenv(REDIS_PORT)
+	// Config file
+	configFile, configFileSet := c.configFile, len(c.configFile) > 0
+	if !configFileSet {
+		configFile, configFileSet = os.LookupEnv(CONFIG_FILE)
+	}
+	if !configFileSet {
+		configFile = DEFAULT_CONFIG_FILE
+	}
+	problems = append(problems, c.cfgData.loadFile(configFile, configFileSet)...)
+
+	// Secrets file
+	secretsFile, secretsFileSet := os.LookupEnv(SECRETS_FILE)
+	if !secretsFileSet {
+		secretsFile = DEFAULT_SECRETS_FILE
+	}
+	problems = append(problems, c.cfgData.loadFile(secretsFile, secretsFileSet)...)
+
+	// Environment
+	slog.Info("Loading config environment variables")
+	problems = append(problems, c.cfgData.loadEnv()...)
+
+	problems = append(problems, c.cfgData.validate()...)
+	if len(problems) > 0 {
+		c.loadErr = &ValidationError{Problems: problems}
+	}
+	c.loaded = true
+
+	slog.Info("Config loaded", "config", fmt.Sprintf("%+v", c.cfgData.Redacted()))
+
+	return c.cfgData, c.loadErr
 }
 
+// LoadCfgData returns the config data, loading it the first time it is called.
+// Config problems are logged; call Load at startup to stop on an invalid config.
 func (c *Config) LoadCfgData() *CfgData {
-	log.Print("Using config environment to load config")
+	firstLoad := !c.loaded
+
+	cfgData, loadErr := c.Load()
+	if loadErr != nil && firstLoad {
+		slog.Error("Error loading config", common.ERROR_FIELD, loadErr)
+	}
 
-	c.loadConfigEnv()
+	return cfgData
+}
+
+// NewDefaultCfgData returns config data holding the default for every setting
+func NewDefaultCfgData() *CfgData {
+	cfgData := new(CfgData)
+	cfgData.Port = DEFAULT_PORT
+	cfgData.LogLevel = DEFAULT_LOG_LEVEL
+	cfgData.LogFormat = DEFAULT_LOG_FORMAT
+	cfgData.HealthCheckTimeout = DEFAULT_HEALTH_CHECK_TIMEOUT
+	cfgData.StartupTimeout = DEFAULT_STARTUP_TIMEOUT
+	cfgData.ServerReadTimeout = DEFAULT_SERVER_READ_TIMEOUT
+	cfgData.ServerWriteTimeout = DEFAULT_SERVER_WRITE_TIMEOUT
+	cfgData.ServerIdleTimeout = DEFAULT_SERVER_IDLE_TIMEOUT
+	cfgData.ShutdownTimeout = DEFAULT_SHUTDOWN_TIMEOUT
+	cfgData.RedisURL = DEFAULT_REDIS_URL
+	cfgData.RedisPort = DEFAULT_REDIS_PORT
+	cfgData.QuestionTTL = DEFAULT_QUESTION_TTL
+	cfgData.StoreType = DEFAULT_STORE_TYPE
+	cfgData.GameTTL = DEFAULT_GAME_TTL
+	cfgData.AnswerDeadlines = make(map[string]time.Duration)
+	cfgData.TriviaProvider = DEFAULT_TRIVIA_PROVIDER
+	cfgData.DefaultLocale = DEFAULT_LOCALE
+	cfgData.MaxAnswerTypos = DEFAULT_MAX_ANSWER_TYPOS
+	cfgData.UpstreamTimeout = DEFAULT_UPSTREAM_TIMEOUT
+	cfgData.UpstreamMaxRetries = DEFAULT_UPSTREAM_RETRIES
+	cfgData.UpstreamBackoff = DEFAULT_UPSTREAM_BACKOFF
+	cfgData.UpstreamMaxBackoff = DEFAULT_UPSTREAM_MAX_BACKOFF
+	cfgData.BreakerFailureThreshold = DEFAULT_BREAKER_FAILURE_THRESHOLD
+	cfgData.BreakerOpenTimeout = DEFAULT_BREAKER_OPEN_TIMEOUT
+	cfgData.QuestionCacheSize = DEFAULT_QUESTION_CACHE_SIZE
+	cfgData.PrefetchStore = DEFAULT_PREFETCH_STORE
+	cfgData.PrefetchWorkers = DEFAULT_PREFETCH_WORKERS
+	cfgData.PrefetchInterval = DEFAULT_PREFETCH_INTERVAL
+	cfgData.SeenQuestionWindow = DEFAULT_SEEN_QUESTION_WINDOW
+	cfgData.SeenQuestionRetries = DEFAULT_SEEN_QUESTION_RETRIES
+	cfgData.RateLimitRequests = DEFAULT_RATE_LIMIT_REQUESTS
+	cfgData.RateLimitPeriod = DEFAULT_RATE_LIMIT_PERIOD
+	cfgData.RateLimitStore = DEFAULT_RATE_LIMIT_STORE
 
-	return c.cfgData
+	return cfgData
 }
 
 func NewConfig() *Config {
 	if config == nil {
-		log.Print("creating config object")
-
 		// Initialize config
 		config = new(Config)
 
 		// Initialize config data
-		config.cfgData = new(CfgData)
+		config.cfgData = NewDefaultCfgData()
 	} else {
-		log.Print("returning config object")
+		slog.Debug("Returning config object")
 	}
 
 	return config
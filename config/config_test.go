@@ -0,0 +1,180 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFile writes a config file holding settings and returns its path
+func writeTestFile(t *testing.T, name string, settings map[string]string) string {
+	byteStream, marshalErr := json.Marshal(settings)
+	if marshalErr != nil {
+		t.Fatalf("json.Marshal() error = %v", marshalErr)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if writeErr := os.WriteFile(path, byteStream, 0o600); writeErr != nil {
+		t.Fatalf("os.WriteFile() error = %v", writeErr)
+	}
+
+	return path
+}
+
+// unsetTestEnv unsets an environment variable until the test ends
+func unsetTestEnv(t *testing.T, key string) {
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name         string
+		configFile   map[string]string
+		secretsFile  map[string]string
+		env          map[string]string
+		flagFile     map[string]string
+		wantPort     int
+		wantLogLevel string
+	}{
+		{
+			name:         "defaults",
+			wantPort:     DEFAULT_PORT,
+			wantLogLevel: DEFAULT_LOG_LEVEL,
+		},
+		{
+			name:         "config file overrides defaults",
+			configFile:   map[string]string{"hostport": "9000", "loglevel": "debug"},
+			wantPort:     9000,
+			wantLogLevel: "debug",
+		},
+		{
+			name:         "secrets file overrides config file",
+			configFile:   map[string]string{"hostport": "9000", "loglevel": "debug"},
+			secretsFile:  map[string]string{"hostport": "9001"},
+			wantPort:     9001,
+			wantLogLevel: "debug",
+		},
+		{
+			name:         "environment overrides files",
+			configFile:   map[string]string{"hostport": "9000", "loglevel": "debug"},
+			secretsFile:  map[string]string{"hostport": "9001"},
+			env:          map[string]string{PORT: "9002"},
+			wantPort:     9002,
+			wantLogLevel: "debug",
+		},
+		{
+			name:         "config file flag overrides CONFIG_FILE",
+			configFile:   map[string]string{"hostport": "9000", "loglevel": "debug"},
+			flagFile:     map[string]string{"hostport": "9003"},
+			wantPort:     9003,
+			wantLogLevel: DEFAULT_LOG_LEVEL,
+		},
+		{
+			name:         "environment overrides config file flag",
+			flagFile:     map[string]string{"hostport": "9003", "loglevel": "debug"},
+			env:          map[string]string{LOG_LEVEL: "error"},
+			wantPort:     9003,
+			wantLogLevel: "error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, key := range []string{CONFIG_FILE, PORT, LOG_LEVEL} {
+				unsetTestEnv(t, key)
+			}
+			t.Setenv(RAPIDAPI_KEY, "secret-key")
+
+			cfgConfig := new(Config)
+			if test.configFile != nil {
+				t.Setenv(CONFIG_FILE, writeTestFile(t, "config.json", test.configFile))
+			}
+			secretsFile := test.secretsFile
+			if secretsFile == nil {
+				secretsFile = map[string]string{}
+			}
+			t.Setenv(SECRETS_FILE, writeTestFile(t, "secrets.json", secretsFile))
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			if test.flagFile != nil {
+				cfgConfig.SetConfigFile(writeTestFile(t, "flag.json", test.flagFile))
+			}
+
+			cfgData, loadErr := cfgConfig.Load()
+			if loadErr != nil {
+				t.Fatalf("Load() error = %v", loadErr)
+			}
+			if cfgData.Port != test.wantPort || cfgData.LogLevel != test.wantLogLevel {
+				t.Errorf("Load() port, log level = %d, %q, want %d, %q", cfgData.Port, cfgData.LogLevel,
+					test.wantPort, test.wantLogLevel)
+			}
+		})
+	}
+}
+
+func TestLoadProblems(t *testing.T) {
+	missingFile := filepath.Join(t.TempDir(), "missing.json")
+
+	tests := []struct {
+		name         string
+		configFile   string
+		env          map[string]string
+		wantProblems []string
+	}{
+		{
+			name:         "missing config file",
+			configFile:   missingFile,
+			wantProblems: []string{"config file " + missingFile},
+		},
+		{
+			name:         "invalid setting in config file",
+			configFile:   writeTestFile(t, "config.json", map[string]string{"hostport": "port"}),
+			wantProblems: []string{"hostport:"},
+		},
+		{
+			name:         "invalid environment variable",
+			env:          map[string]string{PORT: "port", QUESTION_TTL: "soon"},
+			wantProblems: []string{PORT + ":", QUESTION_TTL + ":"},
+		},
+		{
+			name:         "invalid values",
+			env:          map[string]string{STORE_TYPE: "paper", LOG_FORMAT: "xml"},
+			wantProblems: []string{LOG_FORMAT + ":", STORE_TYPE + ":"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, key := range []string{CONFIG_FILE, PORT, QUESTION_TTL, STORE_TYPE, LOG_FORMAT} {
+				unsetTestEnv(t, key)
+			}
+			t.Setenv(SECRETS_FILE, writeTestFile(t, "secrets.json", map[string]string{"rapidapikey": "secret-key"}))
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			cfgConfig := new(Config)
+			cfgConfig.SetConfigFile(test.configFile)
+
+			// Every problem is reported at once
+			_, loadErr := cfgConfig.Load()
+			var validationErr *ValidationError
+			if !errors.As(loadErr, &validationErr) {
+				t.Fatalf("Load() error = %v, want a *ValidationError", loadErr)
+			}
+			if len(validationErr.Problems) != len(test.wantProblems) {
+				t.Fatalf("Load() problems = %q, want %d problem(s)", validationErr.Problems, len(test.wantProblems))
+			}
+			for idx, want := range test.wantProblems {
+				if !strings.Contains(validationErr.Problems[idx], want) {
+					t.Errorf("Load() problem = %q, want it to contain %q", validationErr.Problems[idx], want)
+				}
+			}
+		})
+	}
+}
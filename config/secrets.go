@@ -0,0 +1,31 @@
+package config
+
+import "reflect"
+
+// REDACTED_VALUE replaces secret settings in logs
+const REDACTED_VALUE string = "********"
+
+// Redacted returns a copy of the config data with every setting tagged `secret:"true"` replaced
+// by REDACTED_VALUE. Use it whenever config data is logged or printed.
+func (cd CfgData) Redacted() CfgData {
+	redacted := cd
+
+	value := reflect.ValueOf(&redacted).Elem()
+	for idx := 0; idx < value.NumField(); idx++ {
+		field := value.Type().Field(idx)
+		if field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String {
+			value.Field(idx).SetString(RedactSecret(value.Field(idx).String()))
+		}
+	}
+
+	return redacted
+}
+
+// RedactSecret hides a secret value, an unset secret is left empty so it still shows as missing
+func RedactSecret(secret string) string {
+	if len(secret) == 0 {
+		return ""
+	}
+
+	return REDACTED_VALUE
+}
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DURATION_MAP_SEPARATOR separates the entries of a duration map setting: 'easy=20s,hard=45s'
+	DURATION_MAP_SEPARATOR string = ","
+
+	// LIST_SEPARATOR separates the entries of a list setting: 'opentdb,questionbank'
+	LIST_SEPARATOR string = ","
+)
+
+// settingValue is a typed setting that can be set from, and printed as, a string
+type settingValue interface {
+	Set(value string) error
+	String() string
+}
+
+// setting ties a config data field to its config file key and environment variable
+type setting struct {
+	fileKey string
+	envKey  string
+	value   settingValue
+}
+
+// settings returns every setting of the config data
+func (cd *CfgData) settings() []setting {
+	return []setting{
+		{"env", ENV, (*stringValue)(&cd.Env)},
+		{"hostname", HOST, (*stringValue)(&cd.Host)},
+		{"hostport", PORT, (*portValue)(&cd.Port)},
+		{"loglevel", LOG_LEVEL, (*stringValue)(&cd.LogLevel)},
+		{"logformat", LOG_FORMAT, (*stringValue)(&cd.LogFormat)},
+		{"healthchecktimeout", HEALTH_CHECK_TIMEOUT, (*durationValue)(&cd.HealthCheckTimeout)},
+		{"startuptimeout", STARTUP_TIMEOUT, (*durationValue)(&cd.StartupTimeout)},
+		{"serverreadtimeout", SERVER_READ_TIMEOUT, (*durationValue)(&cd.ServerReadTimeout)},
+		{"serverwritetimeout", SERVER_WRITE_TIMEOUT, (*durationValue)(&cd.ServerWriteTimeout)},
+		{"serveridletimeout", SERVER_IDLE_TIMEOUT, (*durationValue)(&cd.ServerIdleTimeout)},
+		{"shutdowntimeout", SHUTDOWN_TIMEOUT, (*durationValue)(&cd.ShutdownTimeout)},
+		{"redistlsurl", REDIS_TLS_URL, (*stringValue)(&cd.RedisTLSURL)},
+		{"redisurl", REDIS_URL, (*stringValue)(&cd.RedisURL)},
+		{"redisport", REDIS_PORT, (*portValue)(&cd.RedisPort)},
+		{"questionttl", QUESTION_TTL, (*durationValue)(&cd.QuestionTTL)},
+		{"storetype", STORE_TYPE, (*stringValue)(&cd.StoreType)},
+		{"gamettl", GAME_TTL, (*durationValue)(&cd.GameTTL)},
+		{"sqldriver", SQL_DRIVER, (*stringValue)(&cd.SQLDriver)},
+		{"sqldsn", SQL_DSN, (*stringValue)(&cd.SQLDSN)},
+		{"answerdeadline", ANSWER_DEADLINE, (*durationValue)(&cd.AnswerDeadline)},
+		{"answerdeadlines", ANSWER_DEADLINES, (*durationMapValue)(&cd.AnswerDeadlines)},
+		{"speedscoring", SPEED_SCORING, (*boolValue)(&cd.SpeedScoring)},
+		{"maxanswertypos", MAX_ANSWER_TYPOS, (*intValue)(&cd.MaxAnswerTypos)},
+		{"alternateanswers", ALTERNATE_ANSWERS, (*stringValue)(&cd.AlternateAnswersPath)},
+		{"triviaprovider", TRIVIA_PROVIDER, (*stringValue)(&cd.TriviaProvider)},
+		{"fallbackproviders", FALLBACK_PROVIDERS, (*stringListValue)(&cd.FallbackProviders)},
+		{"breakerfailurethreshold", BREAKER_FAILURE_THRESHOLD, (*intValue)(&cd.BreakerFailureThreshold)},
+		{"breakeropentimeout", BREAKER_OPEN_TIMEOUT, (*durationValue)(&cd.BreakerOpenTimeout)},
+		{"questioncachesize", QUESTION_CACHE_SIZE, (*intValue)(&cd.QuestionCacheSize)},
+		{"prefetchwatermark", PREFETCH_WATERMARK, (*intValue)(&cd.PrefetchWatermark)},
+		{"prefetchstore", PREFETCH_STORE, (*stringValue)(&cd.PrefetchStore)},
+		{"prefetchcategories", PREFETCH_CATEGORIES, (*stringListValue)(&cd.PrefetchCategories)},
+		{"prefetchworkers", PREFETCH_WORKERS, (*intValue)(&cd.PrefetchWorkers)},
+		{"prefetchinterval", PREFETCH_INTERVAL, (*durationValue)(&cd.PrefetchInterval)},
+		{"seenquestionwindow", SEEN_QUESTION_WINDOW, (*durationValue)(&cd.SeenQuestionWindow)},
+		{"seenquestionretries", SEEN_QUESTION_RETRIES, (*intValue)(&cd.SeenQuestionRetries)},
+		{"seenquestionstore", SEEN_QUESTION_STORE, (*stringValue)(&cd.SeenQuestionStore)},
+		{"leaderboardstore", LEADERBOARD_STORE, (*stringValue)(&cd.LeaderboardStore)},
+		{"ratelimitrequests", RATE_LIMIT_REQUESTS, (*intValue)(&cd.RateLimitRequests)},
+		{"ratelimitperiod", RATE_LIMIT_PERIOD, (*durationValue)(&cd.RateLimitPeriod)},
+		{"ratelimitburst", RATE_LIMIT_BURST, (*intValue)(&cd.RateLimitBurst)},
+		{"ratelimitstore", RATE_LIMIT_STORE, (*stringValue)(&cd.RateLimitStore)},
+		{"trustedproxies", TRUSTED_PROXIES, (*stringListValue)(&cd.TrustedProxies)},
+		{"questionbankpath", QUESTION_BANK_PATH, (*stringValue)(&cd.QuestionBankPath)},
+		{"upstreamtimeout", UPSTREAM_TIMEOUT, (*durationValue)(&cd.UpstreamTimeout)},
+		{"upstreammaxretries", UPSTREAM_MAX_RETRIES, (*intValue)(&cd.UpstreamMaxRetries)},
+		{"upstreambackoff", UPSTREAM_BACKOFF, (*durationValue)(&cd.UpstreamBackoff)},
+		{"upstreammaxbackoff", UPSTREAM_MAX_BACKOFF, (*durationValue)(&cd.UpstreamMaxBackoff)},
+		{"opentdburl", OPENTDB_URL, (*stringValue)(&cd.OpenTDBURL)},
+		{"opentdbdifficulty", OPENTDB_DIFFICULTY, (*stringValue)(&cd.OpenTDBDifficulty)},
+		{"opentdbtype", OPENTDB_TYPE, (*stringValue)(&cd.OpenTDBType)},
+		{"opentdbencoding", OPENTDB_ENCODING, (*stringValue)(&cd.OpenTDBEncoding)},
+		{"apininjasurl", APININJAS_URL, (*stringValue)(&cd.APINinjasURL)},
+		{"rapidapihost", RAPIDAPI_HOST, (*stringValue)(&cd.RapidAPIHost)},
+		{"rapidapikey", RAPIDAPI_KEY, (*stringValue)(&cd.RapidAPIKey)},
+		{"messagecatalog", MESSAGE_CATALOG, (*stringValue)(&cd.MessageCatalogPath)},
+		{"defaultlocale", MESSAGE_LOCALE, (*stringValue)(&cd.DefaultLocale)},
+		{"congrats", CONGRATS_MESSAGE, (*stringValue)(&cd.CongratsMsg)},
+		{"tryagain", TRY_AGAIN_MESSAGE, (*stringValue)(&cd.TryAgainMsg)},
+	}
+}
+
+// Print writes the config data as JSON with secrets redacted, using the config file keys
+func (cd CfgData) Print(w io.Writer) error {
+	redacted := cd.Redacted()
+
+	values := make(map[string]string)
+	for _, s := range redacted.settings() {
+		values[s.fileKey] = s.value.String()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(values)
+}
+
+// unexported type methods
+// loadFile overrides config data with the settings in a JSON or YAML file. YAML is used for files
+// ending in .yaml or .yml. A missing file is only a problem when required is set.
+func (cd *CfgData) loadFile(path string, required bool) []string {
+	byteStream, readErr := os.ReadFile(path)
+	if errors.Is(readErr, fs.ErrNotExist) && !required {
+		return nil
+	} else if readErr != nil {
+		return []string{fmt.Sprintf("config file %s: %v", path, readErr)}
+	}
+
+	logger := slog.With("file", path)
+	logger.Info("Loading config file")
+
+	fileValues := make(map[string]interface{})
+	var unmarshalErr error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		unmarshalErr = yaml.Unmarshal(byteStream, &fileValues)
+	default:
+		unmarshalErr = json.Unmarshal(byteStream, &fileValues)
+	}
+	if unmarshalErr != nil {
+		return []string{fmt.Sprintf("config file %s: %v", path, unmarshalErr)}
+	}
+
+	var problems []string
+	settings := cd.settings()
+	for _, s := range settings {
+		fileValue, found := fileValues[s.fileKey]
+		if !found {
+			continue
+		}
+		delete(fileValues, s.fileKey)
+
+		setErr := s.value.Set(fileValueString(fileValue))
+		if setErr != nil {
+			problems = append(problems, fmt.Sprintf("config file %s: %s: %v", path, s.fileKey, setErr))
+		}
+	}
+
+	for fileKey := range fileValues {
+		logger.Warn("Ignoring unknown setting in config file", "setting", fileKey)
+	}
+
+	return problems
+}
+
+// loadEnv overrides config data with the environment variables that are set
+func (cd *CfgData) loadEnv() []string {
+	var problems []string
+	for _, s := range cd.settings() {
+		envValue, found := os.LookupEnv(s.envKey)
+		if !found {
+			continue
+		}
+
+		setErr := s.value.Set(envValue)
+		if setErr != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.envKey, setErr))
+		}
+	}
+
+	return problems
+}
+
+// unexported functions
+// fileValueString converts a value read from a config file to the string form used by environment variables
+func fileValueString(fileValue interface{}) string {
+	switch value := fileValue.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []interface{}:
+		entries := make([]string, 0, len(value))
+		for _, entryValue := range value {
+			entries = append(entries, fileValueString(entryValue))
+		}
+		return strings.Join(entries, LIST_SEPARATOR)
+	case map[string]interface{}:
+		entries := make([]string, 0, len(value))
+		for key, entryValue := range value {
+			entries = append(entries, key+"="+fileValueString(entryValue))
+		}
+		sort.Strings(entries)
+		return strings.Join(entries, DURATION_MAP_SEPARATOR)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Setting value types
+type stringValue string
+
+func (sv *stringValue) Set(value string) error {
+	*sv = stringValue(value)
+	return nil
+}
+
+func (sv *stringValue) String() string {
+	return string(*sv)
+}
+
+type portValue int
+
+func (pv *portValue) Set(value string) error {
+	port, convErr := strconv.Atoi(strings.TrimSpace(value))
+	if convErr != nil {
+		return fmt.Errorf("%q is not a port number", value)
+	}
+
+	*pv = portValue(port)
+	return nil
+}
+
+func (pv *portValue) String() string {
+	return strconv.Itoa(int(*pv))
+}
+
+// stringListValue is a list of strings in the form: 'opentdb,questionbank'
+type stringListValue []string
+
+func (slv *stringListValue) Set(value string) error {
+	var entries []string
+	for _, entry := range strings.Split(value, LIST_SEPARATOR) {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+
+	*slv = entries
+	return nil
+}
+
+func (slv *stringListValue) String() string {
+	return strings.Join(*slv, LIST_SEPARATOR)
+}
+
+type intValue int
+
+func (iv *intValue) Set(value string) error {
+	number, convErr := strconv.Atoi(strings.TrimSpace(value))
+	if convErr != nil {
+		return fmt.Errorf("%q is not a whole number", value)
+	}
+
+	*iv = intValue(number)
+	return nil
+}
+
+func (iv *intValue) String() string {
+	return strconv.Itoa(int(*iv))
+}
+
+type boolValue bool
+
+func (bv *boolValue) Set(value string) error {
+	if len(strings.TrimSpace(value)) == 0 {
+		*bv = false
+		return nil
+	}
+
+	boolean, parseErr := strconv.ParseBool(strings.TrimSpace(value))
+	if parseErr != nil {
+		return fmt.Errorf("%q is not true or false", value)
+	}
+
+	*bv = boolValue(boolean)
+	return nil
+}
+
+func (bv *boolValue) String() string {
+	return strconv.FormatBool(bool(*bv))
+}
+
+type durationValue time.Duration
+
+func (dv *durationValue) Set(value string) error {
+	if len(strings.TrimSpace(value)) == 0 {
+		*dv = 0
+		return nil
+	}
+
+	duration, parseErr := time.ParseDuration(strings.TrimSpace(value))
+	if parseErr != nil {
+		return fmt.Errorf("%q is not a duration", value)
+	}
+
+	*dv = durationValue(duration)
+	return nil
+}
+
+func (dv *durationValue) String() string {
+	return time.Duration(*dv).String()
+}
+
+// durationMapValue is a list of durations by key, in the form: 'easy=20s,hard=45s,geography/hard=60s'.
+// Keys are not case sensitive.
+type durationMapValue map[string]time.Duration
+
+func (dmv *durationMapValue) Set(value string) error {
+	durations := make(map[string]time.Duration)
+
+	for _, entry := range strings.Split(value, DURATION_MAP_SEPARATOR) {
+		if len(strings.TrimSpace(entry)) == 0 {
+			continue
+		}
+
+		key, durationStr, found := strings.Cut(entry, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !found || len(key) == 0 {
+			return fmt.Errorf("entry %q is not in the form key=duration", entry)
+		}
+
+		duration, parseErr := time.ParseDuration(strings.TrimSpace(durationStr))
+		if parseErr != nil {
+			return fmt.Errorf("entry %q does not have a valid duration", entry)
+		}
+
+		durations[key] = duration
+	}
+
+	*dmv = durations
+	return nil
+}
+
+func (dmv *durationMapValue) String() string {
+	entries := make([]string, 0, len(*dmv))
+	for key, duration := range *dmv {
+		entries = append(entries, key+"="+duration.String())
+	}
+	sort.Strings(entries)
+
+	return strings.Join(entries, DURATION_MAP_SEPARATOR)
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Values accepted by the settings that select a component
+var (
+	validStoreTypes      = []string{"redis", "memory", "sql"}
+	validSQLDrivers      = []string{"", "sqlite3", "postgres"}
+	validTriviaProviders = []string{"apininjas", "questionbank", "opentdb"}
+	validPrefetchStores  = []string{"memory", "redis"}
+	validRateLimitStores = []string{"memory", "redis"}
+	validScoreStores     = []string{"", "memory", "redis"}
+	validLogLevels       = []string{"debug", "info", "warn", "error"}
+	validLogFormats      = []string{"text", "json"}
+)
+
+// ValidationError lists every problem found while loading the config
+type ValidationError struct {
+	Problems []string
+}
+
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf("config is invalid, %d problem(s): %s", len(ve.Problems), strings.Join(ve.Problems, "; "))
+}
+
+// unexported type methods
+// validate returns every problem with the values of the config data
+func (cd *CfgData) validate() []string {
+	var problems []string
+
+	if cd.Port < 1 || cd.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("%s: %d is not between 1 and 65535", PORT, cd.Port))
+	}
+
+	if !isValidValue(cd.LogLevel, validLogLevels) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", LOG_LEVEL, cd.LogLevel,
+			strings.Join(validLogLevels, ", ")))
+	}
+
+	if !isValidValue(cd.LogFormat, validLogFormats) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", LOG_FORMAT, cd.LogFormat,
+			strings.Join(validLogFormats, ", ")))
+	}
+
+	if cd.HealthCheckTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", HEALTH_CHECK_TIMEOUT))
+	}
+
+	if cd.StartupTimeout < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", STARTUP_TIMEOUT))
+	}
+
+	if cd.ServerReadTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", SERVER_READ_TIMEOUT))
+	}
+
+	if cd.ServerWriteTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", SERVER_WRITE_TIMEOUT))
+	}
+
+	if cd.ServerIdleTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", SERVER_IDLE_TIMEOUT))
+	}
+
+	if cd.ShutdownTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", SHUTDOWN_TIMEOUT))
+	}
+
+	if cd.RedisPort < 1 || cd.RedisPort > 65535 {
+		problems = append(problems, fmt.Sprintf("%s: %d is not between 1 and 65535", REDIS_PORT, cd.RedisPort))
+	}
+
+	if cd.QuestionTTL <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", QUESTION_TTL))
+	}
+
+	if cd.GameTTL <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", GAME_TTL))
+	}
+
+	if cd.AnswerDeadline < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", ANSWER_DEADLINE))
+	}
+
+	for key, deadline := range cd.AnswerDeadlines {
+		if deadline < 0 {
+			problems = append(problems, fmt.Sprintf("%s: %s must not be negative", ANSWER_DEADLINES, key))
+		}
+	}
+
+	if cd.MaxAnswerTypos < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", MAX_ANSWER_TYPOS))
+	}
+
+	if cd.UpstreamTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", UPSTREAM_TIMEOUT))
+	}
+
+	if cd.UpstreamMaxRetries < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", UPSTREAM_MAX_RETRIES))
+	}
+
+	if cd.UpstreamBackoff < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", UPSTREAM_BACKOFF))
+	}
+
+	if cd.UpstreamMaxBackoff < cd.UpstreamBackoff {
+		problems = append(problems, fmt.Sprintf("%s: must not be less than %s", UPSTREAM_MAX_BACKOFF, UPSTREAM_BACKOFF))
+	}
+
+	if !isValidValue(cd.StoreType, validStoreTypes) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", STORE_TYPE, cd.StoreType,
+			strings.Join(validStoreTypes, ", ")))
+	}
+
+	if !isValidValue(cd.SQLDriver, validSQLDrivers) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", SQL_DRIVER, cd.SQLDriver,
+			strings.Join(validSQLDrivers[1:], ", ")))
+	}
+
+	if cd.StoreType == "sql" && cd.SQLDriver == "postgres" && len(cd.SQLDSN) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: required for the postgres driver", SQL_DSN))
+	}
+
+	if !isValidValue(cd.TriviaProvider, validTriviaProviders) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", TRIVIA_PROVIDER, cd.TriviaProvider,
+			strings.Join(validTriviaProviders, ", ")))
+	}
+
+	for _, fallbackProvider := range cd.FallbackProviders {
+		if !isValidValue(fallbackProvider, validTriviaProviders) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", FALLBACK_PROVIDERS, fallbackProvider,
+				strings.Join(validTriviaProviders, ", ")))
+		}
+	}
+
+	// Every selected provider must have what it needs to serve questions
+	providers := append([]string{cd.TriviaProvider}, cd.FallbackProviders...)
+	if isValidValue("apininjas", providers) && len(cd.RapidAPIKey) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: required for trivia provider apininjas", RAPIDAPI_KEY))
+	}
+	if isValidValue("questionbank", providers) && len(cd.QuestionBankPath) == 0 {
+		problems = append(problems, fmt.Sprintf("%s: required for trivia provider questionbank", QUESTION_BANK_PATH))
+	}
+
+	if cd.BreakerFailureThreshold < 1 {
+		problems = append(problems, fmt.Sprintf("%s: must be at least 1", BREAKER_FAILURE_THRESHOLD))
+	}
+
+	if cd.BreakerOpenTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", BREAKER_OPEN_TIMEOUT))
+	}
+
+	if cd.QuestionCacheSize < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", QUESTION_CACHE_SIZE))
+	}
+
+	if cd.PrefetchWatermark < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", PREFETCH_WATERMARK))
+	}
+
+	if !isValidValue(cd.PrefetchStore, validPrefetchStores) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", PREFETCH_STORE, cd.PrefetchStore,
+			strings.Join(validPrefetchStores, ", ")))
+	}
+
+	if cd.PrefetchWorkers < 1 {
+		problems = append(problems, fmt.Sprintf("%s: must be at least 1", PREFETCH_WORKERS))
+	}
+
+	if cd.PrefetchInterval <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", PREFETCH_INTERVAL))
+	}
+
+	if cd.SeenQuestionWindow < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", SEEN_QUESTION_WINDOW))
+	}
+
+	if cd.SeenQuestionRetries < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", SEEN_QUESTION_RETRIES))
+	}
+
+	if !isValidValue(cd.SeenQuestionStore, validScoreStores) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", SEEN_QUESTION_STORE, cd.SeenQuestionStore,
+			strings.Join(validScoreStores[1:], ", ")))
+	}
+
+	if !isValidValue(cd.LeaderboardStore, validScoreStores) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", LEADERBOARD_STORE, cd.LeaderboardStore,
+			strings.Join(validScoreStores[1:], ", ")))
+	}
+
+	if cd.RateLimitRequests < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", RATE_LIMIT_REQUESTS))
+	}
+
+	if cd.RateLimitPeriod <= 0 {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than 0", RATE_LIMIT_PERIOD))
+	}
+
+	if cd.RateLimitBurst < 0 {
+		problems = append(problems, fmt.Sprintf("%s: must not be negative", RATE_LIMIT_BURST))
+	}
+
+	if !isValidValue(cd.RateLimitStore, validRateLimitStores) {
+		problems = append(problems, fmt.Sprintf("%s: %q is not one of %s", RATE_LIMIT_STORE, cd.RateLimitStore,
+			strings.Join(validRateLimitStores, ", ")))
+	}
+
+	for _, trustedProxy := range cd.TrustedProxies {
+		if !isValidProxy(trustedProxy) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not an IP address or CIDR range", TRUSTED_PROXIES,
+				trustedProxy))
+		}
+	}
+
+	return problems
+}
+
+// unexported functions
+func isValidValue(value string, validValues []string) bool {
+	for _, validValue := range validValues {
+		if value == validValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isValidProxy reports whether proxy is an IP address or a CIDR range
+func isValidProxy(proxy string) bool {
+	if _, addrErr := netip.ParseAddr(proxy); addrErr == nil {
+		return true
+	}
+	_, prefixErr := netip.ParsePrefix(proxy)
+
+	return prefixErr == nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(cd *CfgData)
+		wantProblem string
+	}{
+		{"port", func(cd *CfgData) { cd.Port = 0 }, PORT},
+		{"log level", func(cd *CfgData) { cd.LogLevel = "verbose" }, LOG_LEVEL},
+		{"log format", func(cd *CfgData) { cd.LogFormat = "xml" }, LOG_FORMAT},
+		{"health check timeout", func(cd *CfgData) { cd.HealthCheckTimeout = 0 }, HEALTH_CHECK_TIMEOUT},
+		{"startup timeout", func(cd *CfgData) { cd.StartupTimeout = -time.Second }, STARTUP_TIMEOUT},
+		{"server read timeout", func(cd *CfgData) { cd.ServerReadTimeout = 0 }, SERVER_READ_TIMEOUT},
+		{"server write timeout", func(cd *CfgData) { cd.ServerWriteTimeout = 0 }, SERVER_WRITE_TIMEOUT},
+		{"server idle timeout", func(cd *CfgData) { cd.ServerIdleTimeout = 0 }, SERVER_IDLE_TIMEOUT},
+		{"shutdown timeout", func(cd *CfgData) { cd.ShutdownTimeout = 0 }, SHUTDOWN_TIMEOUT},
+		{"redis port", func(cd *CfgData) { cd.RedisPort = 65536 }, REDIS_PORT},
+		{"question ttl", func(cd *CfgData) { cd.QuestionTTL = 0 }, QUESTION_TTL},
+		{"game ttl", func(cd *CfgData) { cd.GameTTL = 0 }, GAME_TTL},
+		{"answer deadline", func(cd *CfgData) { cd.AnswerDeadline = -time.Second }, ANSWER_DEADLINE},
+		{"answer deadlines", func(cd *CfgData) { cd.AnswerDeadlines["easy"] = -time.Second }, ANSWER_DEADLINES},
+		{"max answer typos", func(cd *CfgData) { cd.MaxAnswerTypos = -1 }, MAX_ANSWER_TYPOS},
+		{"upstream timeout", func(cd *CfgData) { cd.UpstreamTimeout = 0 }, UPSTREAM_TIMEOUT},
+		{"upstream max retries", func(cd *CfgData) { cd.UpstreamMaxRetries = -1 }, UPSTREAM_MAX_RETRIES},
+		{"upstream backoff", func(cd *CfgData) { cd.UpstreamBackoff = -time.Second }, UPSTREAM_BACKOFF},
+		{"upstream max backoff", func(cd *CfgData) { cd.UpstreamMaxBackoff = time.Millisecond }, UPSTREAM_MAX_BACKOFF},
+		{"store type", func(cd *CfgData) { cd.StoreType = "paper" }, STORE_TYPE},
+		{"sql driver", func(cd *CfgData) { cd.SQLDriver = "oracle" }, SQL_DRIVER},
+		{"sql dsn", func(cd *CfgData) { cd.StoreType, cd.SQLDriver = "sql", "postgres" }, SQL_DSN},
+		{"trivia provider", func(cd *CfgData) { cd.TriviaProvider = "encyclopedia" }, TRIVIA_PROVIDER},
+		{"fallback providers", func(cd *CfgData) { cd.FallbackProviders = []string{"encyclopedia"} },
+			FALLBACK_PROVIDERS},
+		{"rapidapi key", func(cd *CfgData) { cd.RapidAPIKey = "" }, RAPIDAPI_KEY},
+		{"question bank path", func(cd *CfgData) { cd.FallbackProviders = []string{"questionbank"} },
+			QUESTION_BANK_PATH},
+		{"breaker failure threshold", func(cd *CfgData) { cd.BreakerFailureThreshold = 0 },
+			BREAKER_FAILURE_THRESHOLD},
+		{"breaker open timeout", func(cd *CfgData) { cd.BreakerOpenTimeout = 0 }, BREAKER_OPEN_TIMEOUT},
+		{"question cache size", func(cd *CfgData) { cd.QuestionCacheSize = -1 }, QUESTION_CACHE_SIZE},
+		{"prefetch watermark", func(cd *CfgData) { cd.PrefetchWatermark = -1 }, PREFETCH_WATERMARK},
+		{"prefetch store", func(cd *CfgData) { cd.PrefetchStore = "paper" }, PREFETCH_STORE},
+		{"prefetch workers", func(cd *CfgData) { cd.PrefetchWorkers = 0 }, PREFETCH_WORKERS},
+		{"prefetch interval", func(cd *CfgData) { cd.PrefetchInterval = 0 }, PREFETCH_INTERVAL},
+		{"seen question window", func(cd *CfgData) { cd.SeenQuestionWindow = -time.Second }, SEEN_QUESTION_WINDOW},
+		{"seen question retries", func(cd *CfgData) { cd.SeenQuestionRetries = -1 }, SEEN_QUESTION_RETRIES},
+		{"seen question store", func(cd *CfgData) { cd.SeenQuestionStore = "paper" }, SEEN_QUESTION_STORE},
+		{"leaderboard store", func(cd *CfgData) { cd.LeaderboardStore = "paper" }, LEADERBOARD_STORE},
+		{"rate limit requests", func(cd *CfgData) { cd.RateLimitRequests = -1 }, RATE_LIMIT_REQUESTS},
+		{"rate limit period", func(cd *CfgData) { cd.RateLimitPeriod = 0 }, RATE_LIMIT_PERIOD},
+		{"rate limit burst", func(cd *CfgData) { cd.RateLimitBurst = -1 }, RATE_LIMIT_BURST},
+		{"rate limit store", func(cd *CfgData) { cd.RateLimitStore = "paper" }, RATE_LIMIT_STORE},
+		{"trusted proxies", func(cd *CfgData) { cd.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"} },
+			TRUSTED_PROXIES},
+	}
+
+	valid := NewDefaultCfgData()
+	valid.RapidAPIKey = "secret-key"
+	if problems := valid.validate(); len(problems) > 0 {
+		t.Fatalf("validate() of the defaults = %q, want no problems", problems)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfgData := NewDefaultCfgData()
+			cfgData.RapidAPIKey = "secret-key"
+			test.change(cfgData)
+
+			problems := cfgData.validate()
+			if len(problems) != 1 || !strings.HasPrefix(problems[0], test.wantProblem+":") {
+				t.Errorf("validate() = %q, want one problem with %s", problems, test.wantProblem)
+			}
+		})
+	}
+}
@@ -1,15 +1,39 @@
 package controllers
 
 import (
+	"context"
 	"github.com/gorilla/mux"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/external"
 	"github.com/sflewis2970/trivia-api/handlers"
-	"log"
+	"github.com/sflewis2970/trivia-api/models"
+	"io"
+	"log/slog"
 )
 
 // Controller structure defines teh layout of the Controller
 type Controller struct {
 	Router        *mux.Router
 	triviaHandler *handlers.TriviaHandler
+	gameHandler   *handlers.GameHandler
+
+	leaderboardHandler *handlers.LeaderboardHandler
+	playerHandler      *handlers.PlayerHandler
+	metricsHandler     *handlers.MetricsHandler
+	healthHandler      *handlers.HealthHandler
+
+	// rateLimitHandler is set when requests are rate limited
+	rateLimitHandler *handlers.RateLimitHandler
+
+	// prefetcher is set when questions are prefetched
+	prefetcher *external.Prefetcher
+
+	// Data stores are closed along with the controller
+	triviaStore models.TriviaStore
+	redisModel  *models.RedisModel
+
+	logger *slog.Logger
 }
 
 // Package controllers object
@@ -17,25 +41,252 @@ var controller *Controller
 
 func (c *Controller) setupRoutes() {
 	// Display log message
-	log.Print("Setting up trivia api service routes")
+	c.logger.Info("Setting up trivia api service routes")
+
+	// Every request is tagged with a request ID, counted and timed
+	c.Router.Use(handlers.NewRequestLogger(c.logger).Handle, handlers.RecordRequestMetrics)
+
+	// Metrics and health checks do not require an API key
+	c.Router.HandleFunc("/metrics", c.metricsHandler.GetMetrics).Methods("GET")
+	c.Router.HandleFunc("/healthz", c.healthHandler.Healthz).Methods("GET")
+	c.Router.HandleFunc("/readyz", c.healthHandler.Readyz).Methods("GET")
+
+	// API routes are rate limited, health checks and metrics are not. Routes that do not require an API key
+	// are limited for each client IP.
+	apiRouter := c.Router.PathPrefix("/api/v1/api").Subrouter()
+	publicRouter := apiRouter.NewRoute().Subrouter()
+	if c.rateLimitHandler != nil {
+		publicRouter.Use(c.rateLimitHandler.Limit)
+	}
+
+	// Player registration does not require an API key
+	publicRouter.HandleFunc("/players", c.playerHandler.RegisterPlayer).Methods("POST")
+
+	// Leaderboard routes
+	publicRouter.HandleFunc("/leaderboards/category/{category}", c.leaderboardHandler.GetLeaderboard).Methods("GET")
+	publicRouter.HandleFunc("/leaderboards/category/{category}/players/{playerid}", c.leaderboardHandler.GetPlayerRank).Methods("GET")
+	publicRouter.HandleFunc("/leaderboards/{board}", c.leaderboardHandler.GetLeaderboard).Methods("GET")
+	publicRouter.HandleFunc("/leaderboards/{board}/players/{playerid}", c.leaderboardHandler.GetPlayerRank).Methods("GET")
+
+	// Routes below require an API key, the authenticated player is attached to the request context and the
+	// routes are limited for each player. Public routes must be registered before this subrouter.
+	authRouter := apiRouter.NewRoute().Subrouter()
+	authRouter.Use(c.playerHandler.Authenticate)
+	if c.rateLimitHandler != nil {
+		authRouter.Use(c.rateLimitHandler.Limit)
+	}
+
+	// Player routes
+	authRouter.HandleFunc("/players/me", c.playerHandler.GetPlayer).Methods("GET")
+	authRouter.HandleFunc("/players/me/seenquestions", c.playerHandler.ResetSeenQuestions).Methods("DELETE")
 
 	// Trivia routes
-	c.Router.HandleFunc("/api/v1/api/getquestion", c.triviaHandler.GetQuestion).Methods("GET")
-	c.Router.HandleFunc("/api/v1/api/answerquestion", c.triviaHandler.AnswerQuestion).Methods("POST")
+	authRouter.HandleFunc("/getquestion", c.triviaHandler.GetQuestion).Methods("GET")
+	authRouter.HandleFunc("/answerquestion", c.triviaHandler.AnswerQuestion).Methods("POST")
+
+	// Game routes
+	authRouter.HandleFunc("/games", c.gameHandler.StartGame).Methods("POST")
+	authRouter.HandleFunc("/games/{gameid}/question", c.gameHandler.NextQuestion).Methods("GET")
+	authRouter.HandleFunc("/games/{gameid}/answer", c.gameHandler.AnswerQuestion).Methods("POST")
+	authRouter.HandleFunc("/games/{gameid}/summary", c.gameHandler.GameSummary).Methods("GET")
+}
+
+// WaitForDependencies waits until the dependencies of the service respond, after which the service reports
+// that it is ready. The error of ctx is returned when it is done first.
+func (c *Controller) WaitForDependencies(ctx context.Context) error {
+	return c.healthHandler.WaitForDependencies(ctx, handlers.HEALTH_CHECK_INTERVAL)
+}
+
+// Close stops the background workers of the controller, then closes the data stores.
+// The first error is returned once everything has been closed.
+func (c *Controller) Close() error {
+	var closers []io.Closer
+	if c.prefetcher != nil {
+		closers = append(closers, c.prefetcher)
+	}
+	if storeCloser, ok := c.triviaStore.(io.Closer); ok {
+		closers = append(closers, storeCloser)
+	}
+	if storeModel, isRedisModel := c.triviaStore.(*models.RedisModel); c.redisModel != nil &&
+		(!isRedisModel || storeModel != c.redisModel) {
+		closers = append(closers, c.redisModel)
+	}
+
+	var firstErr error
+	for _, closer := range closers {
+		closeErr := closer.Close()
+		if closeErr != nil {
+			c.logger.Error("Error closing controller", common.ERROR_FIELD, closeErr)
+			if firstErr == nil {
+				firstErr = closeErr
+			}
+		}
+	}
+
+	return firstErr
 }
 
-// NewController function create a new Controller and initializes new Controller object
-func NewController() *Controller {
+// NewController function create a new Controller and initializes new Controller object.
+// The controller and the components it creates log with logger.
+func NewController(logger *slog.Logger) (*Controller, error) {
 	// Create controllers component
-	log.Print("Creating controllers object...")
+	logger.Info("Creating controllers object")
 	controller = new(Controller)
+	controller.logger = logger
+
+	// Get config data
+	cfgData := config.NewConfig().LoadCfgData()
+
+	// Trivia provider selected in config
+	triviaProvider, providerErr := external.NewTriviaProvider(cfgData, logger)
+	if providerErr != nil {
+		logger.Error("Error creating trivia provider", common.ERROR_FIELD, providerErr)
+		return nil, providerErr
+	}
+
+	// Trivia store selected in config
+	triviaStore, storeErr := models.NewTriviaStore(cfgData, logger)
+	if storeErr != nil {
+		logger.Error("Error creating trivia store", common.ERROR_FIELD, storeErr)
+		return nil, storeErr
+	}
+
+	// From here on the controller is closed when it cannot be created, so the stores and the prefetcher
+	// are not left open
+	controller.triviaStore = triviaStore
+
+	// Redis is shared with the trivia store when it is the trivia store, and is only connected to otherwise
+	// when another component keeps its data there
+	redisModel, isRedisStore := triviaStore.(*models.RedisModel)
+	if !isRedisStore && usesRedis(cfgData) {
+		redisModel = models.NewRedisModel(logger)
+	}
+	controller.redisModel = redisModel
+
+	// Leaderboards and seen questions are kept in the score store selected in config
+	seenStore := models.NewScoreStore(cfgData.SeenQuestionStore, cfgData.StoreType, redisModel)
+	seenModel := models.NewSeenModel(cfgData, seenStore)
+
+	// Player handler
+	playerModel, playerErr := models.NewPlayerModel(triviaStore)
+	if playerErr != nil {
+		logger.Error("Error creating player model", common.ERROR_FIELD, playerErr)
+		controller.Close()
+		return nil, playerErr
+	}
+	controller.playerHandler = handlers.NewPlayerHandler(playerModel, seenModel)
+
+	leaderboardStore := models.NewScoreStore(cfgData.LeaderboardStore, cfgData.StoreType, redisModel)
+	leaderboardModel := models.NewLeaderboardModel(leaderboardStore)
+	controller.leaderboardHandler = handlers.NewLeaderboardHandler(leaderboardModel)
+
+	// Questions are served from a pool topped up in the background when a watermark is set
+	if cfgData.PrefetchWatermark > 0 {
+		var questionPool external.QuestionPool = external.NewMemoryPool()
+		if cfgData.PrefetchStore == models.REDIS_STORE {
+			questionPool = redisModel
+		}
+
+		controller.prefetcher = external.NewPrefetcher(triviaProvider, questionPool, cfgData.PrefetchWatermark,
+			cfgData.PrefetchCategories, cfgData.PrefetchWorkers, cfgData.PrefetchInterval)
+		triviaProvider = controller.prefetcher
+		registerPoolMetrics(controller.prefetcher)
+	}
+
+	// Answer messages for every supported locale
+	messageCatalog, catalogErr := models.NewMessageCatalog(cfgData)
+	if catalogErr != nil {
+		logger.Error("Error creating message catalog", common.ERROR_FIELD, catalogErr)
+		controller.Close()
+		return nil, catalogErr
+	}
+
+	// Free text answers are matched allowing for typos and alternate answers
+	answerMatcher, matcherErr := models.NewAnswerMatcher(cfgData)
+	if matcherErr != nil {
+		logger.Error("Error creating answer matcher", common.ERROR_FIELD, matcherErr)
+		controller.Close()
+		return nil, matcherErr
+	}
 
 	// Trivia handler
-	controller.triviaHandler = handlers.NewTriviaHandler()
+	triviaModel := models.NewTriviaModel(triviaStore, messageCatalog, answerMatcher, logger)
+	controller.triviaHandler = handlers.NewTriviaHandler(triviaProvider, triviaModel, leaderboardModel, seenModel,
+		logger)
+
+	// Game handler
+	gameModel, gameErr := models.NewGameModel(triviaStore, triviaModel)
+	if gameErr != nil {
+		logger.Error("Error creating game model", common.ERROR_FIELD, gameErr)
+		controller.Close()
+		return nil, gameErr
+	}
+	controller.gameHandler = handlers.NewGameHandler(triviaProvider, gameModel, leaderboardModel, seenModel)
+
+	// Metrics handler
+	controller.metricsHandler = handlers.NewMetricsHandler(common.Metrics)
+
+	// Requests are rate limited for each client unless the limit is turned off
+	if cfgData.RateLimitRequests > 0 {
+		rateLimiter := models.NewRateLimiter(cfgData, redisModel)
+		rateLimitHandler, rateLimitErr := handlers.NewRateLimitHandler(rateLimiter, cfgData.TrustedProxies, logger)
+		if rateLimitErr != nil {
+			logger.Error("Error creating rate limit handler", common.ERROR_FIELD, rateLimitErr)
+			controller.Close()
+			return nil, rateLimitErr
+		}
+		controller.rateLimitHandler = rateLimitHandler
+	}
+
+	// Health handler
+	controller.healthHandler = handlers.NewHealthHandler(redisModel, triviaStore, triviaProvider,
+		cfgData.HealthCheckTimeout, logger)
 
 	// Set controllers routes
 	controller.Router = mux.NewRouter()
 	controller.setupRoutes()
 
-	return controller
+	return controller, nil
+}
+
+// unexported functions
+// usesRedis reports whether a component other than the trivia store keeps its data in Redis
+func usesRedis(cfgData *config.CfgData) bool {
+	switch {
+	case models.ScoreStoreType(cfgData.LeaderboardStore, cfgData.StoreType) == models.REDIS_STORE:
+		return true
+	case models.ScoreStoreType(cfgData.SeenQuestionStore, cfgData.StoreType) == models.REDIS_STORE:
+		return true
+	case cfgData.PrefetchWatermark > 0 && cfgData.PrefetchStore == models.REDIS_STORE:
+		return true
+	case cfgData.RateLimitRequests > 0 && cfgData.RateLimitStore == models.REDIS_STORE:
+		return true
+	}
+
+	return false
+}
+
+// registerPoolMetrics adds the question pool stats of the prefetcher to the metrics
+func registerPoolMetrics(prefetcher *external.Prefetcher) {
+	poolMetrics := []struct {
+		name    string
+		help    string
+		valueFn func(stats external.PoolStats) uint64
+	}{
+		{"trivia_pool_hits_total", "Questions served from the question pool.",
+			func(stats external.PoolStats) uint64 { return stats.Hits }},
+		{"trivia_pool_misses_total", "Questions fetched live because the question pool was empty.",
+			func(stats external.PoolStats) uint64 { return stats.Misses }},
+		{"trivia_pool_fetched_total", "Questions fetched in the background for the question pool.",
+			func(stats external.PoolStats) uint64 { return stats.Fetched }},
+		{"trivia_pool_fetch_errors_total", "Background fetches for the question pool that failed.",
+			func(stats external.PoolStats) uint64 { return stats.FetchErrors }},
+	}
+
+	for _, poolMetric := range poolMetrics {
+		valueFn := poolMetric.valueFn
+		common.Metrics.SetFunc(poolMetric.name, poolMetric.help, common.COUNTER_TYPE, func() float64 {
+			return float64(valueFn(prefetcher.Stats()))
+		})
+	}
 }
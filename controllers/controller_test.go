@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/models"
+	"io"
+	"testing"
+)
+
+func TestClosePartialController(t *testing.T) {
+	logger := common.NewLogger(io.Discard, "error", "text")
+
+	// Controllers that could not be created are closed with only some of their components
+	partialControllers := map[string]*Controller{
+		"nothing created":   {logger: logger},
+		"only trivia store": {logger: logger, triviaStore: models.NewMemoryModel()},
+	}
+
+	for name, partialController := range partialControllers {
+		t.Run(name, func(t *testing.T) {
+			if closeErr := partialController.Close(); closeErr != nil {
+				t.Errorf("Close() error = %v", closeErr)
+			}
+		})
+	}
+}
+
+func TestUsesRedis(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfgData config.CfgData
+		want    bool
+	}{
+		{"memory store", config.CfgData{StoreType: "memory", PrefetchStore: "redis", RateLimitStore: "redis"}, false},
+		{"sql store", config.CfgData{StoreType: "sql"}, false},
+		{"redis store", config.CfgData{StoreType: "redis"}, true},
+		{"redis leaderboards", config.CfgData{StoreType: "sql", LeaderboardStore: "redis"}, true},
+		{"redis seen questions", config.CfgData{StoreType: "memory", SeenQuestionStore: "redis"}, true},
+		{"redis prefetch", config.CfgData{StoreType: "memory", PrefetchWatermark: 5, PrefetchStore: "redis"}, true},
+		{"memory prefetch", config.CfgData{StoreType: "memory", PrefetchWatermark: 5, PrefetchStore: "memory"}, false},
+		{"redis rate limits", config.CfgData{StoreType: "sql", RateLimitRequests: 10, RateLimitStore: "redis"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := usesRedis(&test.cfgData); got != test.want {
+				t.Errorf("usesRedis() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
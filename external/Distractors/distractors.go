@@ -0,0 +1,275 @@
+package Distractors
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// DefaultPoolSize is the number of answers kept for each category
+	DefaultPoolSize int = 200
+
+	// Years are only generated within this range
+	MinYear int = 1000
+	MaxYear int = 2100
+
+	// YearSpread is how far generated years can be from the answer
+	YearSpread int = 10
+)
+
+// Answer shapes. Distractors are picked with the same shape as the answer so they are plausible.
+const (
+	ShapeNumber string = "number"
+	ShapeYear   string = "year"
+	ShapeName   string = "name"
+	ShapeText   string = "text"
+)
+
+// answerPool holds the answers seen for a category, oldest first, without duplicates
+type answerPool struct {
+	answers []string
+	keys    map[string]bool
+}
+
+// Engine picks plausible wrong answers for a question. Answers seen for each category are cached so
+// distractors can be picked from answers to other questions in the category. Number and year answers
+// get nearby numbers and years instead.
+//
+// The engine is safe for concurrent use. Passing a seeded source to NewEngine makes the distractors
+// repeatable.
+type Engine struct {
+	mutex    sync.Mutex
+	random   *rand.Rand
+	poolSize int
+	pools    map[string]*answerPool
+}
+
+// AddAnswers adds answers to the pool for the category. When the pool is full the oldest answers are
+// removed.
+func (e *Engine) AddAnswers(category string, answers ...string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	pool, found := e.pools[category]
+	if !found {
+		pool = &answerPool{keys: make(map[string]bool)}
+		e.pools[category] = pool
+	}
+
+	for _, answer := range answers {
+		answer = strings.TrimSpace(answer)
+		key := answerKey(answer)
+		if len(key) == 0 || pool.keys[key] {
+			continue
+		}
+
+		pool.answers = append(pool.answers, answer)
+		pool.keys[key] = true
+	}
+
+	for len(pool.answers) > e.poolSize {
+		delete(pool.keys, answerKey(pool.answers[0]))
+		pool.answers = pool.answers[1:]
+	}
+}
+
+// Distractors returns up to count wrong answers for the answer, none of them equal to the answer or to
+// each other, ignoring case and spacing. Fewer are returned when not enough answers have been cached.
+//
+// Number and year answers get nearby numbers and years. Other answers get cached answers of the same
+// shape from the category, preferring answers with the same number of words, then answers of any shape
+// from the category, then answers of the same shape from other categories, then any cached answer.
+func (e *Engine) Distractors(category string, answer string, count int) []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if count <= 0 {
+		return nil
+	}
+
+	answer = strings.TrimSpace(answer)
+	shape := AnswerShape(answer)
+
+	var candidates []string
+	switch shape {
+	case ShapeNumber, ShapeYear:
+		candidates = e.numberCandidates(answer, shape, count)
+	default:
+		candidates = e.poolCandidates(category, answer, shape)
+	}
+
+	// Every candidate is looked at once, so picking always ends
+	seen := map[string]bool{answerKey(answer): true}
+	distractors := make([]string, 0, count)
+	for _, candidate := range candidates {
+		key := answerKey(candidate)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		distractors = append(distractors, candidate)
+		if len(distractors) == count {
+			break
+		}
+	}
+
+	return distractors
+}
+
+// unexported type methods
+// numberCandidates returns numbers near the answer in random order. Years stay within YearSpread of
+// the answer; other numbers are spread by a step that grows with the size of the answer.
+func (e *Engine) numberCandidates(answer string, shape string, count int) []string {
+	value, _ := strconv.Atoi(answer)
+
+	step, spread := 1, YearSpread
+	if shape == ShapeNumber {
+		// 7 gets neighbours 1 apart, 250 gets neighbours 10 apart, 45000 gets neighbours 1000 apart
+		magnitude := len(strconv.Itoa(absInt(value)))
+		if magnitude > 2 {
+			step = int(math.Pow10(magnitude - 2))
+		}
+		spread = 2 * count
+	}
+
+	var candidates []string
+	for offset := -spread; offset <= spread; offset++ {
+		candidate := value + offset*step
+		if offset == 0 || (value >= 0 && candidate < 0) {
+			continue
+		}
+		if shape == ShapeYear && (candidate < MinYear || candidate > MaxYear) {
+			continue
+		}
+
+		candidates = append(candidates, strconv.Itoa(candidate))
+	}
+
+	e.shuffle(candidates)
+
+	return candidates
+}
+
+// poolCandidates returns cached answers ordered from most to least plausible, in random order within
+// each tier
+func (e *Engine) poolCandidates(category string, answer string, shape string) []string {
+	wordCount := len(strings.Fields(answer))
+
+	var sameWordCount, sameShape, otherShape, otherCategories, anyAnswer []string
+	for _, cached := range e.poolAnswers(category) {
+		switch {
+		case AnswerShape(cached) != shape:
+			otherShape = append(otherShape, cached)
+		case len(strings.Fields(cached)) == wordCount:
+			sameWordCount = append(sameWordCount, cached)
+		default:
+			sameShape = append(sameShape, cached)
+		}
+	}
+
+	// Categories are visited in order so a seeded source gives repeatable distractors
+	poolCategories := make([]string, 0, len(e.pools))
+	for poolCategory := range e.pools {
+		if poolCategory != category {
+			poolCategories = append(poolCategories, poolCategory)
+		}
+	}
+	sort.Strings(poolCategories)
+
+	for _, poolCategory := range poolCategories {
+
+		for _, cached := range e.pools[poolCategory].answers {
+			if AnswerShape(cached) == shape {
+				otherCategories = append(otherCategories, cached)
+			} else {
+				anyAnswer = append(anyAnswer, cached)
+			}
+		}
+	}
+
+	var candidates []string
+	for _, tier := range [][]string{sameWordCount, sameShape, otherShape, otherCategories, anyAnswer} {
+		e.shuffle(tier)
+		candidates = append(candidates, tier...)
+	}
+
+	return candidates
+}
+
+// poolAnswers returns the answers cached for the category
+func (e *Engine) poolAnswers(category string) []string {
+	pool, found := e.pools[category]
+	if !found {
+		return nil
+	}
+
+	return pool.answers
+}
+
+func (e *Engine) shuffle(list []string) {
+	e.random.Shuffle(len(list), func(idx1, idx2 int) {
+		list[idx1], list[idx2] = list[idx2], list[idx1]
+	})
+}
+
+// AnswerShape returns the shape of an answer: a whole number, a year, a name made of capitalised
+// words such as a person or place, or any other text
+func AnswerShape(answer string) string {
+	answer = strings.TrimSpace(answer)
+
+	value, convErr := strconv.Atoi(answer)
+	if convErr == nil {
+		if len(answer) == 4 && value >= MinYear && value <= MaxYear {
+			return ShapeYear
+		}
+		return ShapeNumber
+	}
+
+	words := strings.Fields(answer)
+	if len(words) == 0 {
+		return ShapeText
+	}
+
+	for _, word := range words {
+		if !unicode.IsUpper([]rune(word)[0]) {
+			return ShapeText
+		}
+	}
+
+	return ShapeName
+}
+
+// NewEngine creates a distractor engine keeping up to poolSize answers for each category. source is used
+// for every random choice; a source with a fixed seed gives repeatable distractors.
+func NewEngine(poolSize int, source rand.Source) *Engine {
+	if poolSize <= 0 {
+		poolSize = DefaultPoolSize
+	}
+
+	engine := new(Engine)
+	engine.random = rand.New(source)
+	engine.poolSize = poolSize
+	engine.pools = make(map[string]*answerPool)
+
+	return engine
+}
+
+// unexported functions
+// answerKey is the form answers are compared in to find duplicates
+func answerKey(answer string) string {
+	return strings.ToLower(strings.Join(strings.Fields(answer), " "))
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
@@ -0,0 +1,228 @@
+package Distractors
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const TEST_SEED int64 = 42
+
+func TestAnswerShape(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{"42", ShapeNumber},
+		{"-7", ShapeNumber},
+		{"250000", ShapeNumber},
+		{"1969", ShapeYear},
+		{" 1066 ", ShapeYear},
+		{"999", ShapeNumber},
+		{"2500", ShapeNumber},
+		{"Paris", ShapeName},
+		{"Isaac Newton", ShapeName},
+		{"a red apple", ShapeText},
+		{"Red apple", ShapeText},
+		{"", ShapeText},
+	}
+
+	for _, test := range tests {
+		if got := AnswerShape(test.answer); got != test.want {
+			t.Errorf("AnswerShape(%q) = %q, want %q", test.answer, got, test.want)
+		}
+	}
+}
+
+func TestDistractors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     map[string][]string
+		category string
+		answer   string
+		count    int
+		check    func(t *testing.T, distractors []string)
+	}{
+		{
+			name:     "small numbers are neighbours",
+			category: "math",
+			answer:   "7",
+			count:    3,
+			check:    numbersWithin(7, 6, 1),
+		},
+		{
+			name:     "large numbers are spread by their size",
+			category: "math",
+			answer:   "45000",
+			count:    3,
+			check:    numbersWithin(45000, 6000, 1000),
+		},
+		{
+			name:     "years are nearby years",
+			category: "history",
+			answer:   "1969",
+			count:    3,
+			check:    numbersWithin(1969, YearSpread, 1),
+		},
+		{
+			name:     "years stay in range",
+			category: "history",
+			answer:   "2095",
+			count:    3,
+			check: func(t *testing.T, distractors []string) {
+				for _, distractor := range distractors {
+					if year, _ := strconv.Atoi(distractor); year > MaxYear {
+						t.Errorf("distractor %s is after %d", distractor, MaxYear)
+					}
+				}
+			},
+		},
+		{
+			name:     "names come from the category first",
+			pool:     map[string][]string{"science": {"Isaac Newton", "Marie Curie", "the atom"}, "art": {"Pablo Picasso"}},
+			category: "science",
+			answer:   "Albert Einstein",
+			count:    2,
+			check:    sameElements([]string{"Isaac Newton", "Marie Curie"}),
+		},
+		{
+			name:     "other categories fill in",
+			pool:     map[string][]string{"science": {"Isaac Newton"}, "art": {"Pablo Picasso"}},
+			category: "science",
+			answer:   "Albert Einstein",
+			count:    2,
+			check:    sameElements([]string{"Isaac Newton", "Pablo Picasso"}),
+		},
+		{
+			name:     "the answer is excluded ignoring case and spacing",
+			pool:     map[string][]string{"geography": {"paris", "  Paris ", "Rome", "Berlin"}},
+			category: "geography",
+			answer:   "PARIS",
+			count:    3,
+			check:    sameElements([]string{"Rome", "Berlin"}),
+		},
+		{
+			name:     "small pools return fewer distractors",
+			pool:     map[string][]string{"geography": {"Rome"}},
+			category: "geography",
+			answer:   "Paris",
+			count:    3,
+			check:    sameElements([]string{"Rome"}),
+		},
+		{
+			name:     "empty pools return none",
+			category: "geography",
+			answer:   "Paris",
+			count:    3,
+			check:    sameElements(nil),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewEngine(0, rand.NewSource(TEST_SEED))
+			for category, answers := range test.pool {
+				engine.AddAnswers(category, answers...)
+			}
+
+			distractors := engine.Distractors(test.category, test.answer, test.count)
+			if len(distractors) > test.count {
+				t.Errorf("Distractors() = %q, want at most %d", distractors, test.count)
+			}
+
+			seen := map[string]bool{answerKey(test.answer): true}
+			for _, distractor := range distractors {
+				if seen[answerKey(distractor)] {
+					t.Errorf("Distractors() = %q repeats %q or the answer", distractors, distractor)
+				}
+				seen[answerKey(distractor)] = true
+			}
+
+			test.check(t, distractors)
+		})
+	}
+}
+
+func TestDistractorsRepeatable(t *testing.T) {
+	answers := []string{"Rome", "Berlin", "Madrid", "Lisbon", "Vienna", "Prague"}
+
+	var results [][]string
+	for run := 0; run < 2; run++ {
+		engine := NewEngine(0, rand.NewSource(TEST_SEED))
+		engine.AddAnswers("geography", answers...)
+		results = append(results, engine.Distractors("geography", "Paris", 3))
+	}
+
+	if !reflect.DeepEqual(results[0], results[1]) {
+		t.Errorf("Distractors() = %q then %q, want the same with the same seed", results[0], results[1])
+	}
+}
+
+func TestAddAnswersEvictsOldest(t *testing.T) {
+	engine := NewEngine(3, rand.NewSource(TEST_SEED))
+	engine.AddAnswers("geography", "Rome", "Berlin", "rome", "Madrid")
+	engine.AddAnswers("geography", "Lisbon")
+
+	want := []string{"Berlin", "Madrid", "Lisbon"}
+	if got := engine.poolAnswers("geography"); !reflect.DeepEqual(got, want) {
+		t.Errorf("pool = %q, want %q", got, want)
+	}
+
+	// Evicted answers can be added again
+	engine.AddAnswers("geography", "Rome")
+	want = []string{"Madrid", "Lisbon", "Rome"}
+	if got := engine.poolAnswers("geography"); !reflect.DeepEqual(got, want) {
+		t.Errorf("pool = %q, want %q", got, want)
+	}
+}
+
+func TestDistractorsZeroCount(t *testing.T) {
+	engine := NewEngine(0, rand.NewSource(TEST_SEED))
+	engine.AddAnswers("geography", "Rome")
+
+	if distractors := engine.Distractors("geography", "Paris", 0); len(distractors) != 0 {
+		t.Errorf("Distractors(0) = %q, want none", distractors)
+	}
+}
+
+// numbersWithin checks every distractor is a multiple of step away from answer and within spread of it
+func numbersWithin(answer int, spread int, step int) func(t *testing.T, distractors []string) {
+	return func(t *testing.T, distractors []string) {
+		if len(distractors) == 0 {
+			t.Fatal("Distractors() returned none")
+		}
+
+		for _, distractor := range distractors {
+			value, convErr := strconv.Atoi(distractor)
+			if convErr != nil {
+				t.Errorf("distractor %q is not a number", distractor)
+				continue
+			}
+
+			offset := absInt(value - answer)
+			if offset == 0 || offset > spread || offset%step != 0 {
+				t.Errorf("distractor %d is not within %d of %d in steps of %d", value, spread, answer, step)
+			}
+		}
+	}
+}
+
+// sameElements checks the distractors are want in any order
+func sameElements(want []string) func(t *testing.T, distractors []string) {
+	return func(t *testing.T, distractors []string) {
+		got := map[string]bool{}
+		for _, distractor := range distractors {
+			got[distractor] = true
+		}
+
+		wantSet := map[string]bool{}
+		for _, value := range want {
+			wantSet[value] = true
+		}
+
+		if len(distractors) != len(want) || !reflect.DeepEqual(got, wantSet) {
+			t.Errorf("Distractors() = %q, want %q in any order", distractors, want)
+		}
+	}
+}
@@ -1,14 +1,16 @@
 package OpenTriviaAPI
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/external/Distractors"
 	"github.com/sflewis2970/trivia-api/messages"
-	"io"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"time"
 )
 
@@ -16,21 +18,18 @@ import (
 const (
 	RapidAPIHostKey string = "X-RapidAPI-Host"
 	RapidAPIKey     string = "X-RapidAPI-Key"
-	RapidAPIValue   string = "1f8720c0c7msh43fe783209a6813p1833b2jsnc2300c30b9a9"
 
-	TriviaURL          string = "https://trivia-by-api-ninjas.p.rapidapi.com/v1/trivia"
-	TriviaAPIHostValue string = "api-by-api-ninjas.p.rapidapi.com"
+	// Used when the URL or host are not configured. The RapidAPI key must always be configured.
+	DefaultTriviaURL  string = "https://trivia-by-api-ninjas.p.rapidapi.com/v1/trivia"
+	DefaultTriviaHost string = "api-by-api-ninjas.p.rapidapi.com"
+
+	ProviderName string = "apininjas"
 
 	TriviaCategoryCount  int = 14
 	EmptyRecordCount     int = 0
 	TriviaMaxRecordCount int = 5
 )
 
-type Message struct {
-	CongratsMsg string `json:"congrats"`
-	TryAgainMsg string `json:"tryagain"`
-}
-
 var CategoryList = [TriviaCategoryCount]string{"artliterature", "language", "sciencenature", "general", "fooddrink", "peopleplaces",
 	"geography", "historyholidays", "entertainment", "toysgames", "music", "mathematics", "religionmythology", "sportsleisure"}
 
@@ -41,91 +40,97 @@ type TriviaResponse struct {
 }
 
 type OpenTrivia struct {
+	triviaURL        string
+	rapidAPIHost     string
+	rapidAPIKey      string
+	distractorEngine *Distractors.Engine
+	httpClient       *common.HTTPClient
+	logger           *slog.Logger
 }
 
 var openTrivia *OpenTrivia
 
+// Name returns the name used to select the provider in config
+func (ot *OpenTrivia) Name() string {
+	return ProviderName
+}
+
+// Categories returns the list of categories supported by the API
+func (ot *OpenTrivia) Categories() []string {
+	return CategoryList[:]
+}
+
 // GetTrivia exported type method
-func (ot *OpenTrivia) GetTrivia(category string) (messages.Trivia, error) {
-	// Initialize data store when needed
-	categoryLen := len(category)
-	limit := 0
-	requestComplete := false
-	var apiResponseErr error
-	var apiResponses []TriviaResponse
-	apiResponsesSize := 0
-	// timestamp := ""
+func (ot *OpenTrivia) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	logger := common.LoggerFromContext(ctx, ot.logger).With("provider", ProviderName,
+		common.CATEGORY_FIELD, category)
 
 	// validate category
-	if categoryLen > 0 && !isItemInCategoryList(category) {
+	if len(category) > 0 && !isItemInCategoryList(category) {
 		errMsg := fmt.Sprintf("%s is invalid", category)
-		log.Print(errMsg)
+		logger.Warn(errMsg)
 		return messages.Trivia{}, errors.New(errMsg)
 	}
 
-	// Check for duplicates for marking the request as complete
-	for !requestComplete {
-		// Send request to API
-		apiResponses, _, apiResponseErr = ot.triviaRequest(category, limit)
-
-		// Get API Response size
-		apiResponsesSize = len(apiResponses)
-
-		if apiResponsesSize > 0 {
-			// When results are returned, make sure there are no duplicate answers
-			if !ot.containsDuplicates(apiResponses) {
-				log.Print("No duplicates found...")
-				requestComplete = true
-			} else {
-				log.Print("Found duplicates...")
-			}
-		} else {
-			// An error occurred or no results found
-			requestComplete = true
-		}
+	// Send request to API
+	apiResponses, timestamp, apiResponseErr := ot.triviaRequest(ctx, category, 0)
+	if apiResponseErr != nil {
+		// If an error occurs let the client know
+		logger.Error("Error requesting trivia", common.ERROR_FIELD, apiResponseErr)
+		return messages.Trivia{}, apiResponseErr
+	}
+
+	if len(apiResponses) == 0 {
+		errMsg := "no trivia results returned"
+		logger.Warn(errMsg)
+		return messages.Trivia{}, errors.New(errMsg)
+	}
+
+	// Every answer returned is added to the pool distractors are picked from
+	for _, apiResponse := range apiResponses {
+		ot.distractorEngine.AddAnswers(apiResponse.Category, apiResponse.Answer)
 	}
 
 	// Question (Request) Response message
 	var trivia messages.Trivia
 
-	// Build API Response
-	// trivia.Timestamp = timestamp
+	// Build API Response. Only the first result is asked, the rest fill the distractor pool.
+	trivia.QuestionID = uuid.New().String()
+	trivia.QuestionID = common.BuildUUID(trivia.QuestionID, messages.DASH, messages.ONE_SET)
+	trivia.Category = apiResponses[0].Category
+	trivia.Question = apiResponses[0].Question
+	trivia.Type = messages.MULTIPLE_CHOICE
+	trivia.Answer = apiResponses[0].Answer
+	trivia.Timestamp = timestamp
+	trivia.Distractors = ot.distractorEngine.Distractors(trivia.Category, trivia.Answer, TriviaMaxRecordCount-1)
 
-	if apiResponseErr != nil {
-		// If an error occurs let the client know
-		return messages.Trivia{}, apiResponseErr
-	} else {
-		// Since the client is no longer allowed to supply a limit
-		// there should be five items returned from the API
-		// After getting a valid response from the API, generate a question ID
-		// trivia.QuestionID = uuid.New().String()
-		// trivia.QuestionID = common.BuildUUID(trivia.QuestionID, messages.DASH, messages.ONE_SET)
-		// trivia.Category = apiResponses[0].Category
-		// trivia.Question = apiResponses[0].Question
-		// trivia.Answer = apiResponses[0].Answer
-
-		// Build choices string
-		var choiceList []string
-		for idx := 0; idx < apiResponsesSize; idx++ {
-			choiceList = append(choiceList, apiResponses[idx].Answer)
-		}
+	logger.Debug("Trivia received", common.QUESTION_ID_FIELD, trivia.QuestionID)
 
-		// Shuttle list
-		choiceList = common.ShuffleList(choiceList)
+	// Build choices string
+	choiceList := make([]string, 0, len(trivia.Distractors)+1)
+	choiceList = append(choiceList, trivia.Answer)
+	choiceList = append(choiceList, trivia.Distractors...)
 
-		// Add a message filler to the beginning of the list
-		// trivia.Choices = append(trivia.Choices, messages.MAKE_SELECTION_MSG)
-		// trivia.Choices = append(trivia.Choices, choiceList...)
-	}
+	// Shuttle list
+	choiceList = common.ShuffleList(choiceList)
+
+	// Add a message filler to the beginning of the list
+	trivia.Choices = append(trivia.Choices, messages.MAKE_SELECTION_MSG)
+	trivia.Choices = append(trivia.Choices, choiceList...)
 
 	return trivia, nil
 }
 
+// Ping checks that the API is reachable without asking for a question
+func (ot *OpenTrivia) Ping(ctx context.Context) error {
+	return ot.httpClient.Ping(ctx, ot.triviaURL)
+}
+
 // unexported type method
 // triviaRequest is a function that sends a request to the API to retrieve the api
-func (ot *OpenTrivia) triviaRequest(category string, limit int) ([]TriviaResponse, string, error) {
+func (ot *OpenTrivia) triviaRequest(ctx context.Context, category string, limit int) ([]TriviaResponse, string, error) {
 	// Build URL string
-	url := TriviaURL
+	url := ot.triviaURL
 
 	// Add optional parameters string
 	// Get category string
@@ -147,76 +152,62 @@ func (ot *OpenTrivia) triviaRequest(category string, limit int) ([]TriviaRespons
 	}
 
 	headers := []common.HTTPHeader{
-		{Key: RapidAPIHostKey, Value: TriviaAPIHostValue},
-		{Key: RapidAPIKey, Value: RapidAPIValue},
-	}
-
-	// Create a http request
-	method := "GET"
-	request, requestErr := common.CreateRequest(method, url, headers, nil)
-	if requestErr != nil {
-		log.Print("Error creating request...")
-		return nil, "", requestErr
+		{Key: RapidAPIHostKey, Value: ot.rapidAPIHost},
+		{Key: RapidAPIKey, Value: ot.rapidAPIKey},
 	}
 
 	// Execute request
-	response, responseErr := common.ExecuteRequest(request)
+	body, responseErr := ot.httpClient.Get(ctx, url, headers)
 	if responseErr != nil {
-		log.Print("Error executing request...")
 		return nil, "", responseErr
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-		}
-	}(response.Body)
 
 	// Get timestamp right after receiving a valid request
-	timestamp := common.GetFormattedTime(time.Now(), "Mon Jan 2 15:04:05 2006")
-
-	// Parse request body
-	body, readErr := ioutil.ReadAll(response.Body)
-	if readErr != nil {
-		log.Print("Error reading response...", readErr)
-		return nil, "", readErr
-	}
+	timestamp := common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
 
 	// Parse response into JSON format
 	responses := make([]TriviaResponse, 0)
 	unmarshalErr := json.Unmarshal(body, &responses)
 	if unmarshalErr != nil {
-		log.Print("Error unmarshalling response...")
-		return nil, "", unmarshalErr
+		return nil, "", fmt.Errorf("error unmarshalling response: %w", unmarshalErr)
 	}
 
 	// Return a valid response (in JSON format) as well as a timestamp
 	return responses, timestamp, nil
 }
 
-// containsDuplicates checks the slice for any duplicate items
-func (ot *OpenTrivia) containsDuplicates(items []TriviaResponse) bool {
-	// Initialize the map for usage
-	itemsMap := make(map[string]int)
+// NewOpenTrivia creates the API-Ninjas provider picking distractors with distractorEngine, sending
+// requests with httpClient and logging with logger. An error is returned when the RapidAPI key is not set.
+func NewOpenTrivia(triviaURL string, rapidAPIHost string, rapidAPIKey string,
+	distractorEngine *Distractors.Engine, httpClient *common.HTTPClient, logger *slog.Logger) (*OpenTrivia, error) {
+	logger.Info("Creating API object", "provider", ProviderName)
+
+	if len(rapidAPIKey) == 0 {
+		errMsg := "trivia provider " + ProviderName + " requires a RapidAPI key, set " + config.RAPIDAPI_KEY +
+			" or rapidapikey in the config or secrets file"
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
 
-	// Since maps uses unique keys, use the string value of answer to be the key
-	for idx, item := range items {
-		itemsMap[item.Answer] = idx + 1
+	if len(triviaURL) == 0 {
+		triviaURL = DefaultTriviaURL
 	}
 
-	// If the size of the map is the same size of the slice, then there are no duplicates
-	if len(itemsMap) != len(items) {
-		return true
+	if len(rapidAPIHost) == 0 {
+		rapidAPIHost = DefaultTriviaHost
 	}
 
-	// Otherwise return false
-	return false
-}
+	logger.Info("Using RapidAPI host", "host", rapidAPIHost, "key", config.RedactSecret(rapidAPIKey))
 
-func NewOpenTrivia() *OpenTrivia {
-	log.Print("Creating API object...")
 	openTrivia = new(OpenTrivia)
-
-	return openTrivia
+	openTrivia.triviaURL = triviaURL
+	openTrivia.rapidAPIHost = rapidAPIHost
+	openTrivia.rapidAPIKey = rapidAPIKey
+	openTrivia.distractorEngine = distractorEngine
+	openTrivia.httpClient = httpClient
+	openTrivia.logger = logger
+
+	return openTrivia, nil
 }
 
 // unexported functions
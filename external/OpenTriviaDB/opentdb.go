@@ -0,0 +1,522 @@
+package OpenTriviaDB
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/external/OpenTriviaAPI"
+	"github.com/sflewis2970/trivia-api/messages"
+	"html"
+	"log/slog"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderName string = "opentdb"
+
+	DefaultBaseURL string = "https://opentdb.com"
+	QuestionPath   string = "/api.php"
+	TokenPath      string = "/api_token.php"
+
+	TokenRequestCommand string = "request"
+	TokenResetCommand   string = "reset"
+)
+
+// Open Trivia DB response codes
+const (
+	ResponseSuccess         int = 0
+	ResponseNoResults       int = 1
+	ResponseInvalidParam    int = 2
+	ResponseTokenNotFound   int = 3
+	ResponseTokenEmpty      int = 4
+	ResponseRateLimitExceed int = 5
+)
+
+// Open Trivia DB encodings
+const (
+	EncodingDefault string = ""
+	EncodingURL3986 string = "url3986"
+	EncodingBase64  string = "base64"
+)
+
+// Open Trivia DB filter values
+const (
+	DifficultyEasy   string = "easy"
+	DifficultyMedium string = "medium"
+	DifficultyHard   string = "hard"
+
+	TypeMultiple string = "multiple"
+	TypeBoolean  string = "boolean"
+)
+
+// DBCategory maps an Open Trivia DB category onto an entry in OpenTriviaAPI.CategoryList
+type DBCategory struct {
+	ID       int
+	Name     string
+	Category string
+}
+
+// DBCategoryList contains the Open Trivia DB categories and the category each one is served as
+var DBCategoryList = []DBCategory{
+	{ID: 9, Name: "General Knowledge", Category: "general"},
+	{ID: 10, Name: "Entertainment: Books", Category: "artliterature"},
+	{ID: 11, Name: "Entertainment: Film", Category: "entertainment"},
+	{ID: 12, Name: "Entertainment: Music", Category: "music"},
+	{ID: 13, Name: "Entertainment: Musicals & Theatres", Category: "entertainment"},
+	{ID: 14, Name: "Entertainment: Television", Category: "entertainment"},
+	{ID: 15, Name: "Entertainment: Video Games", Category: "toysgames"},
+	{ID: 16, Name: "Entertainment: Board Games", Category: "toysgames"},
+	{ID: 17, Name: "Science & Nature", Category: "sciencenature"},
+	{ID: 18, Name: "Science: Computers", Category: "sciencenature"},
+	{ID: 19, Name: "Science: Mathematics", Category: "mathematics"},
+	{ID: 20, Name: "Mythology", Category: "religionmythology"},
+	{ID: 21, Name: "Sports", Category: "sportsleisure"},
+	{ID: 22, Name: "Geography", Category: "geography"},
+	{ID: 23, Name: "History", Category: "historyholidays"},
+	{ID: 24, Name: "Politics", Category: "peopleplaces"},
+	{ID: 25, Name: "Art", Category: "artliterature"},
+	{ID: 26, Name: "Celebrities", Category: "peopleplaces"},
+	{ID: 27, Name: "Animals", Category: "sciencenature"},
+	{ID: 28, Name: "Vehicles", Category: "sportsleisure"},
+	{ID: 29, Name: "Entertainment: Comics", Category: "entertainment"},
+	{ID: 30, Name: "Science: Gadgets", Category: "sciencenature"},
+	{ID: 31, Name: "Entertainment: Japanese Anime & Manga", Category: "entertainment"},
+	{ID: 32, Name: "Entertainment: Cartoon & Animations", Category: "entertainment"},
+}
+
+type DBResult struct {
+	Category         string   `json:"category"`
+	Type             string   `json:"type"`
+	Difficulty       string   `json:"difficulty"`
+	Question         string   `json:"question"`
+	CorrectAnswer    string   `json:"correct_answer"`
+	IncorrectAnswers []string `json:"incorrect_answers"`
+}
+
+type DBResponse struct {
+	ResponseCode int        `json:"response_code"`
+	Results      []DBResult `json:"results"`
+}
+
+type TokenResponse struct {
+	ResponseCode    int    `json:"response_code"`
+	ResponseMessage string `json:"response_message"`
+	Token           string `json:"token"`
+}
+
+// OpenTriviaDB is a client for the Open Trivia DB API
+type OpenTriviaDB struct {
+	baseURL    string
+	difficulty string
+	qType      string
+	encoding   string
+
+	httpClient *common.HTTPClient
+
+	tokenMutex sync.Mutex
+	token      string
+
+	logger *slog.Logger
+}
+
+// Name returns the name used to select the provider in config
+func (otdb *OpenTriviaDB) Name() string {
+	return ProviderName
+}
+
+// Categories returns the categories that can be served by Open Trivia DB
+func (otdb *OpenTriviaDB) Categories() []string {
+	var categories []string
+	for _, category := range OpenTriviaAPI.CategoryList {
+		if len(categoryIDs(category)) > 0 {
+			categories = append(categories, category)
+		}
+	}
+
+	return categories
+}
+
+// GetTrivia returns a single question from Open Trivia DB for the requested category
+// using the configured difficulty
+func (otdb *OpenTriviaDB) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	return otdb.getTrivia(ctx, category, otdb.difficulty)
+}
+
+// GetTriviaByDifficulty returns a single question from Open Trivia DB for the requested
+// category and difficulty. An empty difficulty uses the configured difficulty.
+func (otdb *OpenTriviaDB) GetTriviaByDifficulty(ctx context.Context, category string, difficulty string) (messages.Trivia, error) {
+	if len(difficulty) == 0 {
+		difficulty = otdb.difficulty
+	}
+
+	if !isValidDifficulty(difficulty) {
+		errMsg := fmt.Sprintf("open trivia db difficulty %s is invalid", difficulty)
+		otdb.logFor(ctx).Warn(errMsg)
+		return messages.Trivia{}, errors.New(errMsg)
+	}
+
+	return otdb.getTrivia(ctx, category, difficulty)
+}
+
+// Ping checks that the API is reachable without asking for a question
+func (otdb *OpenTriviaDB) Ping(ctx context.Context) error {
+	return otdb.httpClient.Ping(ctx, otdb.baseURL+QuestionPath)
+}
+
+// unexported type methods
+// logFor returns the logger for a request to the API
+func (otdb *OpenTriviaDB) logFor(ctx context.Context) *slog.Logger {
+	return common.LoggerFromContext(ctx, otdb.logger).With("provider", ProviderName)
+}
+
+// getTrivia requests a single question and builds the trivia message
+func (otdb *OpenTriviaDB) getTrivia(ctx context.Context, category string, difficulty string) (messages.Trivia, error) {
+	logger := otdb.logFor(ctx).With(common.CATEGORY_FIELD, category)
+
+	// validate category
+	categoryID := 0
+	if len(category) > 0 {
+		ids := categoryIDs(category)
+		if len(ids) == 0 {
+			errMsg := fmt.Sprintf("%s is invalid", category)
+			logger.Warn(errMsg)
+			return messages.Trivia{}, errors.New(errMsg)
+		}
+
+		// Pick one of the Open Trivia DB categories mapped to the category
+		categoryID = ids[rand.Intn(len(ids))]
+	}
+
+	dbResult, requestErr := otdb.questionRequest(ctx, categoryID, difficulty)
+	if requestErr != nil {
+		return messages.Trivia{}, requestErr
+	}
+
+	decodedResult, decodeErr := otdb.decodeResult(dbResult)
+	if decodeErr != nil {
+		logger.Error("Error decoding result", common.ERROR_FIELD, decodeErr)
+		return messages.Trivia{}, decodeErr
+	}
+
+	// Build trivia message
+	var trivia messages.Trivia
+	trivia.QuestionID = uuid.New().String()
+	trivia.QuestionID = common.BuildUUID(trivia.QuestionID, messages.DASH, messages.ONE_SET)
+	trivia.Category = category
+	if len(trivia.Category) == 0 {
+		trivia.Category = categoryFromName(decodedResult.Category)
+	}
+	trivia.Question = decodedResult.Question
+	trivia.Answer = decodedResult.CorrectAnswer
+	trivia.Difficulty = decodedResult.Difficulty
+	trivia.Timestamp = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+
+	// Build choices string
+	var choiceList []string
+	if decodedResult.Type == TypeBoolean {
+		// True and False are always offered in the same order
+		trivia.Type = messages.BOOLEAN
+		choiceList = []string{messages.TRUE_ANSWER, messages.FALSE_ANSWER}
+	} else {
+		trivia.Type = messages.MULTIPLE_CHOICE
+		trivia.Distractors = decodedResult.IncorrectAnswers
+
+		choiceList = make([]string, 0, len(decodedResult.IncorrectAnswers)+1)
+		choiceList = append(choiceList, decodedResult.CorrectAnswer)
+		choiceList = append(choiceList, decodedResult.IncorrectAnswers...)
+
+		// Shuffle list
+		choiceList = common.ShuffleList(choiceList)
+	}
+
+	// Add a message filler to the beginning of the list
+	trivia.Choices = append(trivia.Choices, messages.MAKE_SELECTION_MSG)
+	trivia.Choices = append(trivia.Choices, choiceList...)
+
+	return trivia, nil
+}
+
+// questionRequest requests a single question, renewing or resetting the session token when needed
+func (otdb *OpenTriviaDB) questionRequest(ctx context.Context, categoryID int, difficulty string) (DBResult, error) {
+	token, tokenErr := otdb.sessionToken(ctx)
+	if tokenErr != nil {
+		return DBResult{}, tokenErr
+	}
+
+	dbResponse, requestErr := otdb.apiRequest(ctx, categoryID, difficulty, token)
+	if requestErr != nil {
+		return DBResult{}, requestErr
+	}
+
+	logger := otdb.logFor(ctx)
+	switch dbResponse.ResponseCode {
+	case ResponseTokenNotFound:
+		// Token expired, request a new one and try again
+		logger.Info("Session token not found, requesting a new token")
+		token, tokenErr = otdb.renewToken(ctx, TokenRequestCommand, "")
+		if tokenErr != nil {
+			return DBResult{}, tokenErr
+		}
+		dbResponse, requestErr = otdb.apiRequest(ctx, categoryID, difficulty, token)
+	case ResponseTokenEmpty:
+		// Every question has been served for this token, reset it and try again
+		logger.Info("Session token exhausted, resetting token")
+		token, tokenErr = otdb.renewToken(ctx, TokenResetCommand, token)
+		if tokenErr != nil {
+			return DBResult{}, tokenErr
+		}
+		dbResponse, requestErr = otdb.apiRequest(ctx, categoryID, difficulty, token)
+	}
+
+	if requestErr != nil {
+		return DBResult{}, requestErr
+	}
+
+	if dbResponse.ResponseCode != ResponseSuccess {
+		errMsg := fmt.Sprintf("open trivia db request failed: %s", responseCodeMsg(dbResponse.ResponseCode))
+		logger.Error(errMsg)
+		return DBResult{}, errors.New(errMsg)
+	}
+
+	if len(dbResponse.Results) == 0 {
+		errMsg := "open trivia db returned no results"
+		logger.Error(errMsg)
+		return DBResult{}, errors.New(errMsg)
+	}
+
+	return dbResponse.Results[0], nil
+}
+
+// apiRequest sends a question request to the API
+func (otdb *OpenTriviaDB) apiRequest(ctx context.Context, categoryID int, difficulty string, token string) (DBResponse, error) {
+	params := url.Values{}
+	params.Set("amount", "1")
+	if categoryID > 0 {
+		params.Set("category", strconv.Itoa(categoryID))
+	}
+	if len(difficulty) > 0 {
+		params.Set("difficulty", difficulty)
+	}
+	if len(otdb.qType) > 0 {
+		params.Set("type", otdb.qType)
+	}
+	if len(otdb.encoding) > 0 {
+		params.Set("encode", otdb.encoding)
+	}
+	if len(token) > 0 {
+		params.Set("token", token)
+	}
+
+	var dbResponse DBResponse
+	getErr := otdb.getJSON(ctx, otdb.baseURL+QuestionPath+"?"+params.Encode(), &dbResponse)
+	if getErr != nil {
+		return DBResponse{}, getErr
+	}
+
+	return dbResponse, nil
+}
+
+// sessionToken returns the current session token, requesting one when none is held
+func (otdb *OpenTriviaDB) sessionToken(ctx context.Context) (string, error) {
+	otdb.tokenMutex.Lock()
+	token := otdb.token
+	otdb.tokenMutex.Unlock()
+
+	if len(token) > 0 {
+		return token, nil
+	}
+
+	return otdb.renewToken(ctx, TokenRequestCommand, "")
+}
+
+// renewToken requests a new session token or resets the current one
+func (otdb *OpenTriviaDB) renewToken(ctx context.Context, command string, token string) (string, error) {
+	params := url.Values{}
+	params.Set("command", command)
+	if len(token) > 0 {
+		params.Set("token", token)
+	}
+
+	var tokenResponse TokenResponse
+	getErr := otdb.getJSON(ctx, otdb.baseURL+TokenPath+"?"+params.Encode(), &tokenResponse)
+	if getErr != nil {
+		return "", getErr
+	}
+
+	if tokenResponse.ResponseCode != ResponseSuccess || len(tokenResponse.Token) == 0 {
+		errMsg := fmt.Sprintf("open trivia db token %s failed: %s", command, responseCodeMsg(tokenResponse.ResponseCode))
+		otdb.logFor(ctx).Error(errMsg)
+		return "", errors.New(errMsg)
+	}
+
+	otdb.tokenMutex.Lock()
+	otdb.token = tokenResponse.Token
+	otdb.tokenMutex.Unlock()
+
+	return tokenResponse.Token, nil
+}
+
+// getJSON sends a GET request and unmarshals the JSON response body into v
+func (otdb *OpenTriviaDB) getJSON(ctx context.Context, url string, v interface{}) error {
+	// Execute request
+	body, responseErr := otdb.httpClient.Get(ctx, url, nil)
+	if responseErr != nil {
+		otdb.logFor(ctx).Error("Error executing request", common.ERROR_FIELD, responseErr)
+		return responseErr
+	}
+
+	// Parse response into JSON format
+	unmarshalErr := json.Unmarshal(body, v)
+	if unmarshalErr != nil {
+		otdb.logFor(ctx).Error("Error unmarshalling response", common.ERROR_FIELD, unmarshalErr)
+		return unmarshalErr
+	}
+
+	return nil
+}
+
+// decodeResult decodes the text fields of a result using the configured encoding
+func (otdb *OpenTriviaDB) decodeResult(dbResult DBResult) (DBResult, error) {
+	var decodeErr error
+	decode := func(value string) string {
+		if decodeErr != nil {
+			return ""
+		}
+
+		var decoded string
+		decoded, decodeErr = decodeString(otdb.encoding, value)
+		return decoded
+	}
+
+	decodedResult := DBResult{
+		Category:      decode(dbResult.Category),
+		Type:          decode(dbResult.Type),
+		Difficulty:    decode(dbResult.Difficulty),
+		Question:      decode(dbResult.Question),
+		CorrectAnswer: decode(dbResult.CorrectAnswer),
+	}
+	for _, incorrectAnswer := range dbResult.IncorrectAnswers {
+		decodedResult.IncorrectAnswers = append(decodedResult.IncorrectAnswers, decode(incorrectAnswer))
+	}
+
+	if decodeErr != nil {
+		return DBResult{}, decodeErr
+	}
+
+	return decodedResult, nil
+}
+
+// NewOpenTriviaDB creates an Open Trivia DB client sending requests with httpClient. An empty baseURL uses
+// the public API.
+func NewOpenTriviaDB(baseURL string, difficulty string, qType string, encoding string,
+	httpClient *common.HTTPClient) (*OpenTriviaDB, error) {
+	logger := slog.Default()
+	logger.Info("Creating API object", "provider", ProviderName)
+
+	if !isValidDifficulty(difficulty) {
+		return nil, fmt.Errorf("open trivia db difficulty %s is invalid", difficulty)
+	}
+
+	switch qType {
+	case "", TypeMultiple, TypeBoolean:
+	default:
+		return nil, fmt.Errorf("open trivia db type %s is invalid", qType)
+	}
+
+	switch encoding {
+	case EncodingDefault, EncodingURL3986, EncodingBase64:
+	default:
+		return nil, fmt.Errorf("open trivia db encoding %s is invalid", encoding)
+	}
+
+	if len(baseURL) == 0 {
+		baseURL = DefaultBaseURL
+	}
+
+	openTriviaDB := new(OpenTriviaDB)
+	openTriviaDB.baseURL = baseURL
+	openTriviaDB.difficulty = difficulty
+	openTriviaDB.qType = qType
+	openTriviaDB.encoding = encoding
+	openTriviaDB.httpClient = httpClient
+	openTriviaDB.logger = logger
+
+	return openTriviaDB, nil
+}
+
+// unexported functions
+func isValidDifficulty(difficulty string) bool {
+	switch difficulty {
+	case "", DifficultyEasy, DifficultyMedium, DifficultyHard:
+		return true
+	}
+
+	return false
+}
+
+// categoryIDs returns the Open Trivia DB category IDs mapped to category
+func categoryIDs(category string) []int {
+	var ids []int
+	for _, dbCategory := range DBCategoryList {
+		if dbCategory.Category == category {
+			ids = append(ids, dbCategory.ID)
+		}
+	}
+
+	return ids
+}
+
+// categoryFromName returns the category mapped to an Open Trivia DB category name
+func categoryFromName(name string) string {
+	for _, dbCategory := range DBCategoryList {
+		if dbCategory.Name == name {
+			return dbCategory.Category
+		}
+	}
+
+	return ""
+}
+
+// decodeString decodes a single value returned by the API
+func decodeString(encoding string, value string) (string, error) {
+	switch encoding {
+	case EncodingBase64:
+		decoded, decodeErr := base64.StdEncoding.DecodeString(value)
+		if decodeErr != nil {
+			return "", decodeErr
+		}
+		return string(decoded), nil
+	case EncodingURL3986:
+		return url.PathUnescape(value)
+	default:
+		// The default encoding uses HTML entities
+		return html.UnescapeString(value), nil
+	}
+}
+
+func responseCodeMsg(responseCode int) string {
+	switch responseCode {
+	case ResponseSuccess:
+		return "success"
+	case ResponseNoResults:
+		return "no results"
+	case ResponseInvalidParam:
+		return "invalid parameter"
+	case ResponseTokenNotFound:
+		return "token not found"
+	case ResponseTokenEmpty:
+		return "token empty"
+	case ResponseRateLimitExceed:
+		return "rate limit exceeded"
+	default:
+		return fmt.Sprintf("unknown response code %d", responseCode)
+	}
+}
@@ -0,0 +1,375 @@
+package OpenTriviaDB
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeOpenTDB is a stand-in for the Open Trivia DB API. Question requests are answered with the queued
+// response codes in turn, then with success.
+type fakeOpenTDB struct {
+	mutex            sync.Mutex
+	result           DBResult
+	responseCodes    []int
+	tokens           int
+	tokenRequests    []url.Values
+	questionRequests []url.Values
+}
+
+func (fake *fakeOpenTDB) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	params := r.URL.Query()
+	switch r.URL.Path {
+	case TokenPath:
+		fake.tokenRequests = append(fake.tokenRequests, params)
+
+		tokenResponse := TokenResponse{ResponseCode: ResponseSuccess}
+		switch params.Get("command") {
+		case TokenRequestCommand:
+			fake.tokens++
+			tokenResponse.Token = "token" + strconv.Itoa(fake.tokens)
+		case TokenResetCommand:
+			tokenResponse.Token = params.Get("token")
+		}
+		_ = json.NewEncoder(rw).Encode(tokenResponse)
+	case QuestionPath:
+		fake.questionRequests = append(fake.questionRequests, params)
+
+		dbResponse := DBResponse{ResponseCode: ResponseSuccess}
+		if len(fake.responseCodes) > 0 {
+			dbResponse.ResponseCode = fake.responseCodes[0]
+			fake.responseCodes = fake.responseCodes[1:]
+		}
+		if dbResponse.ResponseCode == ResponseSuccess {
+			dbResponse.Results = []DBResult{fake.result}
+		}
+		_ = json.NewEncoder(rw).Encode(dbResponse)
+	default:
+		http.NotFound(rw, r)
+	}
+}
+
+var capitalResult = DBResult{
+	Category:         "Geography",
+	Type:             TypeMultiple,
+	Difficulty:       DifficultyEasy,
+	Question:         "What is the capital of France?",
+	CorrectAnswer:    "Paris",
+	IncorrectAnswers: []string{"London", "Rome", "Berlin"},
+}
+
+// newTestOpenTriviaDB starts a stand-in server answering with result and a client sending requests to it
+func newTestOpenTriviaDB(t *testing.T, result DBResult, difficulty string, qType string,
+	encoding string) (*OpenTriviaDB, *fakeOpenTDB) {
+	t.Helper()
+
+	fake := &fakeOpenTDB{result: result}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	openTriviaDB, dbErr := NewOpenTriviaDB(server.URL, difficulty, qType, encoding,
+		common.NewHTTPClient(common.HTTPClientConfig{}))
+	if dbErr != nil {
+		t.Fatalf("NewOpenTriviaDB: %v", dbErr)
+	}
+
+	return openTriviaDB, fake
+}
+
+func TestGetTriviaResponseCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		responseCodes []int
+		wantErr       bool
+
+		// wantCommands are the token commands sent, wantTokens the token sent with each question request
+		wantCommands []string
+		wantTokens   []string
+	}{
+		{"success", nil, false, []string{"request"}, []string{"token1"}},
+		{"no results", []int{ResponseNoResults}, true, []string{"request"}, []string{"token1"}},
+		{"invalid parameter", []int{ResponseInvalidParam}, true, []string{"request"}, []string{"token1"}},
+		{"token not found", []int{ResponseTokenNotFound}, false, []string{"request", "request"},
+			[]string{"token1", "token2"}},
+		{"token not found again", []int{ResponseTokenNotFound, ResponseTokenNotFound}, true,
+			[]string{"request", "request"}, []string{"token1", "token2"}},
+		{"token empty", []int{ResponseTokenEmpty}, false, []string{"request", "reset"},
+			[]string{"token1", "token1"}},
+		{"rate limit exceeded", []int{ResponseRateLimitExceed}, true, []string{"request"}, []string{"token1"}},
+		{"unknown response code", []int{42}, true, []string{"request"}, []string{"token1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			openTriviaDB, fake := newTestOpenTriviaDB(t, capitalResult, "", "", EncodingDefault)
+			fake.responseCodes = test.responseCodes
+
+			trivia, triviaErr := openTriviaDB.GetTrivia(context.Background(), "geography")
+			if test.wantErr {
+				if triviaErr == nil {
+					t.Errorf("GetTrivia returned %+v, want an error", trivia)
+				}
+			} else if triviaErr != nil {
+				t.Fatalf("GetTrivia: %v", triviaErr)
+			} else if trivia.Question != capitalResult.Question || trivia.Answer != capitalResult.CorrectAnswer {
+				t.Errorf("GetTrivia returned %+v", trivia)
+			}
+
+			var commands, tokens []string
+			for _, params := range fake.tokenRequests {
+				commands = append(commands, params.Get("command"))
+			}
+			for _, params := range fake.questionRequests {
+				tokens = append(tokens, params.Get("token"))
+			}
+			if !reflect.DeepEqual(commands, test.wantCommands) {
+				t.Errorf("token commands = %q, want %q", commands, test.wantCommands)
+			}
+			if !reflect.DeepEqual(tokens, test.wantTokens) {
+				t.Errorf("question tokens = %q, want %q", tokens, test.wantTokens)
+			}
+		})
+	}
+}
+
+func TestSessionTokenIsReused(t *testing.T) {
+	openTriviaDB, fake := newTestOpenTriviaDB(t, capitalResult, "", "", EncodingDefault)
+
+	for idx := 0; idx < 3; idx++ {
+		_, triviaErr := openTriviaDB.GetTrivia(context.Background(), "")
+		if triviaErr != nil {
+			t.Fatalf("GetTrivia %d: %v", idx, triviaErr)
+		}
+	}
+
+	if len(fake.tokenRequests) != 1 {
+		t.Errorf("sent %d token requests, want 1", len(fake.tokenRequests))
+	}
+
+	// A reset keeps the token for the next question
+	fake.responseCodes = []int{ResponseTokenEmpty}
+	_, triviaErr := openTriviaDB.GetTrivia(context.Background(), "")
+	if triviaErr != nil {
+		t.Fatalf("GetTrivia after reset: %v", triviaErr)
+	}
+	resetRequest := fake.tokenRequests[len(fake.tokenRequests)-1]
+	if resetRequest.Get("command") != TokenResetCommand || resetRequest.Get("token") != "token1" {
+		t.Errorf("reset request = %v, want a reset of token1", resetRequest)
+	}
+	for _, params := range fake.questionRequests {
+		if params.Get("token") != "token1" {
+			t.Errorf("question request token = %q, want token1", params.Get("token"))
+		}
+	}
+}
+
+func TestGetTriviaDecodesEncodings(t *testing.T) {
+	htmlResult := DBResult{
+		Category:         "Entertainment: Books",
+		Type:             TypeMultiple,
+		Difficulty:       DifficultyMedium,
+		Question:         "Who wrote &quot;Pride &amp; Prejudice&quot;?",
+		CorrectAnswer:    "Jane Austen",
+		IncorrectAnswers: []string{"Charlotte Bront&euml;", "Mary Shelley", "George Eliot"},
+	}
+	wantQuestion := `Who wrote "Pride & Prejudice"?`
+	wantDistractors := []string{"Charlotte Brontë", "Mary Shelley", "George Eliot"}
+
+	encodeResult := func(encode func(string) string) DBResult {
+		encoded := DBResult{
+			Category:      encode("Entertainment: Books"),
+			Type:          encode(TypeMultiple),
+			Difficulty:    encode(DifficultyMedium),
+			Question:      encode(wantQuestion),
+			CorrectAnswer: encode("Jane Austen"),
+		}
+		for _, distractor := range wantDistractors {
+			encoded.IncorrectAnswers = append(encoded.IncorrectAnswers, encode(distractor))
+		}
+
+		return encoded
+	}
+
+	tests := []struct {
+		encoding string
+		result   DBResult
+	}{
+		{EncodingDefault, htmlResult},
+		{EncodingBase64, encodeResult(func(value string) string {
+			return base64.StdEncoding.EncodeToString([]byte(value))
+		})},
+		{EncodingURL3986, encodeResult(func(value string) string {
+			return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+		})},
+	}
+
+	for _, test := range tests {
+		t.Run("encoding "+test.encoding, func(t *testing.T) {
+			openTriviaDB, fake := newTestOpenTriviaDB(t, test.result, "", "", test.encoding)
+
+			trivia, triviaErr := openTriviaDB.GetTrivia(context.Background(), "")
+			if triviaErr != nil {
+				t.Fatalf("GetTrivia: %v", triviaErr)
+			}
+
+			if got := fake.questionRequests[0].Get("encode"); got != test.encoding {
+				t.Errorf("encode parameter = %q, want %q", got, test.encoding)
+			}
+			if trivia.Question != wantQuestion || trivia.Answer != "Jane Austen" ||
+				trivia.Difficulty != DifficultyMedium {
+				t.Errorf("trivia = %+v", trivia)
+			}
+			if !reflect.DeepEqual(trivia.Distractors, wantDistractors) {
+				t.Errorf("distractors = %q, want %q", trivia.Distractors, wantDistractors)
+			}
+
+			// Questions without a category are served as the category their Open Trivia DB category maps to
+			if trivia.Category != "artliterature" {
+				t.Errorf("category = %q, want artliterature", trivia.Category)
+			}
+		})
+	}
+
+	openTriviaDB, _ := newTestOpenTriviaDB(t, DBResult{Question: "not base64!"}, "", "", EncodingBase64)
+	_, triviaErr := openTriviaDB.GetTrivia(context.Background(), "")
+	if triviaErr == nil {
+		t.Error("GetTrivia returned no error for a result that is not base64")
+	}
+}
+
+func TestGetTriviaFilters(t *testing.T) {
+	openTriviaDB, fake := newTestOpenTriviaDB(t, capitalResult, DifficultyHard, TypeMultiple, EncodingDefault)
+	ctx := context.Background()
+
+	trivia, triviaErr := openTriviaDB.GetTrivia(ctx, "mathematics")
+	if triviaErr != nil {
+		t.Fatalf("GetTrivia: %v", triviaErr)
+	}
+	params := fake.questionRequests[0]
+	if params.Get("amount") != "1" || params.Get("category") != "19" || params.Get("difficulty") != DifficultyHard ||
+		params.Get("type") != TypeMultiple {
+		t.Errorf("question request = %v", params)
+	}
+	if trivia.Category != "mathematics" || trivia.Type != messages.MULTIPLE_CHOICE {
+		t.Errorf("trivia = %+v", trivia)
+	}
+
+	// The difficulty asked for replaces the configured difficulty
+	_, triviaErr = openTriviaDB.GetTriviaByDifficulty(ctx, "", DifficultyEasy)
+	if triviaErr != nil {
+		t.Fatalf("GetTriviaByDifficulty: %v", triviaErr)
+	}
+	params = fake.questionRequests[1]
+	if params.Get("difficulty") != DifficultyEasy || params.Has("category") {
+		t.Errorf("question request = %v", params)
+	}
+
+	_, triviaErr = openTriviaDB.GetTriviaByDifficulty(ctx, "", "impossible")
+	if triviaErr == nil {
+		t.Error("GetTriviaByDifficulty returned no error for an invalid difficulty")
+	}
+
+	_, triviaErr = openTriviaDB.GetTrivia(ctx, "astrology")
+	if triviaErr == nil {
+		t.Error("GetTrivia returned no error for an unmapped category")
+	}
+
+	// Categories mapped onto several Open Trivia DB categories ask for one of them
+	_, triviaErr = openTriviaDB.GetTrivia(ctx, "entertainment")
+	if triviaErr != nil {
+		t.Fatalf("GetTrivia(entertainment): %v", triviaErr)
+	}
+	categoryID, _ := strconv.Atoi(fake.questionRequests[len(fake.questionRequests)-1].Get("category"))
+	if !containsInt(categoryIDs("entertainment"), categoryID) {
+		t.Errorf("category %d is not mapped to entertainment", categoryID)
+	}
+}
+
+func TestGetTriviaBoolean(t *testing.T) {
+	booleanResult := DBResult{Category: "Science & Nature", Type: TypeBoolean, Difficulty: DifficultyEasy,
+		Question: "The sun is a star.", CorrectAnswer: "True", IncorrectAnswers: []string{"False"}}
+	openTriviaDB, fake := newTestOpenTriviaDB(t, booleanResult, "", TypeBoolean, EncodingDefault)
+
+	trivia, triviaErr := openTriviaDB.GetTrivia(context.Background(), "sciencenature")
+	if triviaErr != nil {
+		t.Fatalf("GetTrivia: %v", triviaErr)
+	}
+
+	if fake.questionRequests[0].Get("type") != TypeBoolean {
+		t.Errorf("type parameter = %q, want %q", fake.questionRequests[0].Get("type"), TypeBoolean)
+	}
+	wantChoices := []string{messages.MAKE_SELECTION_MSG, messages.TRUE_ANSWER, messages.FALSE_ANSWER}
+	if trivia.Type != messages.BOOLEAN || !reflect.DeepEqual(trivia.Choices, wantChoices) {
+		t.Errorf("trivia = %+v", trivia)
+	}
+}
+
+func TestGetTriviaChoices(t *testing.T) {
+	openTriviaDB, _ := newTestOpenTriviaDB(t, capitalResult, "", "", EncodingDefault)
+
+	trivia, triviaErr := openTriviaDB.GetTrivia(context.Background(), "geography")
+	if triviaErr != nil {
+		t.Fatalf("GetTrivia: %v", triviaErr)
+	}
+
+	if len(trivia.Choices) == 0 || trivia.Choices[0] != messages.MAKE_SELECTION_MSG {
+		t.Fatalf("choices = %q, want %q first", trivia.Choices, messages.MAKE_SELECTION_MSG)
+	}
+	choices := append([]string(nil), trivia.Choices[1:]...)
+	sort.Strings(choices)
+	wantChoices := []string{"Berlin", "London", "Paris", "Rome"}
+	if !reflect.DeepEqual(choices, wantChoices) {
+		t.Errorf("choices = %q, want %q in any order", choices, wantChoices)
+	}
+	if len(trivia.QuestionID) == 0 {
+		t.Error("trivia has no question ID")
+	}
+}
+
+func TestNewOpenTriviaDBRejectsInvalidFilters(t *testing.T) {
+	httpClient := common.NewHTTPClient(common.HTTPClientConfig{})
+
+	tests := []struct {
+		name       string
+		difficulty string
+		qType      string
+		encoding   string
+	}{
+		{"difficulty", "impossible", "", EncodingDefault},
+		{"type", "", "essay", EncodingDefault},
+		{"encoding", "", "", "rot13"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, dbErr := NewOpenTriviaDB("", test.difficulty, test.qType, test.encoding, httpClient)
+			if dbErr == nil {
+				t.Error("NewOpenTriviaDB returned no error")
+			}
+		})
+	}
+}
+
+func containsInt(values []int, value int) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,442 @@
+package QuestionBank
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderName string = "questionbank"
+
+	JSONExt string = ".json"
+	YAMLExt string = ".yaml"
+	YMLExt  string = ".yml"
+	CSVExt  string = ".csv"
+
+	// CSVDistractorSeparator separates the distractors, or alternate answers, stored in a single CSV column
+	CSVDistractorSeparator string = "|"
+)
+
+// CSV column names
+const (
+	CSVCategory    string = "category"
+	CSVQuestion    string = "question"
+	CSVAnswer      string = "answer"
+	CSVDistractors string = "distractors"
+	CSVDifficulty  string = "difficulty"
+	CSVType        string = "type"
+	CSVAlternates  string = "alternates"
+)
+
+// BankEntry is a single question stored in a question bank file. Type is one of multiple, boolean or text.
+// When type is not set, entries with distractors are multiple choice, entries answered True or False are
+// boolean and the rest are free text. Alternates are the other answers accepted for free text questions.
+type BankEntry struct {
+	Category    string   `json:"category" yaml:"category"`
+	Question    string   `json:"question" yaml:"question"`
+	Type        string   `json:"type" yaml:"type"`
+	Answer      string   `json:"answer" yaml:"answer"`
+	Distractors []string `json:"distractors" yaml:"distractors"`
+	Alternates  []string `json:"alternates" yaml:"alternates"`
+	Difficulty  string   `json:"difficulty" yaml:"difficulty"`
+}
+
+// QuestionBank serves trivia questions loaded from files on disk
+type QuestionBank struct {
+	mutex      sync.Mutex
+	entries    map[string][]BankEntry
+	categories []string
+	nextIdx    map[string]int
+	logger     *slog.Logger
+}
+
+// Name returns the name used to select the provider in config
+func (qb *QuestionBank) Name() string {
+	return ProviderName
+}
+
+// Categories returns the list of categories found in the question bank
+func (qb *QuestionBank) Categories() []string {
+	return qb.categories
+}
+
+// GetTrivia returns the next question in the bank for the requested category.
+// Questions are served in the order they were loaded so results are repeatable.
+func (qb *QuestionBank) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	return qb.GetTriviaByDifficulty(ctx, category, "")
+}
+
+// GetTriviaByDifficulty returns the next question in the bank for the requested category
+// and difficulty. An empty difficulty matches every question.
+func (qb *QuestionBank) GetTriviaByDifficulty(ctx context.Context, category string, difficulty string) (messages.Trivia, error) {
+	logger := common.LoggerFromContext(ctx, qb.logger).With("provider", ProviderName,
+		common.CATEGORY_FIELD, category)
+
+	qb.mutex.Lock()
+	defer qb.mutex.Unlock()
+
+	// validate category
+	entries, found := qb.entries[category]
+	if !found || len(entries) == 0 {
+		errMsg := fmt.Sprintf("%s is invalid", category)
+		logger.Warn(errMsg)
+		return messages.Trivia{}, errors.New(errMsg)
+	}
+
+	// Filter entries by difficulty
+	if len(difficulty) > 0 {
+		var filteredEntries []BankEntry
+		for _, entry := range entries {
+			if strings.EqualFold(entry.Difficulty, difficulty) {
+				filteredEntries = append(filteredEntries, entry)
+			}
+		}
+
+		if len(filteredEntries) == 0 {
+			errMsg := fmt.Sprintf("no %s questions found for category %s", difficulty, category)
+			logger.Warn(errMsg)
+			return messages.Trivia{}, errors.New(errMsg)
+		}
+
+		entries = filteredEntries
+	}
+
+	// Get the next entry for the category and difficulty
+	idxKey := category + "/" + strings.ToLower(difficulty)
+	idx := qb.nextIdx[idxKey]
+	qb.nextIdx[idxKey] = (idx + 1) % len(entries)
+	entry := entries[idx]
+
+	// Build trivia message
+	var trivia messages.Trivia
+	trivia.QuestionID = uuid.New().String()
+	trivia.QuestionID = common.BuildUUID(trivia.QuestionID, messages.DASH, messages.ONE_SET)
+	trivia.Category = entry.Category
+	trivia.Question = entry.Question
+	trivia.Type = entry.Type
+	trivia.Answer = entry.Answer
+	trivia.Difficulty = entry.Difficulty
+	trivia.Timestamp = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+
+	// Build choices string
+	var choiceList []string
+	switch entry.Type {
+	case messages.FREE_TEXT:
+		// Free text questions are answered without choices
+		trivia.Alternates = entry.Alternates
+		trivia.Choices = []string{}
+		return trivia, nil
+	case messages.BOOLEAN:
+		// True and False are always offered in the same order
+		choiceList = []string{messages.TRUE_ANSWER, messages.FALSE_ANSWER}
+	default:
+		trivia.Distractors = entry.Distractors
+
+		choiceList = make([]string, 0, len(entry.Distractors)+1)
+		choiceList = append(choiceList, entry.Answer)
+		choiceList = append(choiceList, entry.Distractors...)
+
+		// Shuffle list
+		choiceList = common.ShuffleList(choiceList)
+	}
+
+	// Add a message filler to the beginning of the list
+	trivia.Choices = append(trivia.Choices, messages.MAKE_SELECTION_MSG)
+	trivia.Choices = append(trivia.Choices, choiceList...)
+
+	return trivia, nil
+}
+
+// unexported type methods
+// addEntries validates and adds entries to the bank. Entries are also added
+// under the empty category so that requests without a category can be served.
+func (qb *QuestionBank) addEntries(fileName string, entries []BankEntry) error {
+	for idx, entry := range entries {
+		entry.Category = strings.TrimSpace(entry.Category)
+		entry.Question = strings.TrimSpace(entry.Question)
+		entry.Answer = strings.TrimSpace(entry.Answer)
+		entry.Difficulty = strings.TrimSpace(entry.Difficulty)
+		entry.Type = strings.ToLower(strings.TrimSpace(entry.Type))
+		entry.Distractors = uniqueDistractors(entry.Answer, entry.Distractors)
+
+		if len(entry.Question) == 0 || len(entry.Answer) == 0 {
+			errMsg := fmt.Sprintf("%s: entry %d is missing a question or answer", fileName, idx+1)
+			qb.logger.Error(errMsg)
+			return errors.New(errMsg)
+		}
+
+		typeErr := setEntryType(&entry)
+		if typeErr != nil {
+			errMsg := fmt.Sprintf("%s: entry %d %v", fileName, idx+1, typeErr)
+			qb.logger.Error(errMsg)
+			return errors.New(errMsg)
+		}
+
+		if len(entry.Category) > 0 {
+			if _, found := qb.entries[entry.Category]; !found {
+				qb.categories = append(qb.categories, entry.Category)
+			}
+			qb.entries[entry.Category] = append(qb.entries[entry.Category], entry)
+		}
+		qb.entries[""] = append(qb.entries[""], entry)
+	}
+
+	return nil
+}
+
+// loadFile loads the entries from a single question bank file
+func (qb *QuestionBank) loadFile(fileName string) error {
+	logger := qb.logger.With("file", fileName)
+	logger.Info("Loading question bank file")
+
+	file, openErr := os.Open(fileName)
+	if openErr != nil {
+		logger.Error("Error opening question bank file", common.ERROR_FIELD, openErr)
+		return openErr
+	}
+	defer func(file *os.File) {
+		closeErr := file.Close()
+		if closeErr != nil {
+			logger.Warn("Error closing question bank file", common.ERROR_FIELD, closeErr)
+		}
+	}(file)
+
+	var entries []BankEntry
+	var parseErr error
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case JSONExt:
+		parseErr = json.NewDecoder(file).Decode(&entries)
+	case YAMLExt, YMLExt:
+		parseErr = yaml.NewDecoder(file).Decode(&entries)
+	case CSVExt:
+		entries, parseErr = parseCSV(file)
+	default:
+		errMsg := fmt.Sprintf("%s is not a supported question bank file", fileName)
+		logger.Error(errMsg)
+		return errors.New(errMsg)
+	}
+
+	if parseErr != nil {
+		logger.Error("Error parsing question bank file", common.ERROR_FIELD, parseErr)
+		return parseErr
+	}
+
+	return qb.addEntries(fileName, entries)
+}
+
+// NewQuestionBank loads the question bank from bankPath. bankPath may be a single
+// file or a directory; every supported file in a directory is loaded.
+func NewQuestionBank(bankPath string) (*QuestionBank, error) {
+	logger := slog.Default()
+	logger.Info("Creating question bank object", "provider", ProviderName)
+
+	if len(bankPath) == 0 {
+		errMsg := "question bank path is not set"
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+
+	questionBank := new(QuestionBank)
+	questionBank.logger = logger
+	questionBank.entries = make(map[string][]BankEntry)
+	questionBank.nextIdx = make(map[string]int)
+
+	fileInfo, statErr := os.Stat(bankPath)
+	if statErr != nil {
+		logger.Error("Error reading question bank path", common.ERROR_FIELD, statErr)
+		return nil, statErr
+	}
+
+	// Build the list of files to load
+	fileNames := []string{bankPath}
+	if fileInfo.IsDir() {
+		fileNames = []string{}
+
+		dirEntries, readErr := os.ReadDir(bankPath)
+		if readErr != nil {
+			logger.Error("Error reading question bank directory", common.ERROR_FIELD, readErr)
+			return nil, readErr
+		}
+
+		for _, dirEntry := range dirEntries {
+			if !dirEntry.IsDir() && isSupportedFile(dirEntry.Name()) {
+				fileNames = append(fileNames, filepath.Join(bankPath, dirEntry.Name()))
+			}
+		}
+	}
+
+	for _, fileName := range fileNames {
+		loadErr := questionBank.loadFile(fileName)
+		if loadErr != nil {
+			return nil, loadErr
+		}
+	}
+
+	if len(questionBank.entries[""]) == 0 {
+		errMsg := fmt.Sprintf("no questions found in %s", bankPath)
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+
+	sort.Strings(questionBank.categories)
+	logger.Info("Loaded question bank", "questions", len(questionBank.entries[""]), "categories",
+		len(questionBank.categories))
+
+	return questionBank, nil
+}
+
+// unexported functions
+func isSupportedFile(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case JSONExt, YAMLExt, YMLExt, CSVExt:
+		return true
+	}
+
+	return false
+}
+
+// setEntryType sets the question type of an entry when it is not set and checks that the entry can be
+// served as that type
+func setEntryType(entry *BankEntry) error {
+	isBoolean := strings.EqualFold(entry.Answer, messages.TRUE_ANSWER) ||
+		strings.EqualFold(entry.Answer, messages.FALSE_ANSWER)
+
+	if len(entry.Type) == 0 {
+		switch {
+		case len(entry.Distractors) > 0:
+			entry.Type = messages.MULTIPLE_CHOICE
+		case isBoolean:
+			entry.Type = messages.BOOLEAN
+		default:
+			entry.Type = messages.FREE_TEXT
+		}
+	}
+
+	switch entry.Type {
+	case messages.MULTIPLE_CHOICE:
+		if len(entry.Distractors) == 0 {
+			return errors.New("is multiple choice but has no distractors other than the answer")
+		}
+	case messages.BOOLEAN:
+		if !isBoolean {
+			return fmt.Errorf("is boolean but the answer is not %s or %s", messages.TRUE_ANSWER, messages.FALSE_ANSWER)
+		}
+
+		// Boolean answers are served in the same form as the choices
+		if strings.EqualFold(entry.Answer, messages.TRUE_ANSWER) {
+			entry.Answer = messages.TRUE_ANSWER
+		} else {
+			entry.Answer = messages.FALSE_ANSWER
+		}
+	case messages.FREE_TEXT:
+	default:
+		return fmt.Errorf("has an invalid type %s, use %s, %s or %s", entry.Type, messages.MULTIPLE_CHOICE,
+			messages.BOOLEAN, messages.FREE_TEXT)
+	}
+
+	return nil
+}
+
+// uniqueDistractors returns the distractors without blanks, the answer or repeats, ignoring case and spacing
+func uniqueDistractors(answer string, distractors []string) []string {
+	seen := map[string]bool{choiceKey(answer): true}
+
+	var unique []string
+	for _, distractor := range distractors {
+		distractor = strings.TrimSpace(distractor)
+		key := choiceKey(distractor)
+		if len(key) == 0 || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		unique = append(unique, distractor)
+	}
+
+	return unique
+}
+
+// choiceKey is the form choices are compared in to find duplicates
+func choiceKey(choice string) string {
+	return strings.ToLower(strings.Join(strings.Fields(choice), " "))
+}
+
+// parseCSV parses CSV records using the header row to locate each column
+func parseCSV(reader io.Reader) ([]BankEntry, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	records, readErr := csvReader.ReadAll()
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// Map column names to their position
+	columns := make(map[string]int)
+	for idx, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+
+	for _, name := range []string{CSVQuestion, CSVAnswer} {
+		if _, found := columns[name]; !found {
+			return nil, fmt.Errorf("csv header is missing the %s column", name)
+		}
+	}
+
+	getField := func(record []string, name string) string {
+		idx, found := columns[name]
+		if !found || idx >= len(record) {
+			return ""
+		}
+
+		return record[idx]
+	}
+
+	entries := make([]BankEntry, 0, len(records)-1)
+	for _, record := range records[1:] {
+		var entry BankEntry
+		entry.Category = getField(record, CSVCategory)
+		entry.Question = getField(record, CSVQuestion)
+		entry.Answer = getField(record, CSVAnswer)
+		entry.Difficulty = getField(record, CSVDifficulty)
+		entry.Type = getField(record, CSVType)
+
+		for _, distractor := range strings.Split(getField(record, CSVDistractors), CSVDistractorSeparator) {
+			distractor = strings.TrimSpace(distractor)
+			if len(distractor) > 0 {
+				entry.Distractors = append(entry.Distractors, distractor)
+			}
+		}
+
+		for _, alternate := range strings.Split(getField(record, CSVAlternates), CSVDistractorSeparator) {
+			alternate = strings.TrimSpace(alternate)
+			if len(alternate) > 0 {
+				entry.Alternates = append(entry.Alternates, alternate)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
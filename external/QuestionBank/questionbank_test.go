@@ -0,0 +1,225 @@
+package QuestionBank
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/messages"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const jsonBank string = `[
+  {"category": "geography", "question": "What is the capital of France?", "answer": "Paris",
+   "distractors": ["London", "paris", "Rome", " London ", "", "Berlin"], "difficulty": "easy"},
+  {"category": "science", "question": "Is water wet?", "answer": "true", "difficulty": "hard"},
+  {"category": "literature", "question": "Who wrote Hamlet?", "answer": "Shakespeare",
+   "alternates": ["William Shakespeare"], "type": "text", "difficulty": "medium"}
+]`
+
+const yamlBank string = `
+- category: geography
+  question: What is the capital of France?
+  answer: Paris
+  distractors: [London, paris, Rome, " London ", "", Berlin]
+  difficulty: easy
+- category: science
+  question: Is water wet?
+  answer: "true"
+  difficulty: hard
+- category: literature
+  question: Who wrote Hamlet?
+  answer: Shakespeare
+  alternates: [William Shakespeare]
+  type: text
+  difficulty: medium
+`
+
+const csvBank string = `category,question,answer,distractors,difficulty,type,alternates
+geography,What is the capital of France?,Paris,London|paris|Rome| London ||Berlin,easy,,
+science,Is water wet?,true,,hard,,
+literature,Who wrote Hamlet?,Shakespeare,,medium,text,William Shakespeare
+`
+
+// writeBank writes a question bank file to a temporary directory and returns its path
+func writeBank(t *testing.T, fileName string, content string) string {
+	t.Helper()
+
+	bankPath := filepath.Join(t.TempDir(), fileName)
+	writeErr := os.WriteFile(bankPath, []byte(content), 0o600)
+	if writeErr != nil {
+		t.Fatalf("writing %s: %v", bankPath, writeErr)
+	}
+
+	return bankPath
+}
+
+func TestNewQuestionBankLoadsEveryFormat(t *testing.T) {
+	tests := []struct {
+		fileName string
+		content  string
+	}{
+		{"bank.json", jsonBank},
+		{"bank.yaml", yamlBank},
+		{"bank.yml", yamlBank},
+		{"bank.csv", csvBank},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fileName, func(t *testing.T) {
+			questionBank, bankErr := NewQuestionBank(writeBank(t, test.fileName, test.content))
+			if bankErr != nil {
+				t.Fatalf("NewQuestionBank: %v", bankErr)
+			}
+
+			wantCategories := []string{"geography", "literature", "science"}
+			if !reflect.DeepEqual(questionBank.Categories(), wantCategories) {
+				t.Errorf("categories = %v, want %v", questionBank.Categories(), wantCategories)
+			}
+
+			ctx := context.Background()
+
+			// Distractors repeating the answer or each other are dropped
+			trivia, triviaErr := questionBank.GetTrivia(ctx, "geography")
+			if triviaErr != nil {
+				t.Fatalf("GetTrivia(geography): %v", triviaErr)
+			}
+			if trivia.Type != messages.MULTIPLE_CHOICE || trivia.Answer != "Paris" || trivia.Difficulty != "easy" {
+				t.Errorf("geography trivia = %+v", trivia)
+			}
+			wantDistractors := []string{"London", "Rome", "Berlin"}
+			if !reflect.DeepEqual(trivia.Distractors, wantDistractors) {
+				t.Errorf("distractors = %q, want %q", trivia.Distractors, wantDistractors)
+			}
+			if len(trivia.Choices) == 0 || trivia.Choices[0] != messages.MAKE_SELECTION_MSG {
+				t.Fatalf("choices = %q, want %q first", trivia.Choices, messages.MAKE_SELECTION_MSG)
+			}
+			choices := append([]string(nil), trivia.Choices[1:]...)
+			sort.Strings(choices)
+			wantChoices := []string{"Berlin", "London", "Paris", "Rome"}
+			if !reflect.DeepEqual(choices, wantChoices) {
+				t.Errorf("choices = %q, want %q in any order", choices, wantChoices)
+			}
+
+			// Answers of True or False make boolean questions
+			trivia, triviaErr = questionBank.GetTrivia(ctx, "science")
+			if triviaErr != nil {
+				t.Fatalf("GetTrivia(science): %v", triviaErr)
+			}
+			wantChoices = []string{messages.MAKE_SELECTION_MSG, messages.TRUE_ANSWER, messages.FALSE_ANSWER}
+			if trivia.Type != messages.BOOLEAN || trivia.Answer != messages.TRUE_ANSWER ||
+				!reflect.DeepEqual(trivia.Choices, wantChoices) {
+				t.Errorf("science trivia = %+v", trivia)
+			}
+
+			// Free text questions carry their alternates and no choices
+			trivia, triviaErr = questionBank.GetTrivia(ctx, "literature")
+			if triviaErr != nil {
+				t.Fatalf("GetTrivia(literature): %v", triviaErr)
+			}
+			if trivia.Type != messages.FREE_TEXT || len(trivia.Choices) != 0 ||
+				!reflect.DeepEqual(trivia.Alternates, []string{"William Shakespeare"}) {
+				t.Errorf("literature trivia = %+v", trivia)
+			}
+		})
+	}
+}
+
+func TestGetTriviaIsRepeatable(t *testing.T) {
+	questionBank, bankErr := NewQuestionBank(writeBank(t, "bank.json", jsonBank))
+	if bankErr != nil {
+		t.Fatalf("NewQuestionBank: %v", bankErr)
+	}
+
+	// Questions without a category are served in the order they were loaded, then start over
+	wantQuestions := []string{"What is the capital of France?", "Is water wet?", "Who wrote Hamlet?",
+		"What is the capital of France?"}
+	for idx, wantQuestion := range wantQuestions {
+		trivia, triviaErr := questionBank.GetTrivia(context.Background(), "")
+		if triviaErr != nil {
+			t.Fatalf("GetTrivia %d: %v", idx, triviaErr)
+		}
+		if trivia.Question != wantQuestion {
+			t.Errorf("question %d = %q, want %q", idx, trivia.Question, wantQuestion)
+		}
+		if len(trivia.QuestionID) == 0 {
+			t.Errorf("question %d has no question ID", idx)
+		}
+	}
+}
+
+func TestGetTriviaByDifficulty(t *testing.T) {
+	questionBank, bankErr := NewQuestionBank(writeBank(t, "bank.json", jsonBank))
+	if bankErr != nil {
+		t.Fatalf("NewQuestionBank: %v", bankErr)
+	}
+
+	trivia, triviaErr := questionBank.GetTriviaByDifficulty(context.Background(), "", "HARD")
+	if triviaErr != nil {
+		t.Fatalf("GetTriviaByDifficulty: %v", triviaErr)
+	}
+	if trivia.Question != "Is water wet?" {
+		t.Errorf("question = %q, want the hard question", trivia.Question)
+	}
+
+	_, triviaErr = questionBank.GetTriviaByDifficulty(context.Background(), "geography", "hard")
+	if triviaErr == nil {
+		t.Error("GetTriviaByDifficulty returned no error for a difficulty without questions")
+	}
+
+	_, triviaErr = questionBank.GetTrivia(context.Background(), "sports")
+	if triviaErr == nil {
+		t.Error("GetTrivia returned no error for an unknown category")
+	}
+}
+
+func TestNewQuestionBankLoadsDirectory(t *testing.T) {
+	bankDir := t.TempDir()
+	for fileName, content := range map[string]string{"a.json": jsonBank, "b.csv": csvBank, "notes.txt": "notes"} {
+		writeErr := os.WriteFile(filepath.Join(bankDir, fileName), []byte(content), 0o600)
+		if writeErr != nil {
+			t.Fatalf("writing %s: %v", fileName, writeErr)
+		}
+	}
+
+	questionBank, bankErr := NewQuestionBank(bankDir)
+	if bankErr != nil {
+		t.Fatalf("NewQuestionBank: %v", bankErr)
+	}
+	if got := len(questionBank.entries[""]); got != 6 {
+		t.Errorf("loaded %d questions, want 6", got)
+	}
+}
+
+func TestNewQuestionBankRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{"missing answer", "bank.json", `[{"category": "c", "question": "q"}]`},
+		{"only the answer as distractor", "bank.json",
+			`[{"question": "q", "answer": "Paris", "type": "multiple", "distractors": [" PARIS "]}]`},
+		{"boolean without true or false", "bank.json", `[{"question": "q", "answer": "maybe", "type": "boolean"}]`},
+		{"invalid type", "bank.json", `[{"question": "q", "answer": "a", "type": "essay"}]`},
+		{"no questions", "bank.json", `[]`},
+		{"malformed json", "bank.json", `[{`},
+		{"csv without answer column", "bank.csv", "category,question\nc,q\n"},
+		{"unsupported file", "bank.txt", "question"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, bankErr := NewQuestionBank(writeBank(t, test.fileName, test.content))
+			if bankErr == nil {
+				t.Error("NewQuestionBank returned no error")
+			}
+		})
+	}
+
+	_, bankErr := NewQuestionBank("")
+	if bankErr == nil {
+		t.Error("NewQuestionBank returned no error for an empty path")
+	}
+}
@@ -0,0 +1,130 @@
+package external
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   string = "closed"
+	BreakerOpen     string = "open"
+	BreakerHalfOpen string = "half-open"
+)
+
+// CircuitBreaker stops requests to a provider that keeps failing. The breaker is closed while the provider
+// works and opens after failureThreshold failures in a row. Once openTimeout has passed it is half-open:
+// a single trial request is let through, closing the breaker when it succeeds and opening it again when
+// it fails.
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mutex    sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trialing bool
+
+	logger *slog.Logger
+}
+
+// Allow reports whether a request may be sent to the provider. Every allowed request must be followed
+// by a call to RecordSuccess or RecordFailure.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if time.Since(cb.openedAt) < cb.openTimeout {
+			return false
+		}
+		cb.setState(BreakerHalfOpen)
+		cb.trialing = true
+		return true
+	case BreakerHalfOpen:
+		// Only one trial request at a time
+		if cb.trialing {
+			return false
+		}
+		cb.trialing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failures = 0
+	cb.trialing = false
+	cb.setState(BreakerClosed)
+}
+
+// RecordFailure counts a failed request, opening the breaker when the failure threshold is reached or
+// the trial request of a half-open breaker failed
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failures++
+	cb.trialing = false
+	if cb.state == BreakerHalfOpen || cb.failures >= cb.failureThreshold {
+		cb.openedAt = time.Now()
+		cb.setState(BreakerOpen)
+	}
+}
+
+// RecordIgnored ends an allowed request whose outcome says nothing about the health of the provider,
+// such as a request cancelled by the client
+func (cb *CircuitBreaker) RecordIgnored() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.trialing = false
+}
+
+// State returns the current state of the breaker. An open breaker whose timeout has passed is reported
+// as half-open.
+func (cb *CircuitBreaker) State() string {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.state == BreakerOpen && time.Since(cb.openedAt) >= cb.openTimeout {
+		return BreakerHalfOpen
+	}
+
+	return cb.state
+}
+
+// unexported type methods
+// setState changes the state, logging transitions. The mutex must be held.
+func (cb *CircuitBreaker) setState(state string) {
+	if cb.state != state {
+		cb.logger.Warn("Circuit breaker changed state", "provider", cb.name, "state", state)
+		cb.state = state
+	}
+}
+
+// NewCircuitBreaker creates a closed circuit breaker for the named provider
+func NewCircuitBreaker(name string, failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	logger := slog.Default()
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+
+	circuitBreaker := new(CircuitBreaker)
+	circuitBreaker.name = name
+	circuitBreaker.failureThreshold = failureThreshold
+	circuitBreaker.openTimeout = openTimeout
+	circuitBreaker.state = BreakerClosed
+	circuitBreaker.logger = logger
+
+	return circuitBreaker
+}
@@ -0,0 +1,48 @@
+package external
+
+import (
+	"testing"
+	"time"
+)
+
+// expireOpenTimeout moves the time the breaker opened back past its open timeout
+func expireOpenTimeout(cb *CircuitBreaker) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.openedAt = time.Now().Add(-cb.openTimeout)
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker := NewCircuitBreaker("stub", 2, time.Minute)
+
+	tests := []struct {
+		name      string
+		step      func()
+		wantState string
+		wantAllow bool
+	}{
+		{"new breaker", func() {}, BreakerClosed, true},
+		{"failure below threshold", breaker.RecordFailure, BreakerClosed, true},
+		{"success resets failures", breaker.RecordSuccess, BreakerClosed, true},
+		{"failure after success", breaker.RecordFailure, BreakerClosed, true},
+		{"failure threshold reached", breaker.RecordFailure, BreakerOpen, false},
+		{"open timeout passed", func() { expireOpenTimeout(breaker) }, BreakerHalfOpen, true},
+		{"trial request in flight", func() {}, BreakerHalfOpen, false},
+		{"trial request failed", breaker.RecordFailure, BreakerOpen, false},
+		{"open timeout passed again", func() { expireOpenTimeout(breaker) }, BreakerHalfOpen, true},
+		{"trial request ignored", breaker.RecordIgnored, BreakerHalfOpen, true},
+		{"trial request succeeded", breaker.RecordSuccess, BreakerClosed, true},
+	}
+
+	// Every step is followed by a request asking the breaker whether it may be sent
+	for _, test := range tests {
+		test.step()
+		if state := breaker.State(); state != test.wantState {
+			t.Fatalf("%s: State() = %q, want %q", test.name, state, test.wantState)
+		}
+		if allow := breaker.Allow(); allow != test.wantAllow {
+			t.Fatalf("%s: Allow() = %t, want %t", test.name, allow, test.wantAllow)
+		}
+	}
+}
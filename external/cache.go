@@ -0,0 +1,99 @@
+package external
+
+import (
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheProviderName is reported as the provider of questions served from the question cache
+const CacheProviderName string = "cache"
+
+// QuestionCache keeps the most recent questions fetched for each category so they can be served again
+// when every provider is failing
+type QuestionCache struct {
+	mutex     sync.Mutex
+	size      int
+	questions map[string][]messages.Trivia
+}
+
+// Add keeps a copy of the question. When the category is full the oldest question is dropped.
+func (qc *QuestionCache) Add(trivia messages.Trivia) {
+	if qc.size <= 0 {
+		return
+	}
+
+	qc.mutex.Lock()
+	defer qc.mutex.Unlock()
+
+	questions := append(qc.questions[trivia.Category], copyTrivia(trivia))
+	if len(questions) > qc.size {
+		questions = questions[len(questions)-qc.size:]
+	}
+	qc.questions[trivia.Category] = questions
+}
+
+// Get returns a cached question for the category and difficulty. An empty category or difficulty matches
+// every question. The question is issued as a new question: it gets a new question ID and timestamp and
+// its choices are shuffled again.
+func (qc *QuestionCache) Get(category string, difficulty string) (messages.Trivia, bool) {
+	qc.mutex.Lock()
+	defer qc.mutex.Unlock()
+
+	var candidates []messages.Trivia
+	for cachedCategory, questions := range qc.questions {
+		if len(category) > 0 && cachedCategory != category {
+			continue
+		}
+
+		for _, question := range questions {
+			if len(difficulty) == 0 || strings.EqualFold(question.Difficulty, difficulty) {
+				candidates = append(candidates, question)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return messages.Trivia{}, false
+	}
+
+	trivia := copyTrivia(candidates[rand.Intn(len(candidates))])
+	trivia.QuestionID = uuid.New().String()
+	trivia.QuestionID = common.BuildUUID(trivia.QuestionID, messages.DASH, messages.ONE_SET)
+	trivia.Timestamp = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+	trivia.Provider = CacheProviderName
+
+	// Boolean choices keep their order, the message filler stays first
+	if trivia.Type != messages.BOOLEAN && len(trivia.Choices) > 1 && trivia.Choices[0] == messages.MAKE_SELECTION_MSG {
+		common.ShuffleList(trivia.Choices[1:])
+	}
+
+	return trivia, true
+}
+
+// NewQuestionCache creates a question cache keeping up to size questions for each category.
+// A size of 0 disables the cache.
+func NewQuestionCache(size int) *QuestionCache {
+	questionCache := new(QuestionCache)
+	questionCache.size = size
+	questionCache.questions = make(map[string][]messages.Trivia)
+
+	return questionCache
+}
+
+// unexported functions
+// copyTrivia copies a question without sharing its lists, and without the details of when and to whom
+// it was issued
+func copyTrivia(trivia messages.Trivia) messages.Trivia {
+	trivia.Distractors = append([]string(nil), trivia.Distractors...)
+	trivia.Alternates = append([]string(nil), trivia.Alternates...)
+	trivia.Choices = append([]string{}, trivia.Choices...)
+	trivia.IssuedAt = time.Time{}
+	trivia.PlayerID = ""
+
+	return trivia
+}
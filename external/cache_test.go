@@ -0,0 +1,109 @@
+package external
+
+import (
+	"github.com/sflewis2970/trivia-api/messages"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// cachedQuestions returns the questions cached for the category, oldest first
+func cachedQuestions(qc *QuestionCache, category string) []string {
+	qc.mutex.Lock()
+	defer qc.mutex.Unlock()
+
+	var questions []string
+	for _, trivia := range qc.questions[category] {
+		questions = append(questions, trivia.Question)
+	}
+
+	return questions
+}
+
+func TestQuestionCacheDropsOldest(t *testing.T) {
+	questionCache := NewQuestionCache(2)
+	for _, question := range []string{"first", "second", "third"} {
+		questionCache.Add(messages.Trivia{QuestionID: question, Question: question, Category: STUB_CATEGORY})
+	}
+	questionCache.Add(messages.Trivia{QuestionID: "other", Question: "other", Category: "history"})
+
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{STUB_CATEGORY, []string{"second", "third"}},
+		{"history", []string{"other"}},
+	}
+
+	for _, test := range tests {
+		got := cachedQuestions(questionCache, test.category)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("cached questions for %s = %q, want %q", test.category, got, test.want)
+		}
+	}
+
+	// The dropped question is never served
+	for idx := 0; idx < 50; idx++ {
+		trivia, found := questionCache.Get(STUB_CATEGORY, "")
+		if !found || trivia.Question == "first" {
+			t.Fatalf("Get() = %q, %t, want a question still cached", trivia.Question, found)
+		}
+	}
+}
+
+func TestQuestionCacheGet(t *testing.T) {
+	questionCache := NewQuestionCache(10)
+	cached := messages.Trivia{QuestionID: "question-1", Question: "What is 2 + 2?", Category: STUB_CATEGORY,
+		Difficulty: "easy", Type: messages.MULTIPLE_CHOICE, Answer: "4",
+		Choices: []string{messages.MAKE_SELECTION_MSG, "4", "3", "5"}, IssuedAt: time.Now(), PlayerID: "player-1"}
+	questionCache.Add(cached)
+
+	tests := []struct {
+		category   string
+		difficulty string
+		wantFound  bool
+	}{
+		{STUB_CATEGORY, "", true},
+		{STUB_CATEGORY, "EASY", true},
+		{"", "", true},
+		{STUB_CATEGORY, "hard", false},
+		{"history", "", false},
+	}
+
+	for _, test := range tests {
+		trivia, found := questionCache.Get(test.category, test.difficulty)
+		if found != test.wantFound {
+			t.Errorf("Get(%q, %q) found = %t, want %t", test.category, test.difficulty, found, test.wantFound)
+			continue
+		}
+		if !found {
+			continue
+		}
+
+		// The question is issued as a new question
+		if trivia.QuestionID == cached.QuestionID || trivia.Provider != CacheProviderName ||
+			!trivia.IssuedAt.IsZero() || len(trivia.PlayerID) > 0 {
+			t.Errorf("Get(%q, %q) = %+v, want a new question from the cache", test.category, test.difficulty, trivia)
+		}
+		if trivia.Choices[0] != messages.MAKE_SELECTION_MSG {
+			t.Errorf("Get(%q, %q) choices = %q, want %q first", test.category, test.difficulty, trivia.Choices,
+				messages.MAKE_SELECTION_MSG)
+		}
+	}
+
+	// Questions served do not share their choices with the cache
+	trivia, _ := questionCache.Get(STUB_CATEGORY, "")
+	trivia.Choices[1] = "changed"
+	if again, _ := questionCache.Get(STUB_CATEGORY, ""); again.Choices[1] == "changed" {
+		t.Errorf("Get() choices = %q, changed by an earlier question", again.Choices)
+	}
+}
+
+func TestQuestionCacheDisabled(t *testing.T) {
+	questionCache := NewQuestionCache(0)
+	questionCache.Add(messages.Trivia{QuestionID: "question-1", Category: STUB_CATEGORY})
+
+	if _, found := questionCache.Get(STUB_CATEGORY, ""); found {
+		t.Error("Get() found a question in a disabled cache")
+	}
+}
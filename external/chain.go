@@ -0,0 +1,198 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// ErrNoProviderAvailable is returned when no provider in the chain could serve a question and the question
+// cache had none to offer
+var ErrNoProviderAvailable = errors.New("no trivia provider is available")
+
+// Provider metrics, labelled by provider name
+var (
+	upstreamDuration = common.Metrics.NewHistogramVec("trivia_upstream_request_duration_seconds",
+		"Time taken by trivia providers to return a question.", common.LATENCY_BUCKETS, "provider")
+	upstreamErrors = common.Metrics.NewCounterVec("trivia_upstream_errors_total",
+		"Trivia provider requests that failed, by kind of error.", "provider", "kind")
+)
+
+// chainLink is a provider in the chain with its circuit breaker
+type chainLink struct {
+	provider TriviaProvider
+	breaker  *CircuitBreaker
+}
+
+// ProviderChain asks its providers for a question in order, moving to the next provider when one fails.
+// Each provider has a circuit breaker so a provider that keeps failing is skipped until it recovers. When
+// every provider fails, a question previously fetched for the category is served from the question cache.
+//
+// Only upstream errors count as provider failures. Other errors, such as a category the provider does not
+// serve, move to the next provider without tripping the breaker.
+type ProviderChain struct {
+	links         []chainLink
+	questionCache *QuestionCache
+	logger        *slog.Logger
+}
+
+// Name returns the name of the primary provider
+func (pc *ProviderChain) Name() string {
+	return pc.links[0].provider.Name()
+}
+
+// Categories returns the categories served by any provider in the chain
+func (pc *ProviderChain) Categories() []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, link := range pc.links {
+		for _, category := range link.provider.Categories() {
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+
+	sort.Strings(categories)
+
+	return categories
+}
+
+// GetTrivia returns a single trivia question for the requested category from the first provider that can
+// serve it
+func (pc *ProviderChain) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	return pc.getTrivia(ctx, category, "")
+}
+
+// GetTriviaByDifficulty returns a single trivia question for the requested category and difficulty from the
+// first provider that can serve it. Providers that do not support difficulty are only asked for the category.
+func (pc *ProviderChain) GetTriviaByDifficulty(ctx context.Context, category string,
+	difficulty string) (messages.Trivia, error) {
+	return pc.getTrivia(ctx, category, difficulty)
+}
+
+// BreakerStates returns the state of the circuit breaker of each provider, keyed by provider name
+func (pc *ProviderChain) BreakerStates() map[string]string {
+	states := make(map[string]string)
+	for _, link := range pc.links {
+		states[link.provider.Name()] = link.breaker.State()
+	}
+
+	return states
+}
+
+// unexported type methods
+// wrappedProviders returns the providers in the chain, in order
+func (pc *ProviderChain) wrappedProviders() []TriviaProvider {
+	providers := make([]TriviaProvider, 0, len(pc.links))
+	for _, link := range pc.links {
+		providers = append(providers, link.provider)
+	}
+
+	return providers
+}
+
+// supportsDifficulty reports whether any provider in the chain supports difficulty
+func (pc *ProviderChain) supportsDifficulty() bool {
+	for _, link := range pc.links {
+		if SupportsDifficulty(link.provider) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (pc *ProviderChain) getTrivia(ctx context.Context, category string, difficulty string) (messages.Trivia, error) {
+	logger := common.LoggerFromContext(ctx, pc.logger).With(common.CATEGORY_FIELD, category)
+	var lastErr error
+
+	for _, link := range pc.links {
+		if !SupportsCategory(link.provider, category) {
+			continue
+		}
+
+		if !link.breaker.Allow() {
+			logger.Debug("Skipping trivia provider, circuit breaker is open", "provider", link.provider.Name())
+			continue
+		}
+
+		started := time.Now()
+		trivia, triviaErr := GetTriviaByDifficulty(ctx, link.provider, category, difficulty)
+		upstreamDuration.ObserveDuration(started, link.provider.Name())
+		if triviaErr == nil {
+			link.breaker.RecordSuccess()
+
+			trivia.Provider = link.provider.Name()
+			pc.questionCache.Add(trivia)
+
+			return trivia, nil
+		}
+
+		logger.Warn("Error getting trivia from provider", "provider", link.provider.Name(), common.ERROR_FIELD,
+			triviaErr)
+		lastErr = triviaErr
+
+		switch {
+		case ctx.Err() != nil:
+			// The client gave up, which says nothing about the provider
+			link.breaker.RecordIgnored()
+			return messages.Trivia{}, triviaErr
+		case common.IsUpstreamTimeout(triviaErr):
+			upstreamErrors.Inc(link.provider.Name(), "timeout")
+			link.breaker.RecordFailure()
+		case common.IsUpstreamError(triviaErr):
+			upstreamErrors.Inc(link.provider.Name(), "upstream")
+			link.breaker.RecordFailure()
+		default:
+			upstreamErrors.Inc(link.provider.Name(), "other")
+			link.breaker.RecordIgnored()
+		}
+	}
+
+	trivia, found := pc.questionCache.Get(category, difficulty)
+	if found {
+		logger.Info("Serving cached trivia question", common.QUESTION_ID_FIELD, trivia.QuestionID)
+		return trivia, nil
+	}
+
+	if lastErr != nil {
+		return messages.Trivia{}, lastErr
+	}
+
+	return messages.Trivia{}, ErrNoProviderAvailable
+}
+
+// NewProviderChain creates a provider chain that asks the providers in order, the first being the primary
+// provider. Each provider gets a circuit breaker that opens after failureThreshold failures in a row and
+// lets a trial request through after openTimeout. Up to cacheSize questions are cached for each category.
+func NewProviderChain(providers []TriviaProvider, failureThreshold int, openTimeout time.Duration,
+	cacheSize int) (*ProviderChain, error) {
+	logger := slog.Default()
+	if len(providers) == 0 {
+		return nil, errors.New("provider chain needs at least one trivia provider")
+	}
+
+	providerChain := new(ProviderChain)
+	providerChain.logger = logger
+	for _, provider := range providers {
+		for _, link := range providerChain.links {
+			if link.provider.Name() == provider.Name() {
+				return nil, fmt.Errorf("trivia provider %s is in the provider chain more than once", provider.Name())
+			}
+		}
+
+		breaker := NewCircuitBreaker(provider.Name(), failureThreshold, openTimeout)
+		providerChain.links = append(providerChain.links, chainLink{provider: provider, breaker: breaker})
+	}
+
+	providerChain.questionCache = NewQuestionCache(cacheSize)
+
+	return providerChain, nil
+}
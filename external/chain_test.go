@@ -0,0 +1,149 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+const STUB_CATEGORY string = "math"
+
+// errUpstream is a failed request to an upstream service
+var errUpstream = &common.UpstreamError{URL: "http://upstream", StatusCode: 503, Attempts: 1,
+	Err: errors.New("unavailable")}
+
+// stubProvider serves questions for its categories, or fails with err when it is set, and records the
+// names of the providers asked in calls
+type stubProvider struct {
+	name       string
+	categories []string
+	err        error
+
+	mutex sync.Mutex
+	calls *[]string
+}
+
+func (sp *stubProvider) Name() string { return sp.name }
+
+func (sp *stubProvider) Categories() []string { return sp.categories }
+
+func (sp *stubProvider) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	if sp.calls != nil {
+		*sp.calls = append(*sp.calls, sp.name)
+	}
+	if sp.err != nil {
+		return messages.Trivia{}, sp.err
+	}
+
+	return messages.Trivia{QuestionID: sp.name + "-question", Question: "What is 2 + 2?", Category: category,
+		Type: messages.MULTIPLE_CHOICE, Answer: "4", Choices: []string{"4", "3", "5", "22"}}, nil
+}
+
+func TestProviderChainFallbackOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error
+		categories   [][]string
+		wantProvider string
+		wantCalls    []string
+	}{
+		{"primary provider", []error{nil, nil, nil}, nil, "primary", []string{"primary"}},
+		{"first fallback", []error{errUpstream, nil, nil}, nil, "fallback-1", []string{"primary", "fallback-1"}},
+		{"last fallback", []error{errUpstream, errors.New("no questions"), nil}, nil, "fallback-2",
+			[]string{"primary", "fallback-1", "fallback-2"}},
+		{"category not served", []error{nil, nil, nil}, [][]string{{"history"}, {STUB_CATEGORY}, {STUB_CATEGORY}},
+			"fallback-1", []string{"fallback-1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			var providers []TriviaProvider
+			for idx, name := range []string{"primary", "fallback-1", "fallback-2"} {
+				provider := &stubProvider{name: name, categories: []string{STUB_CATEGORY}, err: test.errs[idx],
+					calls: &calls}
+				if test.categories != nil {
+					provider.categories = test.categories[idx]
+				}
+				providers = append(providers, provider)
+			}
+
+			providerChain, chainErr := NewProviderChain(providers, 5, time.Minute, 0)
+			if chainErr != nil {
+				t.Fatalf("NewProviderChain() error = %v", chainErr)
+			}
+
+			trivia, triviaErr := providerChain.GetTrivia(context.Background(), STUB_CATEGORY)
+			if triviaErr != nil || trivia.Provider != test.wantProvider {
+				t.Errorf("GetTrivia() = %q, %v, want a question from %q", trivia.Provider, triviaErr,
+					test.wantProvider)
+			}
+			if !reflect.DeepEqual(calls, test.wantCalls) {
+				t.Errorf("providers asked = %q, want %q", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestProviderChainBreaker(t *testing.T) {
+	var calls []string
+	primary := &stubProvider{name: "primary", categories: []string{STUB_CATEGORY}, err: errUpstream, calls: &calls}
+	fallback := &stubProvider{name: "fallback", categories: []string{STUB_CATEGORY}, calls: &calls}
+
+	providerChain, chainErr := NewProviderChain([]TriviaProvider{primary, fallback}, 1, time.Minute, 0)
+	if chainErr != nil {
+		t.Fatalf("NewProviderChain() error = %v", chainErr)
+	}
+
+	// The upstream error opens the breaker of the primary provider, which is then skipped
+	for idx := 0; idx < 2; idx++ {
+		if _, triviaErr := providerChain.GetTrivia(context.Background(), STUB_CATEGORY); triviaErr != nil {
+			t.Fatalf("GetTrivia(%d) error = %v", idx, triviaErr)
+		}
+	}
+
+	wantCalls := []string{"primary", "fallback", "fallback"}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("providers asked = %q, want %q", calls, wantCalls)
+	}
+	wantStates := map[string]string{"primary": BreakerOpen, "fallback": BreakerClosed}
+	if states := providerChain.BreakerStates(); !reflect.DeepEqual(states, wantStates) {
+		t.Errorf("BreakerStates() = %v, want %v", states, wantStates)
+	}
+}
+
+func TestProviderChainServesCache(t *testing.T) {
+	provider := &stubProvider{name: "primary", categories: []string{STUB_CATEGORY}}
+	providerChain, chainErr := NewProviderChain([]TriviaProvider{provider}, 5, time.Minute, 10)
+	if chainErr != nil {
+		t.Fatalf("NewProviderChain() error = %v", chainErr)
+	}
+
+	if _, triviaErr := providerChain.GetTrivia(context.Background(), STUB_CATEGORY); triviaErr != nil {
+		t.Fatalf("GetTrivia() error = %v", triviaErr)
+	}
+
+	// Once every provider fails, the question fetched before is served again
+	provider.err = errUpstream
+	trivia, triviaErr := providerChain.GetTrivia(context.Background(), STUB_CATEGORY)
+	if triviaErr != nil || trivia.Provider != CacheProviderName {
+		t.Errorf("GetTrivia() = %q, %v, want a question from %q", trivia.Provider, triviaErr, CacheProviderName)
+	}
+
+	// Nothing is cached for other categories
+	if _, triviaErr := providerChain.GetTrivia(context.Background(), ""); triviaErr != nil {
+		t.Errorf("GetTrivia(any category) error = %v", triviaErr)
+	}
+	provider.categories = append(provider.categories, "history")
+	if _, triviaErr := providerChain.GetTrivia(context.Background(), "history"); !errors.Is(triviaErr, errUpstream) {
+		t.Errorf("GetTrivia(history) error = %v, want %v", triviaErr, errUpstream)
+	}
+}
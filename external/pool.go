@@ -0,0 +1,68 @@
+package external
+
+import (
+	"github.com/sflewis2970/trivia-api/messages"
+	"sync"
+)
+
+// QuestionPool holds questions fetched ahead of time, in a list for each pool key. Questions are taken in
+// the order they were added.
+type QuestionPool interface {
+	// PushQuestion adds a question to the end of the list for the pool key
+	PushQuestion(poolKey string, trivia messages.Trivia) error
+
+	// PopQuestion takes the first question from the list for the pool key. found is false when the list
+	// is empty.
+	PopQuestion(poolKey string) (trivia messages.Trivia, found bool, err error)
+
+	// QuestionCount returns the number of questions in the list for the pool key
+	QuestionCount(poolKey string) (int, error)
+}
+
+// MemoryPool is an in-process question pool, safe for concurrent use
+type MemoryPool struct {
+	mutex     sync.Mutex
+	questions map[string][]messages.Trivia
+}
+
+// PushQuestion adds a question to the end of the list for the pool key
+func (mp *MemoryPool) PushQuestion(poolKey string, trivia messages.Trivia) error {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	mp.questions[poolKey] = append(mp.questions[poolKey], trivia)
+
+	return nil
+}
+
+// PopQuestion takes the first question from the list for the pool key
+func (mp *MemoryPool) PopQuestion(poolKey string) (messages.Trivia, bool, error) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	questions := mp.questions[poolKey]
+	if len(questions) == 0 {
+		return messages.Trivia{}, false, nil
+	}
+
+	trivia := questions[0]
+	mp.questions[poolKey] = questions[1:]
+
+	return trivia, true, nil
+}
+
+// QuestionCount returns the number of questions in the list for the pool key
+func (mp *MemoryPool) QuestionCount(poolKey string) (int, error) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	return len(mp.questions[poolKey]), nil
+}
+
+// NewMemoryPool creates an empty in-process question pool
+func NewMemoryPool() *MemoryPool {
+	memoryPool := new(MemoryPool)
+	memoryPool.questions = make(map[string][]messages.Trivia)
+
+	return memoryPool
+}
@@ -0,0 +1,72 @@
+package external
+
+import (
+	"fmt"
+	"github.com/sflewis2970/trivia-api/messages"
+	"sync"
+	"testing"
+)
+
+func TestMemoryPoolOrder(t *testing.T) {
+	memoryPool := NewMemoryPool()
+	for _, questionID := range []string{"question-1", "question-2"} {
+		if pushErr := memoryPool.PushQuestion(STUB_CATEGORY, messages.Trivia{QuestionID: questionID}); pushErr != nil {
+			t.Fatalf("PushQuestion(%s) error = %v", questionID, pushErr)
+		}
+	}
+
+	if count, _ := memoryPool.QuestionCount(STUB_CATEGORY); count != 2 {
+		t.Errorf("QuestionCount() = %d, want 2", count)
+	}
+	if count, _ := memoryPool.QuestionCount("history"); count != 0 {
+		t.Errorf("QuestionCount(history) = %d, want 0", count)
+	}
+
+	// Questions are taken in the order they were added, until the list is empty
+	for _, want := range []string{"question-1", "question-2"} {
+		trivia, found, popErr := memoryPool.PopQuestion(STUB_CATEGORY)
+		if popErr != nil || !found || trivia.QuestionID != want {
+			t.Errorf("PopQuestion() = %q, %t, %v, want %q", trivia.QuestionID, found, popErr, want)
+		}
+	}
+	if _, found, popErr := memoryPool.PopQuestion(STUB_CATEGORY); found || popErr != nil {
+		t.Errorf("PopQuestion() on an empty list = %t, %v, want false, nil", found, popErr)
+	}
+}
+
+func TestMemoryPoolConcurrentUse(t *testing.T) {
+	const questions = 100
+
+	memoryPool := NewMemoryPool()
+	var waitGroup sync.WaitGroup
+	for idx := 0; idx < questions; idx++ {
+		waitGroup.Add(1)
+		go func(idx int) {
+			defer waitGroup.Done()
+			memoryPool.PushQuestion(STUB_CATEGORY, messages.Trivia{QuestionID: fmt.Sprint(idx)})
+		}(idx)
+	}
+	waitGroup.Wait()
+
+	// Every question is taken exactly once
+	taken := make(chan string, questions)
+	for idx := 0; idx < questions; idx++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if trivia, found, _ := memoryPool.PopQuestion(STUB_CATEGORY); found {
+				taken <- trivia.QuestionID
+			}
+		}()
+	}
+	waitGroup.Wait()
+	close(taken)
+
+	seen := make(map[string]bool)
+	for questionID := range taken {
+		seen[questionID] = true
+	}
+	if len(seen) != questions {
+		t.Errorf("questions taken = %d, want %d", len(seen), questions)
+	}
+}
@@ -0,0 +1,278 @@
+package external
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats counts how questions were served by a prefetcher
+type PoolStats struct {
+	// Hits and Misses count requests served from the pool and requests fetched live
+	Hits   uint64
+	Misses uint64
+
+	// Fetched and FetchErrors count questions fetched in the background and fetches that failed
+	Fetched     uint64
+	FetchErrors uint64
+}
+
+// Prefetcher serves questions from a pool kept topped up in the background, so requests do not wait on
+// the provider. Requests fall back to the provider when the pool for the category is empty.
+//
+// Each category is kept topped up to the watermark once it has been requested, along with the categories
+// passed to NewPrefetcher. The pool for an empty category holds questions in any category. Requests for a
+// difficulty always go to the provider.
+type Prefetcher struct {
+	// Counters come first so they are aligned for atomic access on 32-bit platforms
+	hits        uint64
+	misses      uint64
+	fetched     uint64
+	fetchErrors uint64
+
+	provider  TriviaProvider
+	pool      QuestionPool
+	watermark int
+
+	mutex      sync.Mutex
+	categories map[string]bool
+	queued     map[string]bool
+
+	refills   chan string
+	cancel    context.CancelFunc
+	waitGroup sync.WaitGroup
+	closeOnce sync.Once
+
+	logger *slog.Logger
+}
+
+// Name returns the name of the provider questions are fetched from
+func (p *Prefetcher) Name() string {
+	return p.provider.Name()
+}
+
+// Categories returns the categories of the provider questions are fetched from
+func (p *Prefetcher) Categories() []string {
+	return p.provider.Categories()
+}
+
+// GetTrivia returns a question for the category from the pool, or from the provider when the pool is empty
+func (p *Prefetcher) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	trivia, found, popErr := p.pool.PopQuestion(category)
+	if popErr != nil {
+		common.LoggerFromContext(ctx, p.logger).Error("Error getting trivia from the question pool",
+			common.CATEGORY_FIELD, category, common.ERROR_FIELD, popErr)
+	}
+
+	if SupportsCategory(p.provider, category) {
+		p.queueRefill(category)
+	}
+
+	if found {
+		atomic.AddUint64(&p.hits, 1)
+
+		// The question is issued now, not when it was fetched
+		trivia.Timestamp = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+		return trivia, nil
+	}
+
+	atomic.AddUint64(&p.misses, 1)
+
+	return p.provider.GetTrivia(ctx, category)
+}
+
+// GetTriviaByDifficulty returns a question for the category and difficulty. Only requests without a
+// difficulty are served from the pool.
+func (p *Prefetcher) GetTriviaByDifficulty(ctx context.Context, category string,
+	difficulty string) (messages.Trivia, error) {
+	if len(difficulty) == 0 {
+		return p.GetTrivia(ctx, category)
+	}
+
+	return GetTriviaByDifficulty(ctx, p.provider, category, difficulty)
+}
+
+// Stats returns the pool hit and miss counts and the background fetch counts
+func (p *Prefetcher) Stats() PoolStats {
+	return PoolStats{
+		Hits:        atomic.LoadUint64(&p.hits),
+		Misses:      atomic.LoadUint64(&p.misses),
+		Fetched:     atomic.LoadUint64(&p.fetched),
+		FetchErrors: atomic.LoadUint64(&p.fetchErrors),
+	}
+}
+
+// Close stops the background workers, cancelling fetches in progress, and waits for them to finish
+func (p *Prefetcher) Close() error {
+	p.closeOnce.Do(func() {
+		p.logger.Info("Stopping question prefetch workers")
+		p.cancel()
+		p.waitGroup.Wait()
+
+		stats := p.Stats()
+		p.logger.Info("Question pool stats", "hits", stats.Hits, "misses", stats.Misses, "fetched", stats.Fetched,
+			"fetch_errors", stats.FetchErrors)
+	})
+
+	return nil
+}
+
+// unexported type methods
+// wrappedProviders returns the provider questions are fetched from
+func (p *Prefetcher) wrappedProviders() []TriviaProvider {
+	return []TriviaProvider{p.provider}
+}
+
+// supportsDifficulty reports whether the provider questions are fetched from supports difficulty
+func (p *Prefetcher) supportsDifficulty() bool {
+	return SupportsDifficulty(p.provider)
+}
+
+// queueRefill keeps the category topped up and queues it to be refilled, unless it is queued already
+func (p *Prefetcher) queueRefill(category string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.categories[category] = true
+	if p.queued[category] {
+		return
+	}
+
+	select {
+	case p.refills <- category:
+		p.queued[category] = true
+	default:
+		// Every worker is busy, the category is refilled on the next tick
+	}
+}
+
+// queueAll queues every category kept topped up
+func (p *Prefetcher) queueAll() {
+	p.mutex.Lock()
+	categories := make([]string, 0, len(p.categories))
+	for category := range p.categories {
+		categories = append(categories, category)
+	}
+	p.mutex.Unlock()
+
+	sort.Strings(categories)
+	for _, category := range categories {
+		p.queueRefill(category)
+	}
+}
+
+// schedule queues every category at each interval until ctx is done
+func (p *Prefetcher) schedule(ctx context.Context, interval time.Duration) {
+	defer p.waitGroup.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.queueAll()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.queueAll()
+		}
+	}
+}
+
+// work refills queued categories until ctx is done
+func (p *Prefetcher) work(ctx context.Context) {
+	defer p.waitGroup.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case category := <-p.refills:
+			p.refill(ctx, category)
+
+			p.mutex.Lock()
+			delete(p.queued, category)
+			p.mutex.Unlock()
+		}
+	}
+}
+
+// refill fetches questions for the category until its pool reaches the watermark. A failed fetch ends
+// the refill; the category is tried again on the next tick.
+func (p *Prefetcher) refill(ctx context.Context, category string) {
+	logger := p.logger.With(common.CATEGORY_FIELD, category)
+
+	for ctx.Err() == nil {
+		count, countErr := p.pool.QuestionCount(category)
+		if countErr != nil {
+			logger.Error("Error counting questions in the question pool", common.ERROR_FIELD, countErr)
+			return
+		}
+		if count >= p.watermark {
+			return
+		}
+
+		trivia, triviaErr := p.provider.GetTrivia(ctx, category)
+		if triviaErr != nil {
+			if ctx.Err() == nil {
+				atomic.AddUint64(&p.fetchErrors, 1)
+				logger.Warn("Error prefetching trivia", common.ERROR_FIELD, triviaErr)
+			}
+			return
+		}
+
+		pushErr := p.pool.PushQuestion(category, trivia)
+		if pushErr != nil {
+			logger.Error("Error adding trivia to the question pool", common.ERROR_FIELD, pushErr)
+			return
+		}
+
+		atomic.AddUint64(&p.fetched, 1)
+	}
+}
+
+// NewPrefetcher creates a prefetcher that keeps the pool for each category topped up to watermark questions
+// from provider, and starts its workers. Categories are checked every interval, and as soon as a question
+// is taken from their pool. Close must be called to stop the workers.
+func NewPrefetcher(provider TriviaProvider, pool QuestionPool, watermark int, categories []string, workers int,
+	interval time.Duration) *Prefetcher {
+	logger := slog.Default()
+	if workers < 1 {
+		workers = 1
+	}
+
+	prefetcher := new(Prefetcher)
+	prefetcher.provider = provider
+	prefetcher.pool = pool
+	prefetcher.watermark = watermark
+	prefetcher.categories = make(map[string]bool)
+	prefetcher.queued = make(map[string]bool)
+	prefetcher.refills = make(chan string, workers)
+	prefetcher.logger = logger
+
+	for _, category := range categories {
+		if SupportsCategory(provider, category) {
+			prefetcher.categories[category] = true
+		} else {
+			logger.Warn("Not prefetching category, it is not supported by the provider", common.CATEGORY_FIELD,
+				category, "provider", provider.Name())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	prefetcher.cancel = cancel
+
+	logger.Info("Starting question prefetch workers", "workers", workers, "watermark", watermark)
+	prefetcher.waitGroup.Add(workers + 1)
+	for idx := 0; idx < workers; idx++ {
+		go prefetcher.work(ctx)
+	}
+	go prefetcher.schedule(ctx, interval)
+
+	return prefetcher
+}
@@ -0,0 +1,117 @@
+package external
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/messages"
+	"testing"
+	"time"
+)
+
+// blockingProvider serves no questions. Every request blocks until it is cancelled.
+type blockingProvider struct {
+	started chan struct{}
+}
+
+func (bp *blockingProvider) Name() string { return "blocking" }
+
+func (bp *blockingProvider) Categories() []string { return []string{STUB_CATEGORY} }
+
+func (bp *blockingProvider) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	select {
+	case bp.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+
+	return messages.Trivia{}, ctx.Err()
+}
+
+// waitFor waits up to a few seconds for condition to hold
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPrefetcherRefillsToWatermark(t *testing.T) {
+	const watermark = 3
+
+	provider := &stubProvider{name: "primary", categories: []string{STUB_CATEGORY}}
+	memoryPool := NewMemoryPool()
+	prefetcher := NewPrefetcher(provider, memoryPool, watermark, []string{STUB_CATEGORY}, 2, time.Hour)
+	defer prefetcher.Close()
+
+	poolFull := func() bool {
+		count, _ := memoryPool.QuestionCount(STUB_CATEGORY)
+		return count == watermark
+	}
+	waitFor(t, "the pool to be filled", poolFull)
+
+	// A question taken from the pool is replaced
+	trivia, triviaErr := prefetcher.GetTrivia(context.Background(), STUB_CATEGORY)
+	if triviaErr != nil || trivia.QuestionID != "primary-question" {
+		t.Fatalf("GetTrivia() = %q, %v, want a question from the pool", trivia.QuestionID, triviaErr)
+	}
+	waitFor(t, "the pool to be refilled", func() bool { return prefetcher.Stats().Fetched == watermark+1 })
+
+	prefetcher.Close()
+	if count, _ := memoryPool.QuestionCount(STUB_CATEGORY); count != watermark {
+		t.Errorf("QuestionCount() = %d, want %d", count, watermark)
+	}
+	if stats := prefetcher.Stats(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("Stats() = %+v, want 1 hit and no misses", stats)
+	}
+}
+
+func TestPrefetcherFallsBackToProvider(t *testing.T) {
+	provider := &stubProvider{name: "primary", categories: []string{STUB_CATEGORY}}
+	prefetcher := NewPrefetcher(provider, NewMemoryPool(), 3, nil, 1, time.Hour)
+	defer prefetcher.Close()
+
+	// Nothing is prefetched until the category is requested, so the first request goes to the provider
+	trivia, triviaErr := prefetcher.GetTrivia(context.Background(), STUB_CATEGORY)
+	if triviaErr != nil || trivia.QuestionID != "primary-question" {
+		t.Errorf("GetTrivia() = %q, %v, want a question from the provider", trivia.QuestionID, triviaErr)
+	}
+
+	prefetcher.Close()
+	if stats := prefetcher.Stats(); stats.Hits != 0 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want no hits and 1 miss", stats)
+	}
+}
+
+func TestPrefetcherCloseStopsWorkers(t *testing.T) {
+	provider := &blockingProvider{started: make(chan struct{}, 1)}
+	prefetcher := NewPrefetcher(provider, NewMemoryPool(), 3, []string{STUB_CATEGORY}, 2, time.Millisecond)
+
+	select {
+	case <-provider.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a fetch to start")
+	}
+
+	// Close cancels the fetch in progress and returns once every worker has ended
+	closed := make(chan struct{})
+	go func() {
+		prefetcher.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() did not return, the workers are still running")
+	}
+
+	if closeErr := prefetcher.Close(); closeErr != nil {
+		t.Errorf("Close() again error = %v", closeErr)
+	}
+	if stats := prefetcher.Stats(); stats.FetchErrors != 0 {
+		t.Errorf("Stats() = %+v, want cancelled fetches not counted as errors", stats)
+	}
+}
@@ -0,0 +1,205 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/external/Distractors"
+	"github.com/sflewis2970/trivia-api/external/OpenTriviaAPI"
+	"github.com/sflewis2970/trivia-api/external/OpenTriviaDB"
+	"github.com/sflewis2970/trivia-api/external/QuestionBank"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// TriviaProvider defines the operations a trivia question source must support
+type TriviaProvider interface {
+	// Name returns the name used to select the provider in config
+	Name() string
+
+	// Categories returns the list of categories the provider supports
+	Categories() []string
+
+	// GetTrivia returns a single trivia question for the requested category.
+	// When category is empty the provider chooses the category. Requests to an API stop when ctx is done.
+	GetTrivia(ctx context.Context, category string) (messages.Trivia, error)
+}
+
+// DifficultyProvider is implemented by providers that can filter questions by difficulty
+type DifficultyProvider interface {
+	// GetTriviaByDifficulty returns a single trivia question for the requested category and difficulty
+	GetTriviaByDifficulty(ctx context.Context, category string, difficulty string) (messages.Trivia, error)
+}
+
+// Pinger is implemented by providers that get questions from an upstream service, to check the service is
+// reachable without asking for a question
+type Pinger interface {
+	// Ping returns an error when the upstream service cannot be reached
+	Ping(ctx context.Context) error
+}
+
+// providerWrapper is implemented by providers that get questions from other providers
+type providerWrapper interface {
+	wrappedProviders() []TriviaProvider
+}
+
+// difficultySupporter is implemented by providers that wrap other providers. They can ask for questions
+// by difficulty, but only support difficulty when a wrapped provider does.
+type difficultySupporter interface {
+	supportsDifficulty() bool
+}
+
+// GetTriviaByDifficulty returns a single trivia question for the requested category and difficulty.
+// Providers that do not support difficulty are only asked for the category.
+func GetTriviaByDifficulty(ctx context.Context, provider TriviaProvider, category string,
+	difficulty string) (messages.Trivia, error) {
+	if difficultyProvider, ok := provider.(DifficultyProvider); ok {
+		return difficultyProvider.GetTriviaByDifficulty(ctx, category, difficulty)
+	}
+
+	return provider.GetTrivia(ctx, category)
+}
+
+// SupportsDifficulty reports whether the provider can filter questions by difficulty
+func SupportsDifficulty(provider TriviaProvider) bool {
+	if supporter, ok := provider.(difficultySupporter); ok {
+		return supporter.supportsDifficulty()
+	}
+
+	_, ok := provider.(DifficultyProvider)
+	return ok
+}
+
+// Providers returns the providers questions are fetched from, looking through chains and prefetchers to
+// the providers they wrap
+func Providers(provider TriviaProvider) []TriviaProvider {
+	wrapper, ok := provider.(providerWrapper)
+	if !ok {
+		return []TriviaProvider{provider}
+	}
+
+	var providers []TriviaProvider
+	for _, wrappedProvider := range wrapper.wrappedProviders() {
+		providers = append(providers, Providers(wrappedProvider)...)
+	}
+
+	return providers
+}
+
+// PingProvider checks that the upstream service of the provider is reachable. Providers without an upstream
+// service are always reachable.
+func PingProvider(ctx context.Context, provider TriviaProvider) error {
+	if pinger, ok := provider.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+// SupportsCategory reports whether the provider serves the category. An empty category is always supported.
+func SupportsCategory(provider TriviaProvider, category string) bool {
+	if len(category) == 0 {
+		return true
+	}
+
+	for _, providerCategory := range provider.Categories() {
+		if providerCategory == category {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetTriviaList returns count trivia questions from the provider for the requested category
+func GetTriviaList(ctx context.Context, provider TriviaProvider, category string, count int) ([]messages.Trivia, error) {
+	triviaList := make([]messages.Trivia, 0, count)
+
+	for idx := 0; idx < count; idx++ {
+		trivia, triviaErr := provider.GetTrivia(ctx, category)
+		if triviaErr != nil {
+			common.LoggerFromContext(ctx, nil).Warn("Error getting trivia from provider", "provider", provider.Name(),
+				common.ERROR_FIELD, triviaErr)
+			return nil, triviaErr
+		}
+
+		triviaList = append(triviaList, trivia)
+	}
+
+	return triviaList, nil
+}
+
+// NewTriviaProvider creates the trivia provider selected in config, followed by the fallback providers
+// in a provider chain, logging with logger. When no provider is configured the API-Ninjas provider is used.
+func NewTriviaProvider(cfgData *config.CfgData, logger *slog.Logger) (TriviaProvider, error) {
+	providerName := cfgData.TriviaProvider
+	if len(providerName) == 0 {
+		providerName = OpenTriviaAPI.ProviderName
+	}
+
+	// Client for providers that call an API
+	httpClient := common.NewHTTPClient(common.HTTPClientConfig{
+		Timeout:    cfgData.UpstreamTimeout,
+		MaxRetries: cfgData.UpstreamMaxRetries,
+		Backoff:    cfgData.UpstreamBackoff,
+		MaxBackoff: cfgData.UpstreamMaxBackoff,
+	})
+
+	var providers []TriviaProvider
+	for _, name := range append([]string{providerName}, cfgData.FallbackProviders...) {
+		provider, providerErr := newProvider(name, cfgData, httpClient, logger)
+		if providerErr != nil {
+			return nil, providerErr
+		}
+
+		providers = append(providers, provider)
+	}
+
+	providerChain, chainErr := NewProviderChain(providers, cfgData.BreakerFailureThreshold,
+		cfgData.BreakerOpenTimeout, cfgData.QuestionCacheSize)
+	if chainErr != nil {
+		return nil, chainErr
+	}
+
+	return providerChain, nil
+}
+
+// unexported functions
+// newProvider creates the named trivia provider
+func newProvider(providerName string, cfgData *config.CfgData, httpClient *common.HTTPClient,
+	logger *slog.Logger) (TriviaProvider, error) {
+	logger.Info("Creating trivia provider", "provider", providerName)
+
+	switch providerName {
+	case OpenTriviaAPI.ProviderName:
+		// Distractors are picked from the answers seen in each category
+		distractorEngine := Distractors.NewEngine(Distractors.DefaultPoolSize, rand.NewSource(time.Now().UnixNano()))
+		openTrivia, triviaErr := OpenTriviaAPI.NewOpenTrivia(cfgData.APINinjasURL, cfgData.RapidAPIHost,
+			cfgData.RapidAPIKey, distractorEngine, httpClient, logger)
+		if triviaErr != nil {
+			return nil, triviaErr
+		}
+		return openTrivia, nil
+	case QuestionBank.ProviderName:
+		questionBank, bankErr := QuestionBank.NewQuestionBank(cfgData.QuestionBankPath)
+		if bankErr != nil {
+			return nil, bankErr
+		}
+		return questionBank, nil
+	case OpenTriviaDB.ProviderName:
+		openTriviaDB, dbErr := OpenTriviaDB.NewOpenTriviaDB(cfgData.OpenTDBURL, cfgData.OpenTDBDifficulty,
+			cfgData.OpenTDBType, cfgData.OpenTDBEncoding, httpClient)
+		if dbErr != nil {
+			return nil, dbErr
+		}
+		return openTriviaDB, nil
+	default:
+		errMsg := fmt.Sprintf("trivia provider %s is invalid", providerName)
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/external"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"log/slog"
+	"net/http"
+)
+
+// GAME_ID_VAR is the route variable holding the game ID
+const GAME_ID_VAR string = "gameid"
+
+type GameHandler struct {
+	triviaProvider   external.TriviaProvider
+	gameModel        *models.GameModel
+	leaderboardModel *models.LeaderboardModel
+	seenModel        *models.SeenModel
+	logger           *slog.Logger
+}
+
+// StartGame is a http handler that receives a client "POST" request to start a game.
+// The request uses the form of: 'http://<server-name>:8080/api/v1/api/games' including a
+// json object:
+//
+//	"category": "<optional category for every question in the game>",
+//	"totalquestions": "<optional number of questions, defaults to 10>",
+//	"difficulty": "<optional difficulty, when supported by the provider>"
+//
+// The request returns a GameResponse object.
+func (gh *GameHandler) StartGame(rw http.ResponseWriter, r *http.Request) {
+	var gRequest messages.GameRequest
+	var gResponse messages.GameResponse
+	logger := common.LoggerFromContext(r.Context(), gh.logger)
+
+	// Read JSON from stream
+	decodeErr := json.NewDecoder(r.Body).Decode(&gRequest)
+	if decodeErr != nil {
+		logger.Warn("Error decoding json", common.ERROR_FIELD, decodeErr)
+
+		// Update GameResponse
+		gResponse.Error = decodeErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(http.StatusBadRequest)
+
+		// Write JSON to stream
+		encodeResponse(rw, r, gResponse)
+		return
+	}
+
+	// validate category
+	if !external.SupportsCategory(gh.triviaProvider, gRequest.Category) {
+		gResponse.Error = fmt.Sprintf("%s is invalid", gRequest.Category)
+
+		rw.WriteHeader(http.StatusBadRequest)
+		encodeResponse(rw, r, gResponse)
+		return
+	}
+
+	// Ignore difficulty when the provider cannot filter questions by difficulty
+	if len(gRequest.Difficulty) > 0 && !external.SupportsDifficulty(gh.triviaProvider) {
+		gResponse.Warning = fmt.Sprintf("difficulty is not supported by provider %s", gh.triviaProvider.Name())
+		gRequest.Difficulty = ""
+	}
+
+	// Games belong to the player who started them
+	player, _ := PlayerFromContext(r.Context())
+	gRequest.PlayerID = player.PlayerID
+
+	// Send request to model to start the game
+	game, startErr := gh.gameModel.StartGame(r.Context(), gRequest)
+	if startErr != nil {
+		logger.Warn("Error starting game", common.ERROR_FIELD, startErr)
+
+		// Update GameResponse
+		gResponse.Error = startErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(gameErrorStatus(startErr))
+
+		// Write JSON to stream
+		encodeResponse(rw, r, gResponse)
+		return
+	}
+
+	// Update GameResponse struct
+	gResponse.GameID = game.GameID
+	gResponse.Category = game.Category
+	gResponse.Difficulty = game.Difficulty
+	gResponse.TotalQuestions = game.TotalQuestions
+
+	// Update HTTP Header
+	rw.WriteHeader(http.StatusCreated)
+
+	// Write JSON to stream
+	encodeResponse(rw, r, gResponse)
+}
+
+// NextQuestion is a http handler that receives a client "GET" request for the next question in a game.
+// The format used is: 'http://<server-name>:8080/api/v1/api/games/{gameid}/question'.
+// When the current question has not been answered yet, the same question is returned again.
+// The request returns a GameQuestionResponse object.
+func (gh *GameHandler) NextQuestion(rw http.ResponseWriter, r *http.Request) {
+	var gqResponse messages.GameQuestionResponse
+	gqResponse.GameID = mux.Vars(r)[GAME_ID_VAR]
+	player, _ := PlayerFromContext(r.Context())
+	logger := common.LoggerFromContext(r.Context(), gh.logger).With(common.GAME_ID_FIELD, gqResponse.GameID)
+
+	// Get game from model
+	game, getErr := gh.gameModel.GetGame(r.Context(), gqResponse.GameID, player.PlayerID)
+	if getErr != nil {
+		writeGameQuestionError(rw, r, logger, gqResponse, getErr)
+		return
+	}
+
+	// Return the current question again when it has not been answered
+	game, trivia, pending, pendingErr := gh.gameModel.PendingQuestion(r.Context(), game)
+	if pendingErr != nil {
+		writeGameQuestionError(rw, r, logger, gqResponse, pendingErr)
+		return
+	}
+
+	if !pending {
+		if models.GameCompleted(game) {
+			writeGameQuestionError(rw, r, logger, gqResponse, models.ErrGameFinished)
+			return
+		}
+
+		// Process API Get Request
+		var triviaErr error
+		trivia, triviaErr = getUnseenTrivia(r.Context(), gh.triviaProvider, gh.seenModel, player.PlayerID,
+			game.Category, game.Difficulty)
+		if triviaErr != nil {
+			writeGameQuestionError(rw, r, logger, gqResponse, triviaErr)
+			return
+		}
+
+		// Send request to model to issue the question
+		var addErr error
+		game, addErr = gh.gameModel.AddQuestion(r.Context(), game, trivia)
+		if addErr != nil {
+			writeGameQuestionError(rw, r, logger, gqResponse, addErr)
+			return
+		}
+	}
+
+	// Update GameQuestionResponse struct
+	gqResponse.QuestionNumber = len(game.Results) + 1
+	gqResponse.TotalQuestions = game.TotalQuestions
+	gqResponse.QuestionID = trivia.QuestionID
+	gqResponse.Category = trivia.Category
+	gqResponse.Question = trivia.Question
+	gqResponse.Type = trivia.Type
+	gqResponse.Choices = trivia.Choices
+	gqResponse.Timestamp = trivia.Timestamp
+	gqResponse.Provider = trivia.Provider
+
+	// Update HTTP Header
+	rw.WriteHeader(http.StatusOK)
+
+	// Write JSON to stream
+	encodeResponse(rw, r, gqResponse)
+}
+
+// AnswerQuestion is a http handler that receives the answer to the current question in a game.
+// The request uses the form of: 'http://<server-name>:8080/api/v1/api/games/{gameid}/answer' including a
+// json object:
+//
+//	"questionid": "<id received in the question response>",
+//	"response": "<answer question from list of choices>"
+//
+// The request returns a GameAnswerResponse object with the running score.
+func (gh *GameHandler) AnswerQuestion(rw http.ResponseWriter, r *http.Request) {
+	var aRequest messages.AnswerRequest
+	var gaResponse messages.GameAnswerResponse
+	gaResponse.GameID = mux.Vars(r)[GAME_ID_VAR]
+	logger := common.LoggerFromContext(r.Context(), gh.logger).With(common.GAME_ID_FIELD, gaResponse.GameID)
+
+	// Read JSON from stream
+	decodeErr := json.NewDecoder(r.Body).Decode(&aRequest)
+	if decodeErr != nil {
+		logger.Warn("Error decoding json", common.ERROR_FIELD, decodeErr)
+
+		// Update GameAnswerResponse
+		gaResponse.Error = decodeErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(http.StatusBadRequest)
+
+		// Write JSON to stream
+		encodeResponse(rw, r, gaResponse)
+		return
+	}
+
+	// Answers are attributed to the authenticated player
+	player, _ := PlayerFromContext(r.Context())
+	aRequest.PlayerID = player.PlayerID
+	aRequest.Locales = requestLocales(r)
+
+	// Send a request to the model for the answer
+	game, aResponse, answerErr := gh.gameModel.AnswerQuestion(r.Context(), gaResponse.GameID, aRequest)
+	gaResponse.AnswerResponse = aResponse
+	gaResponse.Answered = len(game.Results)
+	gaResponse.Score = game.Score
+	gaResponse.TotalPoints = game.Points
+	gaResponse.Completed = len(game.GameID) > 0 && models.GameCompleted(game)
+
+	if answerErr != nil {
+		logger.Warn("Error answering game question", common.QUESTION_ID_FIELD, aRequest.QuestionID,
+			common.ERROR_FIELD, answerErr)
+
+		// Update GameAnswerResponse
+		gaResponse.Error = answerErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(gameErrorStatus(answerErr))
+
+		// Write JSON to stream
+		encodeResponse(rw, r, gaResponse)
+		return
+	}
+
+	// Update leaderboards for identified players
+	recordScore(r.Context(), gh.leaderboardModel, aRequest, aResponse)
+
+	// Send OK status
+	rw.WriteHeader(http.StatusOK)
+
+	// Encode response
+	encodeResponse(rw, r, gaResponse)
+}
+
+// GameSummary is a http handler that receives a client "GET" request for the results of a game.
+// The format used is: 'http://<server-name>:8080/api/v1/api/games/{gameid}/summary'.
+// The request returns a GameSummaryResponse object with the score, accuracy and per-question results.
+func (gh *GameHandler) GameSummary(rw http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)[GAME_ID_VAR]
+	player, _ := PlayerFromContext(r.Context())
+
+	// Get game from model
+	game, getErr := gh.gameModel.GetGame(r.Context(), gameID, player.PlayerID)
+	if getErr != nil {
+		var gsResponse messages.GameSummaryResponse
+		gsResponse.GameID = gameID
+		gsResponse.Error = getErr.Error()
+
+		rw.WriteHeader(gameErrorStatus(getErr))
+		encodeResponse(rw, r, gsResponse)
+		return
+	}
+
+	// Send OK status
+	rw.WriteHeader(http.StatusOK)
+
+	// Encode response
+	encodeResponse(rw, r, models.NewGameSummary(game))
+}
+
+// NewGameHandler creates a game handler that gets questions from triviaProvider,
+// keeps games in gameModel, adds correct answers to leaderboardModel and avoids
+// questions the player has seen with seenModel
+func NewGameHandler(triviaProvider external.TriviaProvider, gameModel *models.GameModel,
+	leaderboardModel *models.LeaderboardModel, seenModel *models.SeenModel) *GameHandler {
+	logger := slog.Default()
+	gameHandler := new(GameHandler)
+
+	// Set logger
+	gameHandler.logger = logger
+
+	// Set trivia provider
+	gameHandler.triviaProvider = triviaProvider
+
+	// Set game model
+	gameHandler.gameModel = gameModel
+
+	// Set leaderboard model
+	gameHandler.leaderboardModel = leaderboardModel
+
+	// Set seen question model
+	gameHandler.seenModel = seenModel
+
+	return gameHandler
+}
+
+// unexported functions
+func writeGameQuestionError(rw http.ResponseWriter, r *http.Request, logger *slog.Logger,
+	gqResponse messages.GameQuestionResponse, err error) {
+	logger.Warn("Error getting game question", common.ERROR_FIELD, err)
+
+	// Update GameQuestionResponse
+	gqResponse.Error = err.Error()
+
+	// Update HTTP Header
+	rw.WriteHeader(gameErrorStatus(err))
+
+	// Write JSON to stream
+	encodeResponse(rw, r, gqResponse)
+}
+
+// gameErrorStatus maps game errors to a HTTP status
+func gameErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidGameRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrGameNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrGameFinished), errors.Is(err, models.ErrQuestionNotInGame),
+		errors.Is(err, models.ErrGameChanged), errors.Is(err, models.ErrGameExists):
+		return http.StatusConflict
+	case common.IsUpstreamError(err):
+		return questionErrorStatus(err)
+	default:
+		return answerErrorStatus(err)
+	}
+}
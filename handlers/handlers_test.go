@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const QUIZ_CATEGORY string = "math"
+
+// quizProvider serves numbered addition questions. The answer to question n is n + n.
+type quizProvider struct {
+	issued int32
+}
+
+func (qp *quizProvider) Name() string { return "quiz" }
+
+func (qp *quizProvider) Categories() []string { return []string{QUIZ_CATEGORY} }
+
+func (qp *quizProvider) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	number := int(atomic.AddInt32(&qp.issued, 1))
+	answer := fmt.Sprint(number + number)
+
+	return messages.Trivia{
+		QuestionID: fmt.Sprintf("question-%d", number),
+		Question:   fmt.Sprintf("What is %d + %d?", number, number),
+		Category:   QUIZ_CATEGORY,
+		Type:       messages.MULTIPLE_CHOICE,
+		Answer:     answer,
+		Choices:    []string{answer, "0", "-1", "-2"},
+		Provider:   qp.Name(),
+	}, nil
+}
+
+// barrierStore holds back reads of questions once armed, until the set number of reads have been made,
+// so requests read the same question before any of them deletes it
+type barrierStore struct {
+	*models.MemoryModel
+	armed atomic.Bool
+	reads sync.WaitGroup
+}
+
+func (bs *barrierStore) Get(ctx context.Context, questionID string) (messages.TriviaTable, error) {
+	tTable, getErr := bs.MemoryModel.Get(ctx, questionID)
+	if bs.armed.Load() {
+		bs.reads.Done()
+		bs.reads.Wait()
+	}
+
+	return tTable, getErr
+}
+
+// newTestServer serves the player, trivia, game and leaderboard routes over a memory store
+func newTestServer(t *testing.T) *httptest.Server {
+	return newTestStoreServer(t, models.NewMemoryModel())
+}
+
+// newTestStoreServer serves the player, trivia, game and leaderboard routes over memoryModel, which must
+// also implement the game and player stores
+func newTestStoreServer(t *testing.T, memoryModel models.TriviaStore) *httptest.Server {
+	logger := common.NewLogger(io.Discard, "error", "text")
+	cfgData := config.NewDefaultCfgData()
+
+	messageCatalog, catalogErr := models.NewMessageCatalog(cfgData)
+	if catalogErr != nil {
+		t.Fatalf("NewMessageCatalog() error = %v", catalogErr)
+	}
+	answerMatcher, matcherErr := models.NewAnswerMatcher(cfgData)
+	if matcherErr != nil {
+		t.Fatalf("NewAnswerMatcher() error = %v", matcherErr)
+	}
+	playerModel, playerErr := models.NewPlayerModel(memoryModel)
+	if playerErr != nil {
+		t.Fatalf("NewPlayerModel() error = %v", playerErr)
+	}
+	triviaModel := models.NewTriviaModel(memoryModel, messageCatalog, answerMatcher, logger)
+	gameModel, gameErr := models.NewGameModel(memoryModel, triviaModel)
+	if gameErr != nil {
+		t.Fatalf("NewGameModel() error = %v", gameErr)
+	}
+	leaderboardModel := models.NewLeaderboardModel(models.NewMemoryScoreStore())
+	seenModel := models.NewSeenModel(cfgData, models.NewMemoryScoreStore())
+
+	provider := new(quizProvider)
+	playerHandler := NewPlayerHandler(playerModel, seenModel)
+	triviaHandler := NewTriviaHandler(provider, triviaModel, leaderboardModel, seenModel, logger)
+	gameHandler := NewGameHandler(provider, gameModel, leaderboardModel, seenModel)
+	leaderboardHandler := NewLeaderboardHandler(leaderboardModel)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/players", playerHandler.RegisterPlayer).Methods("POST")
+	router.HandleFunc("/leaderboards/{board}", leaderboardHandler.GetLeaderboard).Methods("GET")
+	router.HandleFunc("/leaderboards/{board}/players/{playerid}", leaderboardHandler.GetPlayerRank).Methods("GET")
+
+	authRouter := router.NewRoute().Subrouter()
+	authRouter.Use(playerHandler.Authenticate)
+	authRouter.HandleFunc("/getquestion", triviaHandler.GetQuestion).Methods("GET")
+	authRouter.HandleFunc("/answerquestion", triviaHandler.AnswerQuestion).Methods("POST")
+	authRouter.HandleFunc("/games", gameHandler.StartGame).Methods("POST")
+	authRouter.HandleFunc("/games/{gameid}/question", gameHandler.NextQuestion).Methods("GET")
+	authRouter.HandleFunc("/games/{gameid}/answer", gameHandler.AnswerQuestion).Methods("POST")
+	authRouter.HandleFunc("/games/{gameid}/summary", gameHandler.GameSummary).Methods("GET")
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// doRequest sends a request with apiKey to the server, checks the status and decodes the response
+func doRequest(t *testing.T, server *httptest.Server, method string, path string, apiKey string, body any,
+	wantStatus int, response any) {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		encoded, encodeErr := json.Marshal(body)
+		if encodeErr != nil {
+			t.Fatalf("json.Marshal() error = %v", encodeErr)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	request, requestErr := http.NewRequest(method, server.URL+path, reqBody)
+	if requestErr != nil {
+		t.Fatalf("http.NewRequest() error = %v", requestErr)
+	}
+	if len(apiKey) > 0 {
+		request.Header.Set(AUTHORIZATION_HEADER, BEARER_PREFIX+apiKey)
+	}
+
+	resp, doErr := server.Client().Do(request)
+	if doErr != nil {
+		t.Fatalf("%s %s error = %v", method, path, doErr)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s status = %d, want %d", method, path, resp.StatusCode, wantStatus)
+	}
+
+	if decodeErr := json.NewDecoder(resp.Body).Decode(response); decodeErr != nil {
+		t.Fatalf("%s %s decode error = %v", method, path, decodeErr)
+	}
+}
+
+// registerPlayer registers a player and returns it with its API key
+func registerPlayer(t *testing.T, server *httptest.Server, name string) messages.PlayerResponse {
+	t.Helper()
+
+	var pResponse messages.PlayerResponse
+	doRequest(t, server, "POST", "/players", "", messages.PlayerRequest{Name: name}, http.StatusCreated, &pResponse)
+	if len(pResponse.PlayerID) == 0 || len(pResponse.APIKey) == 0 {
+		t.Fatalf("RegisterPlayer() = %+v, want a player ID and API key", pResponse)
+	}
+
+	return pResponse
+}
+
+// correctAnswer returns the answer to a question served by quizProvider
+func correctAnswer(t *testing.T, question string) string {
+	t.Helper()
+
+	var number int
+	if _, scanErr := fmt.Sscanf(question, "What is %d +", &number); scanErr != nil {
+		t.Fatalf("question %q is not a quiz question", question)
+	}
+
+	return fmt.Sprint(number + number)
+}
+
+func TestQuestionAndLeaderboard(t *testing.T) {
+	server := newTestServer(t)
+	player := registerPlayer(t, server, "alice")
+
+	// Questions require an API key
+	var qResponse messages.QuestionResponse
+	doRequest(t, server, "GET", "/getquestion", "", nil, http.StatusUnauthorized, &messages.PlayerResponse{})
+	doRequest(t, server, "GET", "/getquestion", player.APIKey, nil, http.StatusCreated, &qResponse)
+	if len(qResponse.QuestionID) == 0 || qResponse.Category != QUIZ_CATEGORY || len(qResponse.Choices) != 4 {
+		t.Fatalf("GetQuestion() = %+v", qResponse)
+	}
+
+	var aResponse messages.AnswerResponse
+	aRequest := messages.AnswerRequest{QuestionID: qResponse.QuestionID, Response: correctAnswer(t, qResponse.Question)}
+	doRequest(t, server, "POST", "/answerquestion", player.APIKey, aRequest, http.StatusOK, &aResponse)
+	if !aResponse.Correct || aResponse.QuestionID != qResponse.QuestionID {
+		t.Errorf("AnswerQuestion() = %+v, want a correct answer to %s", aResponse, qResponse.QuestionID)
+	}
+
+	// Answered questions are removed
+	doRequest(t, server, "POST", "/answerquestion", player.APIKey, aRequest, http.StatusNotFound,
+		&messages.AnswerResponse{})
+
+	var lResponse messages.LeaderboardResponse
+	doRequest(t, server, "GET", "/leaderboards/overall", "", nil, http.StatusOK, &lResponse)
+	wantEntry := messages.LeaderboardEntry{Rank: 1, PlayerID: player.PlayerID, Score: 1}
+	if len(lResponse.Entries) != 1 || lResponse.Entries[0] != wantEntry {
+		t.Errorf("GetLeaderboard() entries = %+v, want [%+v]", lResponse.Entries, wantEntry)
+	}
+
+	var prResponse messages.PlayerRankResponse
+	doRequest(t, server, "GET", "/leaderboards/daily/players/"+player.PlayerID, "", nil, http.StatusOK, &prResponse)
+	if prResponse.LeaderboardEntry != wantEntry {
+		t.Errorf("GetPlayerRank() = %+v, want %+v", prResponse.LeaderboardEntry, wantEntry)
+	}
+}
+
+func TestGameSummary(t *testing.T) {
+	server := newTestServer(t)
+	player := registerPlayer(t, server, "bob")
+	other := registerPlayer(t, server, "carol")
+
+	var gResponse messages.GameResponse
+	gRequest := messages.GameRequest{Category: QUIZ_CATEGORY, TotalQuestions: 2}
+	doRequest(t, server, "POST", "/games", player.APIKey, gRequest, http.StatusCreated, &gResponse)
+	gamePath := "/games/" + gResponse.GameID
+
+	// Games can only be played by the player who started them
+	doRequest(t, server, "GET", gamePath+"/question", other.APIKey, nil, http.StatusNotFound,
+		&messages.GameQuestionResponse{})
+
+	// The first question is answered correctly and the second incorrectly
+	for number, correct := range []bool{true, false} {
+		var gqResponse messages.GameQuestionResponse
+		doRequest(t, server, "GET", gamePath+"/question", player.APIKey, nil, http.StatusOK, &gqResponse)
+		if gqResponse.QuestionNumber != number+1 {
+			t.Errorf("NextQuestion() question number = %d, want %d", gqResponse.QuestionNumber, number+1)
+		}
+
+		// The current question is served again until it is answered
+		var again messages.GameQuestionResponse
+		doRequest(t, server, "GET", gamePath+"/question", player.APIKey, nil, http.StatusOK, &again)
+		if again.QuestionID != gqResponse.QuestionID {
+			t.Errorf("NextQuestion() again = %s, want %s", again.QuestionID, gqResponse.QuestionID)
+		}
+
+		aRequest := messages.AnswerRequest{QuestionID: gqResponse.QuestionID, Response: "0"}
+		if correct {
+			aRequest.Response = correctAnswer(t, gqResponse.Question)
+		}
+
+		var gaResponse messages.GameAnswerResponse
+		doRequest(t, server, "POST", gamePath+"/answer", player.APIKey, aRequest, http.StatusOK, &gaResponse)
+		if gaResponse.Correct != correct || gaResponse.Answered != number+1 {
+			t.Errorf("AnswerQuestion() = %+v, want correct %v after %d answers", gaResponse, correct, number+1)
+		}
+	}
+
+	// Finished games have no more questions
+	doRequest(t, server, "GET", gamePath+"/question", player.APIKey, nil, http.StatusConflict,
+		&messages.GameQuestionResponse{})
+
+	var gsResponse messages.GameSummaryResponse
+	doRequest(t, server, "GET", gamePath+"/summary", player.APIKey, nil, http.StatusOK, &gsResponse)
+	if !gsResponse.Completed || gsResponse.Answered != 2 || gsResponse.Score != 1 || gsResponse.Accuracy != 50 {
+		t.Errorf("GameSummary() = %+v, want 1 of 2 correct", gsResponse)
+	}
+	if len(gsResponse.Results) != 2 || !gsResponse.Results[0].Correct || gsResponse.Results[1].Correct {
+		t.Errorf("GameSummary() results = %+v", gsResponse.Results)
+	}
+	if len(gsResponse.Finished) == 0 {
+		t.Error("GameSummary() finished is empty")
+	}
+
+	var lResponse messages.LeaderboardResponse
+	doRequest(t, server, "GET", "/leaderboards/weekly", "", nil, http.StatusOK, &lResponse)
+	if len(lResponse.Entries) != 1 || lResponse.Entries[0].PlayerID != player.PlayerID {
+		t.Errorf("GetLeaderboard() entries = %+v, want only %s", lResponse.Entries, player.PlayerID)
+	}
+}
+
+func TestAnswerQuestionConcurrently(t *testing.T) {
+	const answers = 10
+
+	triviaStore := &barrierStore{MemoryModel: models.NewMemoryModel()}
+	server := newTestStoreServer(t, triviaStore)
+	player := registerPlayer(t, server, "dave")
+
+	var qResponse messages.QuestionResponse
+	doRequest(t, server, "GET", "/getquestion", player.APIKey, nil, http.StatusCreated, &qResponse)
+
+	// The same correct answer is sent several times at once, every request reads the question
+	aRequest := messages.AnswerRequest{QuestionID: qResponse.QuestionID, Response: correctAnswer(t, qResponse.Question)}
+	triviaStore.reads.Add(answers)
+	triviaStore.armed.Store(true)
+
+	var waitGroup sync.WaitGroup
+	statuses := make(chan int, answers)
+	for idx := 0; idx < answers; idx++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			encoded, _ := json.Marshal(aRequest)
+			request, _ := http.NewRequest("POST", server.URL+"/answerquestion", bytes.NewReader(encoded))
+			request.Header.Set(AUTHORIZATION_HEADER, BEARER_PREFIX+player.APIKey)
+			resp, doErr := server.Client().Do(request)
+			if doErr != nil {
+				t.Errorf("POST /answerquestion error = %v", doErr)
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	waitGroup.Wait()
+	close(statuses)
+	triviaStore.armed.Store(false)
+
+	// Only one answer is scored, the others find the question gone
+	answered := 0
+	for status := range statuses {
+		switch status {
+		case http.StatusOK:
+			answered++
+		case http.StatusNotFound:
+		default:
+			t.Errorf("POST /answerquestion status = %d, want %d or %d", status, http.StatusOK, http.StatusNotFound)
+		}
+	}
+	if answered != 1 {
+		t.Errorf("answers scored = %d, want 1", answered)
+	}
+
+	var prResponse messages.PlayerRankResponse
+	doRequest(t, server, "GET", "/leaderboards/overall/players/"+player.PlayerID, "", nil, http.StatusOK, &prResponse)
+	if prResponse.Score != 1 {
+		t.Errorf("leaderboard score = %v, want 1", prResponse.Score)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/external"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// HEALTH_CHECK_INTERVAL is the wait between checks of the dependencies while the service starts
+	HEALTH_CHECK_INTERVAL time.Duration = 2 * time.Second
+
+	// Names the dependencies are reported by. Providers are reported as provider:<name>.
+	REDIS_DEPENDENCY    string = "redis"
+	STORE_DEPENDENCY    string = "store"
+	PROVIDER_DEPENDENCY string = "provider:"
+)
+
+// dependencyCheck checks a dependency of the service
+type dependencyCheck struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// HealthHandler answers liveness and readiness checks. The service is ready once its dependencies have
+// responded at startup, and for as long as the data stores in use and at least one trivia provider are
+// reachable. Each provider in a chain is checked, but only one of them needs to be reachable.
+type HealthHandler struct {
+	// started is set once the dependencies have responded at startup
+	started int32
+
+	checks       []dependencyCheck
+	checkTimeout time.Duration
+	logger       *slog.Logger
+}
+
+// Healthz is a http handler reporting that the process is alive. Dependencies are not checked.
+func (hh *HealthHandler) Healthz(rw http.ResponseWriter, r *http.Request) {
+	var hResponse messages.HealthResponse
+	hResponse.Status = messages.STATUS_OK
+	hResponse.Timestamp = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+
+	// Update HTTP Header
+	rw.WriteHeader(http.StatusOK)
+
+	// Write JSON to stream
+	encodeResponse(rw, r, hResponse)
+}
+
+// Readyz is a http handler reporting whether the service is ready to serve requests, along with the
+// status and latency of each dependency. The service is not ready until its dependencies have
+// responded at startup.
+func (hh *HealthHandler) Readyz(rw http.ResponseWriter, r *http.Request) {
+	hResponse := hh.CheckDependencies(r.Context())
+	if atomic.LoadInt32(&hh.started) == 0 {
+		hResponse.Status = messages.STATUS_STARTING
+	}
+
+	// Update HTTP Header
+	if hResponse.Status == messages.STATUS_OK {
+		rw.WriteHeader(http.StatusOK)
+	} else {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	// Write JSON to stream
+	encodeResponse(rw, r, hResponse)
+}
+
+// CheckDependencies checks every dependency at once, each limited to the check timeout
+func (hh *HealthHandler) CheckDependencies(ctx context.Context) messages.HealthResponse {
+	dependencies := make([]messages.DependencyStatus, len(hh.checks))
+
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(len(hh.checks))
+	for idx, check := range hh.checks {
+		go func(idx int, check dependencyCheck) {
+			defer waitGroup.Done()
+			dependencies[idx] = hh.checkDependency(ctx, check)
+		}(idx, check)
+	}
+	waitGroup.Wait()
+
+	var hResponse messages.HealthResponse
+	hResponse.Status = dependencyHealth(dependencies)
+	hResponse.Timestamp = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+	hResponse.Dependencies = dependencies
+
+	return hResponse
+}
+
+// WaitForDependencies checks the dependencies every interval until they respond, then marks the service
+// as ready. The error of ctx is returned when it is done first.
+func (hh *HealthHandler) WaitForDependencies(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		hResponse := hh.CheckDependencies(ctx)
+		if hResponse.Status == messages.STATUS_OK {
+			atomic.StoreInt32(&hh.started, 1)
+			return nil
+		}
+
+		for _, dependency := range hResponse.Dependencies {
+			if dependency.Status != messages.STATUS_OK {
+				hh.logger.Warn("Waiting for dependency", "dependency", dependency.Name, common.ERROR_FIELD,
+					dependency.Error)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// unexported type methods
+func (hh *HealthHandler) checkDependency(ctx context.Context, check dependencyCheck) messages.DependencyStatus {
+	checkCtx, cancel := context.WithTimeout(ctx, hh.checkTimeout)
+	defer cancel()
+
+	started := time.Now()
+	checkErr := check.check(checkCtx)
+
+	var dependency messages.DependencyStatus
+	dependency.Name = check.name
+	dependency.Required = check.required
+	dependency.Latency = float64(time.Since(started).Microseconds()) / 1000
+	dependency.Status = messages.STATUS_OK
+	if checkErr != nil {
+		dependency.Status = messages.STATUS_FAILING
+		dependency.Error = checkErr.Error()
+	}
+
+	return dependency
+}
+
+// NewHealthHandler creates a health handler checking redisModel when Redis is used, triviaStore when it is
+// not redisModel, and every provider behind triviaProvider. Each check is limited to checkTimeout.
+func NewHealthHandler(redisModel *models.RedisModel, triviaStore models.TriviaStore,
+	triviaProvider external.TriviaProvider, checkTimeout time.Duration, logger *slog.Logger) *HealthHandler {
+	healthHandler := new(HealthHandler)
+	healthHandler.checkTimeout = checkTimeout
+	healthHandler.logger = logger
+
+	// Redis can hold leaderboards, seen questions, prefetched questions and rate limits even when it is not
+	// the trivia store. redisModel is nil when nothing is kept in Redis.
+	if redisModel != nil {
+		healthHandler.checks = append(healthHandler.checks, dependencyCheck{name: REDIS_DEPENDENCY, required: true,
+			check: redisModel.Ping})
+	}
+	if storeModel, isRedisModel := triviaStore.(*models.RedisModel); !isRedisModel || storeModel != redisModel {
+		healthHandler.checks = append(healthHandler.checks, dependencyCheck{name: STORE_DEPENDENCY,
+			required: true, check: triviaStore.Ping})
+	}
+
+	for _, provider := range external.Providers(triviaProvider) {
+		provider := provider
+		healthHandler.checks = append(healthHandler.checks, dependencyCheck{
+			name: PROVIDER_DEPENDENCY + provider.Name(),
+			check: func(ctx context.Context) error {
+				return external.PingProvider(ctx, provider)
+			},
+		})
+	}
+
+	return healthHandler
+}
+
+// unexported functions
+// dependencyHealth returns ok when every required dependency and at least one provider are reachable
+func dependencyHealth(dependencies []messages.DependencyStatus) string {
+	providerOK := false
+	for _, dependency := range dependencies {
+		if dependency.Status == messages.STATUS_OK && !dependency.Required {
+			providerOK = true
+		} else if dependency.Status != messages.STATUS_OK && dependency.Required {
+			return messages.STATUS_FAILING
+		}
+	}
+
+	if !providerOK {
+		return messages.STATUS_FAILING
+	}
+
+	return messages.STATUS_OK
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubProvider is a trivia provider without an upstream service
+type stubProvider struct{}
+
+func (sp stubProvider) Name() string { return "stub" }
+
+func (sp stubProvider) Categories() []string { return nil }
+
+func (sp stubProvider) GetTrivia(ctx context.Context, category string) (messages.Trivia, error) {
+	return messages.Trivia{}, nil
+}
+
+func TestHealthHandlerWithoutRedis(t *testing.T) {
+	logger := common.NewLogger(io.Discard, "error", "text")
+	healthHandler := NewHealthHandler(nil, models.NewMemoryModel(), stubProvider{}, time.Second, logger)
+
+	hResponse := healthHandler.CheckDependencies(context.Background())
+	if hResponse.Status != messages.STATUS_OK {
+		t.Errorf("Status = %q, want %q", hResponse.Status, messages.STATUS_OK)
+	}
+
+	var names []string
+	for _, dependency := range hResponse.Dependencies {
+		names = append(names, dependency.Name)
+	}
+	want := []string{STORE_DEPENDENCY, PROVIDER_DEPENDENCY + "stub"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("dependencies = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// Leaderboard route variables and query parameters
+const (
+	BOARD_VAR     string = "board"
+	CATEGORY_VAR  string = "category"
+	PLAYER_ID_VAR string = "playerid"
+	COUNT_PARAM   string = "count"
+)
+
+type LeaderboardHandler struct {
+	leaderboardModel *models.LeaderboardModel
+	logger           *slog.Logger
+}
+
+// GetLeaderboard is a http handler that receives a client "GET" request for the top players on a leaderboard.
+// The formats used are:
+//
+//	'http://<server-name>:8080/api/v1/api/leaderboards/{board}?count=10' where board is overall, daily or weekly
+//	'http://<server-name>:8080/api/v1/api/leaderboards/category/{category}?count=10'
+//
+// The request returns a LeaderboardResponse object.
+func (lh *LeaderboardHandler) GetLeaderboard(rw http.ResponseWriter, r *http.Request) {
+	var lResponse messages.LeaderboardResponse
+	lResponse.Board, lResponse.Category = leaderboardVars(r)
+
+	count := 0
+	countParam := r.URL.Query().Get(COUNT_PARAM)
+	if len(countParam) > 0 {
+		var convErr error
+		count, convErr = strconv.Atoi(countParam)
+		if convErr != nil {
+			lResponse.Error = "count is invalid"
+
+			rw.WriteHeader(http.StatusBadRequest)
+			encodeResponse(rw, r, lResponse)
+			return
+		}
+	}
+
+	// Get leaderboard from model
+	entries, topErr := lh.leaderboardModel.TopPlayers(lResponse.Board, lResponse.Category, count)
+	if topErr != nil {
+		common.LoggerFromContext(r.Context(), lh.logger).Warn("Error getting leaderboard", "board", lResponse.Board,
+			common.CATEGORY_FIELD, lResponse.Category, common.ERROR_FIELD, topErr)
+
+		// Update LeaderboardResponse
+		lResponse.Error = topErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(leaderboardErrorStatus(topErr))
+
+		// Write JSON to stream
+		encodeResponse(rw, r, lResponse)
+		return
+	}
+
+	lResponse.Entries = entries
+
+	// Send OK status
+	rw.WriteHeader(http.StatusOK)
+
+	// Encode response
+	encodeResponse(rw, r, lResponse)
+}
+
+// GetPlayerRank is a http handler that receives a client "GET" request for a player's rank on a leaderboard.
+// The formats used are:
+//
+//	'http://<server-name>:8080/api/v1/api/leaderboards/{board}/players/{playerid}'
+//	'http://<server-name>:8080/api/v1/api/leaderboards/category/{category}/players/{playerid}'
+//
+// The request returns a PlayerRankResponse object.
+func (lh *LeaderboardHandler) GetPlayerRank(rw http.ResponseWriter, r *http.Request) {
+	var prResponse messages.PlayerRankResponse
+	prResponse.Board, prResponse.Category = leaderboardVars(r)
+	prResponse.PlayerID = mux.Vars(r)[PLAYER_ID_VAR]
+
+	// Get rank from model
+	entry, rankErr := lh.leaderboardModel.PlayerRank(prResponse.Board, prResponse.Category, prResponse.PlayerID)
+	if rankErr != nil {
+		common.LoggerFromContext(r.Context(), lh.logger).Warn("Error getting player rank", "board", prResponse.Board,
+			common.CATEGORY_FIELD, prResponse.Category, common.PLAYER_ID_FIELD, prResponse.PlayerID,
+			common.ERROR_FIELD, rankErr)
+
+		// Update PlayerRankResponse
+		prResponse.Error = rankErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(leaderboardErrorStatus(rankErr))
+
+		// Write JSON to stream
+		encodeResponse(rw, r, prResponse)
+		return
+	}
+
+	prResponse.LeaderboardEntry = entry
+
+	// Send OK status
+	rw.WriteHeader(http.StatusOK)
+
+	// Encode response
+	encodeResponse(rw, r, prResponse)
+}
+
+// NewLeaderboardHandler creates a leaderboard handler that reads scores from leaderboardModel
+func NewLeaderboardHandler(leaderboardModel *models.LeaderboardModel) *LeaderboardHandler {
+	logger := slog.Default()
+	leaderboardHandler := new(LeaderboardHandler)
+
+	// Set logger
+	leaderboardHandler.logger = logger
+
+	// Set leaderboard model
+	leaderboardHandler.leaderboardModel = leaderboardModel
+
+	return leaderboardHandler
+}
+
+// unexported functions
+// leaderboardVars returns the board and category from the route. Category routes do not carry a board variable.
+func leaderboardVars(r *http.Request) (string, string) {
+	vars := mux.Vars(r)
+
+	category, isCategory := vars[CATEGORY_VAR]
+	if isCategory {
+		return models.CATEGORY_BOARD, category
+	}
+
+	return vars[BOARD_VAR], ""
+}
+
+// leaderboardErrorStatus maps leaderboard errors to a HTTP status
+func leaderboardErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidLeaderboard):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrPlayerNotRanked):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// recordScore adds a correct answer to the leaderboards. Leaderboard errors do not fail the answer.
+func recordScore(ctx context.Context, leaderboardModel *models.LeaderboardModel, aRequest messages.AnswerRequest,
+	aResponse messages.AnswerResponse) {
+	if leaderboardModel == nil || !aResponse.Correct || len(aRequest.PlayerID) == 0 {
+		return
+	}
+
+	// Errors are logged by the model
+	_ = leaderboardModel.RecordCorrectAnswer(ctx, aRequest.PlayerID, aResponse.Category, aResponse.Points)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LOCALE_PARAM is the query parameter a client can use to choose the locale of answer messages.
+	// It takes precedence over the Accept-Language header.
+	LOCALE_PARAM string = "locale"
+
+	ACCEPT_LANGUAGE_HEADER string = "Accept-Language"
+)
+
+// unexported functions
+// requestLocales returns the locales preferred by the client, most preferred first
+func requestLocales(r *http.Request) []string {
+	var locales []string
+
+	locale := strings.TrimSpace(r.URL.Query().Get(LOCALE_PARAM))
+	if len(locale) > 0 {
+		locales = append(locales, locale)
+	}
+
+	return append(locales, parseAcceptLanguage(r.Header.Get(ACCEPT_LANGUAGE_HEADER))...)
+}
+
+// parseAcceptLanguage returns the languages in an Accept-Language header ordered by quality.
+// Languages with a quality of 0 and the '*' wildcard are left out.
+func parseAcceptLanguage(acceptLanguage string) []string {
+	type weightedLanguage struct {
+		language string
+		quality  float64
+	}
+
+	var weightedLanguages []weightedLanguage
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		language, params, _ := strings.Cut(entry, ";")
+		language = strings.TrimSpace(language)
+		if len(language) == 0 || language == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.TrimSpace(key) != "q" {
+				continue
+			}
+
+			parsedQuality, parseErr := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if parseErr == nil {
+				quality = parsedQuality
+			}
+		}
+
+		if quality > 0 {
+			weightedLanguages = append(weightedLanguages, weightedLanguage{language: language, quality: quality})
+		}
+	}
+
+	sort.SliceStable(weightedLanguages, func(idx1, idx2 int) bool {
+		return weightedLanguages[idx1].quality > weightedLanguages[idx2].quality
+	})
+
+	languages := make([]string, 0, len(weightedLanguages))
+	for _, weighted := range weightedLanguages {
+		languages = append(languages, weighted.language)
+	}
+
+	return languages
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/sflewis2970/trivia-api/common"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// UNKNOWN_ROUTE is the route recorded for requests that did not match a route
+const UNKNOWN_ROUTE string = "unknown"
+
+// Request metrics, labelled by route template, method and status
+var (
+	requestsTotal = common.Metrics.NewCounterVec("trivia_http_requests_total",
+		"HTTP requests served, by route, method and status.", "route", "method", "status")
+	requestDuration = common.Metrics.NewHistogramVec("trivia_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by route, method and status.", common.LATENCY_BUCKETS, "route",
+		"method", "status")
+)
+
+// MetricsHandler serves the metrics kept in a registry
+type MetricsHandler struct {
+	registry *common.MetricsRegistry
+}
+
+// GetMetrics is a http handler that returns every metric in the Prometheus text exposition format
+func (mh *MetricsHandler) GetMetrics(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", common.METRICS_CONTENT_TYPE)
+	rw.WriteHeader(http.StatusOK)
+
+	writeErr := mh.registry.WriteText(rw)
+	if writeErr != nil {
+		common.LoggerFromContext(r.Context(), nil).Warn("Error writing metrics", common.ERROR_FIELD, writeErr)
+	}
+}
+
+// RecordRequestMetrics is a middleware counting requests and timing them by the route they matched.
+// Routes are recorded by their template, so requests for different IDs are counted together.
+func RecordRequestMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+
+		recorder := newStatusRecorder(rw)
+		next.ServeHTTP(recorder, r)
+
+		route := requestRoute(r)
+		status := strconv.Itoa(recorder.Status())
+		requestsTotal.Inc(route, r.Method, status)
+		requestDuration.ObserveDuration(started, route, r.Method, status)
+	})
+}
+
+// NewMetricsHandler creates a metrics handler serving the metrics kept in registry
+func NewMetricsHandler(registry *common.MetricsRegistry) *MetricsHandler {
+	metricsHandler := new(MetricsHandler)
+	metricsHandler.registry = registry
+
+	return metricsHandler
+}
+
+// unexported functions
+// requestRoute returns the template of the route the request matched
+func requestRoute(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return UNKNOWN_ROUTE
+	}
+
+	template, templateErr := route.GetPathTemplate()
+	if templateErr != nil || len(template) == 0 {
+		return UNKNOWN_ROUTE
+	}
+
+	return template
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// Headers used to send an API key. Either header may be used.
+const (
+	AUTHORIZATION_HEADER string = "Authorization"
+	API_KEY_HEADER       string = "X-API-Key"
+	BEARER_PREFIX        string = "Bearer "
+)
+
+// playerContextKey is the request context key holding the authenticated player
+type playerContextKey struct{}
+
+type PlayerHandler struct {
+	playerModel *models.PlayerModel
+	seenModel   *models.SeenModel
+	logger      *slog.Logger
+}
+
+// RegisterPlayer is a http handler that receives a client "POST" request to register a player.
+// The request uses the form of: 'http://<server-name>:8080/api/v1/api/players' including a
+// json object:
+//
+//	"name": "<name shown for the player>"
+//
+// The request returns a PlayerResponse object including the API key issued to the player.
+// The API key is only returned once and must be sent with every game and question request,
+// either as 'Authorization: Bearer <apikey>' or 'X-API-Key: <apikey>'.
+func (ph *PlayerHandler) RegisterPlayer(rw http.ResponseWriter, r *http.Request) {
+	var pRequest messages.PlayerRequest
+	var pResponse messages.PlayerResponse
+	logger := common.LoggerFromContext(r.Context(), ph.logger)
+
+	// Read JSON from stream
+	decodeErr := json.NewDecoder(r.Body).Decode(&pRequest)
+	if decodeErr != nil {
+		logger.Warn("Error decoding json", common.ERROR_FIELD, decodeErr)
+
+		// Update PlayerResponse
+		pResponse.Error = decodeErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(http.StatusBadRequest)
+
+		// Write JSON to stream
+		encodeResponse(rw, r, pResponse)
+		return
+	}
+
+	// Send request to model to register the player
+	player, apiKey, registerErr := ph.playerModel.RegisterPlayer(r.Context(), pRequest)
+	if registerErr != nil {
+		logger.Warn("Error registering player", common.ERROR_FIELD, registerErr)
+
+		// Update PlayerResponse
+		pResponse.Error = registerErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(playerErrorStatus(registerErr))
+
+		// Write JSON to stream
+		encodeResponse(rw, r, pResponse)
+		return
+	}
+
+	// Update PlayerResponse struct
+	pResponse.PlayerID = player.PlayerID
+	pResponse.Name = player.Name
+	pResponse.APIKey = apiKey
+	pResponse.Created = player.Created
+
+	// Update HTTP Header
+	rw.WriteHeader(http.StatusCreated)
+
+	// Write JSON to stream
+	encodeResponse(rw, r, pResponse)
+}
+
+// GetPlayer is a http handler that receives a client "GET" request for the authenticated player.
+// The format used is: 'http://<server-name>:8080/api/v1/api/players/me'.
+// The request returns a PlayerResponse object.
+func (ph *PlayerHandler) GetPlayer(rw http.ResponseWriter, r *http.Request) {
+	player, _ := PlayerFromContext(r.Context())
+
+	var pResponse messages.PlayerResponse
+	pResponse.PlayerID = player.PlayerID
+	pResponse.Name = player.Name
+	pResponse.Created = player.Created
+
+	// Send OK status
+	rw.WriteHeader(http.StatusOK)
+
+	// Encode response
+	encodeResponse(rw, r, pResponse)
+}
+
+// ResetSeenQuestions is a http handler that receives a client "DELETE" request to forget the questions the
+// authenticated player has seen, so they can be asked again.
+// The format used is: 'http://<server-name>:8080/api/v1/api/players/me/seenquestions'.
+// The request returns a SeenQuestionsResponse object with the number of questions forgotten.
+func (ph *PlayerHandler) ResetSeenQuestions(rw http.ResponseWriter, r *http.Request) {
+	player, _ := PlayerFromContext(r.Context())
+
+	var sqResponse messages.SeenQuestionsResponse
+	sqResponse.PlayerID = player.PlayerID
+
+	forgotten, resetErr := ph.seenModel.Reset(r.Context(), player.PlayerID)
+	if resetErr != nil {
+		common.LoggerFromContext(r.Context(), ph.logger).Warn("Error resetting seen questions", common.ERROR_FIELD,
+			resetErr)
+
+		// Update SeenQuestionsResponse
+		sqResponse.Error = resetErr.Error()
+
+		// Update HTTP Header
+		rw.WriteHeader(http.StatusInternalServerError)
+
+		// Write JSON to stream
+		encodeResponse(rw, r, sqResponse)
+		return
+	}
+
+	sqResponse.Forgotten = forgotten
+
+	// Send OK status
+	rw.WriteHeader(http.StatusOK)
+
+	// Encode response
+	encodeResponse(rw, r, sqResponse)
+}
+
+// Authenticate is a mux middleware that rejects requests without a valid API key.
+// The authenticated player is attached to the request context, see PlayerFromContext.
+func (ph *PlayerHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		player, authErr := ph.playerModel.AuthenticatePlayer(r.Context(), requestAPIKey(r))
+		if authErr != nil {
+			common.LoggerFromContext(r.Context(), ph.logger).Warn("Error authenticating player", common.ERROR_FIELD,
+				authErr)
+
+			var pResponse messages.PlayerResponse
+			pResponse.Error = authErr.Error()
+
+			// Update HTTP Header
+			if errors.Is(authErr, models.ErrInvalidAPIKey) {
+				rw.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			rw.WriteHeader(playerErrorStatus(authErr))
+
+			// Write JSON to stream
+			encodeResponse(rw, r, pResponse)
+			return
+		}
+
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), playerContextKey{}, player)))
+	})
+}
+
+// PlayerFromContext returns the player attached to ctx by Authenticate
+func PlayerFromContext(ctx context.Context) (messages.Player, bool) {
+	player, found := ctx.Value(playerContextKey{}).(messages.Player)
+	return player, found
+}
+
+// NewPlayerHandler creates a player handler that registers and authenticates players with playerModel
+// and resets the questions they have seen with seenModel
+func NewPlayerHandler(playerModel *models.PlayerModel, seenModel *models.SeenModel) *PlayerHandler {
+	logger := slog.Default()
+	playerHandler := new(PlayerHandler)
+
+	// Set logger
+	playerHandler.logger = logger
+
+	// Set player model
+	playerHandler.playerModel = playerModel
+
+	// Set seen question model
+	playerHandler.seenModel = seenModel
+
+	return playerHandler
+}
+
+// unexported functions
+// requestAPIKey returns the API key sent as a bearer token or in the API key header
+func requestAPIKey(r *http.Request) string {
+	authorization := r.Header.Get(AUTHORIZATION_HEADER)
+	if len(authorization) > len(BEARER_PREFIX) && strings.EqualFold(authorization[:len(BEARER_PREFIX)], BEARER_PREFIX) {
+		return strings.TrimSpace(authorization[len(BEARER_PREFIX):])
+	}
+
+	return strings.TrimSpace(r.Header.Get(API_KEY_HEADER))
+}
+
+// playerErrorStatus maps player errors to a HTTP status
+func playerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidPlayerRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrInvalidAPIKey):
+		return http.StatusUnauthorized
+	case errors.Is(err, models.ErrPlayerExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
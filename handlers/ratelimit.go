@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit headers
+const (
+	RATE_LIMIT_LIMIT_HEADER     string = "RateLimit-Limit"
+	RATE_LIMIT_REMAINING_HEADER string = "RateLimit-Remaining"
+	RATE_LIMIT_RESET_HEADER     string = "RateLimit-Reset"
+	RETRY_AFTER_HEADER          string = "Retry-After"
+	FORWARDED_FOR_HEADER        string = "X-Forwarded-For"
+)
+
+// Prefixes keeping the buckets of players and client IPs apart
+const (
+	PLAYER_RATE_LIMIT_PREFIX    string = "player:"
+	CLIENT_IP_RATE_LIMIT_PREFIX string = "ip:"
+)
+
+// rateLimitedTotal counts requests refused by the rate limiter
+var rateLimitedTotal = common.Metrics.NewCounterVec("trivia_rate_limited_requests_total",
+	"Requests refused because the client exceeded the rate limit.", "route")
+
+// RateLimitHandler limits the requests of each client with a token bucket. Clients are identified by the
+// player attached to the request context by the Authenticate middleware, or by their IP address on routes
+// that do not require an API key. Requests forwarded by a trusted proxy are identified by the client IP in
+// the X-Forwarded-For header. Every response carries the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers; requests over the limit are answered with 429 and a Retry-After header.
+//
+// Requests are let through when the rate limiter fails, so a Redis outage does not take the service down.
+type RateLimitHandler struct {
+	rateLimiter    models.RateLimiter
+	trustedProxies []netip.Prefix
+	logger         *slog.Logger
+}
+
+// Limit is the rate limit middleware
+func (rlh *RateLimitHandler) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		result, takeErr := rlh.rateLimiter.Take(r.Context(), rlh.rateLimitKey(r))
+		if takeErr != nil {
+			common.LoggerFromContext(r.Context(), rlh.logger).Warn("Error checking rate limit, request allowed",
+				common.ERROR_FIELD, takeErr)
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		rw.Header().Set(RATE_LIMIT_LIMIT_HEADER, strconv.Itoa(result.Limit))
+		rw.Header().Set(RATE_LIMIT_REMAINING_HEADER, strconv.Itoa(result.Remaining))
+		rw.Header().Set(RATE_LIMIT_RESET_HEADER, strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			rateLimitedTotal.Inc(requestRoute(r))
+			common.LoggerFromContext(r.Context(), rlh.logger).Info("Rate limit exceeded", "retryafter", retryAfter)
+
+			var qResponse messages.QuestionResponse
+			qResponse.Error = fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter)
+
+			// Update HTTP Header
+			rw.Header().Set(RETRY_AFTER_HEADER, strconv.Itoa(retryAfter))
+			rw.WriteHeader(http.StatusTooManyRequests)
+
+			// Write JSON to stream
+			encodeResponse(rw, r, qResponse)
+			return
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// unexported type methods
+// rateLimitKey returns the key of the client's bucket: the ID of the authenticated player, or the client IP
+// address
+func (rlh *RateLimitHandler) rateLimitKey(r *http.Request) string {
+	if player, found := PlayerFromContext(r.Context()); found {
+		return PLAYER_RATE_LIMIT_PREFIX + player.PlayerID
+	}
+
+	return CLIENT_IP_RATE_LIMIT_PREFIX + rlh.clientIP(r)
+}
+
+// clientIP returns the IP address the request came from. When it came through trusted proxies, the client
+// IP is the last address in X-Forwarded-For that is not a trusted proxy.
+func (rlh *RateLimitHandler) clientIP(r *http.Request) string {
+	remoteIP, _, splitErr := net.SplitHostPort(r.RemoteAddr)
+	if splitErr != nil {
+		remoteIP = r.RemoteAddr
+	}
+
+	if !rlh.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	forwardedIPs := strings.Split(strings.Join(r.Header.Values(FORWARDED_FOR_HEADER), ","), ",")
+	for idx := len(forwardedIPs) - 1; idx >= 0; idx-- {
+		forwardedIP := strings.TrimSpace(forwardedIPs[idx])
+		if len(forwardedIP) == 0 {
+			continue
+		}
+
+		// An address the proxies could not have added ends the chain
+		if _, addrErr := netip.ParseAddr(forwardedIP); addrErr != nil {
+			break
+		}
+
+		remoteIP = forwardedIP
+		if !rlh.isTrustedProxy(forwardedIP) {
+			break
+		}
+	}
+
+	return remoteIP
+}
+
+// isTrustedProxy reports whether ip belongs to a trusted proxy
+func (rlh *RateLimitHandler) isTrustedProxy(ip string) bool {
+	addr, addrErr := netip.ParseAddr(ip)
+	if addrErr != nil {
+		return false
+	}
+
+	for _, trustedProxy := range rlh.trustedProxies {
+		if trustedProxy.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewRateLimitHandler creates the rate limit middleware taking tokens from rateLimiter. trustedProxies are
+// the IP addresses and CIDR ranges of the proxies whose X-Forwarded-For header is believed.
+func NewRateLimitHandler(rateLimiter models.RateLimiter, trustedProxies []string,
+	logger *slog.Logger) (*RateLimitHandler, error) {
+	rateLimitHandler := new(RateLimitHandler)
+	rateLimitHandler.rateLimiter = rateLimiter
+	rateLimitHandler.logger = logger
+
+	for _, trustedProxy := range trustedProxies {
+		prefix, parseErr := parseTrustedProxy(trustedProxy)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		rateLimitHandler.trustedProxies = append(rateLimitHandler.trustedProxies, prefix)
+	}
+
+	return rateLimitHandler, nil
+}
+
+// unexported functions
+// parseTrustedProxy parses an IP address or CIDR range as a CIDR range
+func parseTrustedProxy(trustedProxy string) (netip.Prefix, error) {
+	if addr, addrErr := netip.ParseAddr(trustedProxy); addrErr == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, prefixErr := netip.ParsePrefix(trustedProxy)
+	if prefixErr != nil {
+		return netip.Prefix{}, fmt.Errorf("trusted proxy %q is not an IP address or CIDR range", trustedProxy)
+	}
+
+	return prefix.Masked(), nil
+}
+
+// ceilSeconds returns the duration in whole seconds, rounded up
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
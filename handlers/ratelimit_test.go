@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRateLimitHandler creates a rate limit handler allowing bursts of burst requests, refilled once a
+// minute, and a player registered over a memory store. The public handler is limited for each client IP, the
+// authenticated handler authenticates the player before limiting, as the routes are set up by the controller.
+func newTestRateLimitHandler(t *testing.T, burst int) (http.Handler, http.Handler, string) {
+	memoryModel := models.NewMemoryModel()
+	t.Cleanup(func() { memoryModel.Close() })
+
+	playerModel, playerErr := models.NewPlayerModel(memoryModel)
+	if playerErr != nil {
+		t.Fatalf("NewPlayerModel() error = %v", playerErr)
+	}
+	_, apiKey, registerErr := playerModel.RegisterPlayer(context.Background(), messages.PlayerRequest{Name: "alice"})
+	if registerErr != nil {
+		t.Fatalf("RegisterPlayer() error = %v", registerErr)
+	}
+
+	rateLimiter := models.NewMemoryRateLimiter(models.NewTokenBucket(1, time.Minute, burst))
+	logger := common.NewLogger(io.Discard, "error", "text")
+	rateLimitHandler, rateLimitErr := NewRateLimitHandler(rateLimiter, nil, logger)
+	if rateLimitErr != nil {
+		t.Fatalf("NewRateLimitHandler() error = %v", rateLimitErr)
+	}
+	playerHandler := NewPlayerHandler(playerModel, nil)
+
+	okHandler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	return rateLimitHandler.Limit(okHandler), playerHandler.Authenticate(rateLimitHandler.Limit(okHandler)), apiKey
+}
+
+// limitedRequest sends a request from clientIP with apiKey through handler
+func limitedRequest(handler http.Handler, clientIP string, apiKey string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("GET", "/getquestion", nil)
+	request.RemoteAddr = clientIP + ":41000"
+	if len(apiKey) > 0 {
+		request.Header.Set(API_KEY_HEADER, apiKey)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestRateLimitExceeded(t *testing.T) {
+	handler, _, _ := newTestRateLimitHandler(t, 2)
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		recorder := limitedRequest(handler, "192.0.2.1", "")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+		}
+		if got := recorder.Header().Get(RATE_LIMIT_REMAINING_HEADER); got != strconv.Itoa(remaining) {
+			t.Errorf("%s = %q, want %d", RATE_LIMIT_REMAINING_HEADER, got, remaining)
+		}
+	}
+
+	recorder := limitedRequest(handler, "192.0.2.1", "")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	wantHeaders := map[string]string{
+		RATE_LIMIT_LIMIT_HEADER:     "2",
+		RATE_LIMIT_REMAINING_HEADER: "0",
+		RATE_LIMIT_RESET_HEADER:     "120",
+		RETRY_AFTER_HEADER:          "60",
+	}
+	for header, want := range wantHeaders {
+		if got := recorder.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	var qResponse messages.QuestionResponse
+	if decodeErr := json.NewDecoder(recorder.Body).Decode(&qResponse); decodeErr != nil {
+		t.Fatalf("decode error = %v", decodeErr)
+	}
+	if !strings.Contains(qResponse.Error, "retry in 60 seconds") {
+		t.Errorf("error = %q, want the retry time", qResponse.Error)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	publicHandler, authHandler, apiKey := newTestRateLimitHandler(t, 1)
+
+	// Clients without an API key are limited on their IP
+	if recorder := limitedRequest(publicHandler, "192.0.2.1", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if recorder := limitedRequest(publicHandler, "192.0.2.1", ""); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("no API key status = %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	// Registered players have a bucket of their own, wherever they connect from
+	if recorder := limitedRequest(authHandler, "192.0.2.1", apiKey); recorder.Code != http.StatusOK {
+		t.Errorf("registered player status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if recorder := limitedRequest(authHandler, "198.51.100.7", apiKey); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("registered player from another IP status = %d, want %d", recorder.Code,
+			http.StatusTooManyRequests)
+	}
+
+	// Made up API keys are refused before they reach the rate limiter
+	if recorder := limitedRequest(authHandler, "198.51.100.7", "tk_madeup1"); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("made up API key status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	// Other client IPs are limited separately
+	if recorder := limitedRequest(publicHandler, "198.51.100.7", ""); recorder.Code != http.StatusOK {
+		t.Errorf("other client IP status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteIP       string
+		forwardedFor   []string
+		playerID       string
+		wantKey        string
+	}{
+		{"no trusted proxies", nil, "10.0.0.5", []string{"198.51.100.7"}, "", "ip:10.0.0.5"},
+		{"untrusted remote address", []string{"10.0.0.0/8"}, "192.0.2.1", []string{"198.51.100.7"}, "",
+			"ip:192.0.2.1"},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "10.0.0.5", []string{"198.51.100.7"}, "", "ip:198.51.100.7"},
+		{"trusted proxy address", []string{"10.0.0.5"}, "10.0.0.5", []string{"198.51.100.7"}, "",
+			"ip:198.51.100.7"},
+		{"chain of trusted proxies", []string{"10.0.0.0/8"}, "10.0.0.5", []string{"198.51.100.7, 10.0.0.9"}, "",
+			"ip:198.51.100.7"},
+		{"address added by the client", []string{"10.0.0.0/8"}, "10.0.0.5", []string{"203.0.113.9, 198.51.100.7"},
+			"", "ip:198.51.100.7"},
+		{"several headers", []string{"10.0.0.0/8"}, "10.0.0.5", []string{"203.0.113.9", "198.51.100.7"}, "",
+			"ip:198.51.100.7"},
+		{"invalid address", []string{"10.0.0.0/8"}, "10.0.0.5", []string{"unknown, 10.0.0.9"}, "", "ip:10.0.0.9"},
+		{"no header", []string{"10.0.0.0/8"}, "10.0.0.5", nil, "", "ip:10.0.0.5"},
+		{"ipv6 untrusted remote address", []string{"2001:db8::/32"}, "2001:db9::1", []string{"2001:db8::7"}, "",
+			"ip:2001:db9::1"},
+		{"ipv6 client", []string{"2001:db8::/32"}, "2001:db8::1", []string{"2001:db9::7"}, "", "ip:2001:db9::7"},
+		{"authenticated player", []string{"10.0.0.0/8"}, "10.0.0.5", []string{"198.51.100.7"}, "player-1",
+			"player:player-1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rateLimitHandler, rateLimitErr := NewRateLimitHandler(nil, test.trustedProxies, nil)
+			if rateLimitErr != nil {
+				t.Fatalf("NewRateLimitHandler() error = %v", rateLimitErr)
+			}
+
+			request := httptest.NewRequest("GET", "/getquestion", nil)
+			request.RemoteAddr = net.JoinHostPort(test.remoteIP, "41000")
+			for _, forwardedFor := range test.forwardedFor {
+				request.Header.Add(FORWARDED_FOR_HEADER, forwardedFor)
+			}
+			if len(test.playerID) > 0 {
+				player := messages.Player{PlayerID: test.playerID}
+				request = request.WithContext(context.WithValue(request.Context(), playerContextKey{}, player))
+			}
+
+			if key := rateLimitHandler.rateLimitKey(request); key != test.wantKey {
+				t.Errorf("rateLimitKey() = %q, want %q", key, test.wantKey)
+			}
+		})
+	}
+
+	if _, rateLimitErr := NewRateLimitHandler(nil, []string{"proxy.local"}, nil); rateLimitErr == nil {
+		t.Error("NewRateLimitHandler() with an invalid trusted proxy error = nil, want an error")
+	}
+}
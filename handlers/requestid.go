@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// REQUEST_ID_HEADER carries the ID of a request, from the client or set by the service
+	REQUEST_ID_HEADER string = "X-Request-ID"
+
+	// MAX_REQUEST_ID_LENGTH is the longest request ID accepted from a client
+	MAX_REQUEST_ID_LENGTH int = 128
+)
+
+// RequestLogger tags every request with a request ID and logs each request once it has been served.
+// The ID is taken from the X-Request-ID header when the client sends a valid one, otherwise one is
+// generated. The ID is returned in the X-Request-ID response header, and everything logged for the
+// request is tagged with it.
+type RequestLogger struct {
+	logger *slog.Logger
+}
+
+// Handle is the request ID middleware
+func (rl *RequestLogger) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+
+		requestID := r.Header.Get(REQUEST_ID_HEADER)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		rw.Header().Set(REQUEST_ID_HEADER, requestID)
+
+		logger := rl.logger.With(common.REQUEST_ID_FIELD, requestID)
+		recorder := newStatusRecorder(rw)
+		next.ServeHTTP(recorder, r.WithContext(common.ContextWithLogger(r.Context(), logger)))
+
+		logger.Info("Request served", "method", r.Method, "path", r.URL.Path, "status", recorder.Status(),
+			"duration", time.Since(started))
+	})
+}
+
+// statusRecorder keeps the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it to the response
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body of the response, which has status 200 unless a status was written first
+func (sr *statusRecorder) Write(body []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(body)
+}
+
+// Status returns the status code of the response
+func (sr *statusRecorder) Status() int {
+	if sr.status == 0 {
+		return http.StatusOK
+	}
+	return sr.status
+}
+
+// NewRequestLogger creates the request ID middleware, logging with logger
+func NewRequestLogger(logger *slog.Logger) *RequestLogger {
+	requestLogger := new(RequestLogger)
+	requestLogger.logger = logger
+
+	return requestLogger
+}
+
+// unexported functions
+func newStatusRecorder(rw http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: rw}
+}
+
+// validRequestID reports whether a request ID from a client can be used. IDs are limited in length and to
+// printable ASCII without spaces, so they cannot break log lines.
+func validRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+
+	for idx := 0; idx < len(requestID); idx++ {
+		if requestID[idx] <= ' ' || requestID[idx] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
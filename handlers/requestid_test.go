@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		wantEcho  bool
+	}{
+		{"client ID", "client-request-1", true},
+		{"no ID", "", false},
+		{"ID with spaces", "client request 1", false},
+		{"ID too long", strings.Repeat("a", MAX_REQUEST_ID_LENGTH+1), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var output bytes.Buffer
+			requestLogger := NewRequestLogger(common.NewLogger(&output, common.INFO_LEVEL, common.JSON_FORMAT))
+
+			// The handler logs with the logger of the request
+			handler := requestLogger.Handle(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				common.LoggerFromContext(r.Context(), nil).Info("Handling request")
+				rw.WriteHeader(http.StatusTeapot)
+			}))
+
+			request := httptest.NewRequest("GET", "/getquestion", nil)
+			if len(test.requestID) > 0 {
+				request.Header.Set(REQUEST_ID_HEADER, test.requestID)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			requestID := recorder.Header().Get(REQUEST_ID_HEADER)
+			if test.wantEcho && requestID != test.requestID {
+				t.Errorf("%s = %q, want %q", REQUEST_ID_HEADER, requestID, test.requestID)
+			}
+			if !test.wantEcho {
+				if _, parseErr := uuid.Parse(requestID); parseErr != nil {
+					t.Errorf("%s = %q, want a generated UUID", REQUEST_ID_HEADER, requestID)
+				}
+			}
+
+			// Both the handler line and the request line are tagged with the request ID
+			var lines []map[string]any
+			decoder := json.NewDecoder(&output)
+			for decoder.More() {
+				var line map[string]any
+				if decodeErr := decoder.Decode(&line); decodeErr != nil {
+					t.Fatalf("log line error = %v", decodeErr)
+				}
+				lines = append(lines, line)
+			}
+			if len(lines) != 2 {
+				t.Fatalf("log lines = %v, want the handler line and the request line", lines)
+			}
+			for _, line := range lines {
+				if line[common.REQUEST_ID_FIELD] != requestID {
+					t.Errorf("log line %v, want %s %q", line, common.REQUEST_ID_FIELD, requestID)
+				}
+			}
+			if lines[0]["msg"] != "Handling request" || lines[1]["status"] != float64(http.StatusTeapot) {
+				t.Errorf("log lines = %v, want the handler line then the request line with status %d", lines,
+					http.StatusTeapot)
+			}
+		})
+	}
+}
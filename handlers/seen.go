@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/external"
+	"github.com/sflewis2970/trivia-api/messages"
+	"github.com/sflewis2970/trivia-api/models"
+)
+
+// seenRetries counts questions replaced because the player had seen them, by requested category
+var seenRetries = common.Metrics.NewCounterVec("trivia_seen_question_retries_total",
+	"Questions asked for again because the player had seen them.", "category")
+
+// unexported functions
+// getUnseenTrivia gets a question for the category and difficulty that the player has not seen. When the
+// provider returns a question the player has seen it is asked again, up to the retries set in seenModel;
+// the last question is used when every question was seen. The question is recorded as seen by the player.
+// Errors checking seen questions are logged and the question is used.
+func getUnseenTrivia(ctx context.Context, provider external.TriviaProvider, seenModel *models.SeenModel,
+	playerID string, category string, difficulty string) (messages.Trivia, error) {
+	var trivia messages.Trivia
+	for attempt := 0; attempt <= seenModel.Retries(); attempt++ {
+		var triviaErr error
+		trivia, triviaErr = external.GetTriviaByDifficulty(ctx, provider, category, difficulty)
+		if triviaErr != nil {
+			return messages.Trivia{}, triviaErr
+		}
+
+		seen, seenErr := seenModel.Seen(playerID, trivia.Question)
+		if seenErr != nil || !seen {
+			break
+		}
+
+		if attempt < seenModel.Retries() {
+			seenRetries.Inc(category)
+			common.LoggerFromContext(ctx, nil).Debug("Player has seen question, getting another question",
+				common.QUESTION_ID_FIELD, trivia.QuestionID, common.CATEGORY_FIELD, trivia.Category)
+		}
+	}
+
+	_ = seenModel.MarkSeen(ctx, playerID, trivia.Question)
+
+	return trivia, nil
+}
@@ -2,16 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
-	"github.com/sflewis2970/trivia-api/external/OpenTriviaAPI"
+	"errors"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/external"
 	"github.com/sflewis2970/trivia-api/messages"
 	"github.com/sflewis2970/trivia-api/models"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
 type TriviaHandler struct {
-	openTrivia  *OpenTriviaAPI.OpenTrivia
-	triviaModel *models.TriviaModel
+	triviaProvider   external.TriviaProvider
+	triviaModel      *models.TriviaModel
+	leaderboardModel *models.LeaderboardModel
+	seenModel        *models.SeenModel
+	logger           *slog.Logger
 }
 
 var triviaHandler *TriviaHandler
@@ -27,41 +32,49 @@ var triviaHandler *TriviaHandler
 //       {"questionid": "<random_id>",
 //        "question": "<question from api API>",
 //        "category": "<category is not required and could be blank>",
+//        "type": "<multiple, boolean or text>",
 //        "choices": "<choices are generated from API. One answer is correct, the others are incorrect>",
 //        "timestamp": "<formatted string of when the API returned the question>",
 //        "warning": "<optional warning message>",
 //        "error": "<optional error message>"}
 func (th *TriviaHandler) GetQuestion(rw http.ResponseWriter, r *http.Request) {
-	// Display a log message
-	log.Print("data received from client...")
-
 	// Get category from query parameter
 	category := r.URL.Query().Get("category")
 
+	// Everything logged for the request is tagged with the category
+	logger := common.LoggerFromContext(r.Context(), th.logger).With(common.CATEGORY_FIELD, category)
+	logger.Debug("Question requested")
+
 	var qResponse messages.QuestionResponse
 
-	// Process API Get Request
-	triviaData, triviaErr := th.openTrivia.GetTrivia(category)
+	// Process API Get Request, giving up when the client goes away
+	player, _ := PlayerFromContext(r.Context())
+	triviaData, triviaErr := getUnseenTrivia(r.Context(), th.triviaProvider, th.seenModel, player.PlayerID,
+		category, "")
 	if triviaErr != nil {
-		log.Print("Error encoding json...:", triviaErr)
+		logger.Error("Error getting trivia from provider", common.ERROR_FIELD, triviaErr)
 
 		// Update QuestionResponse struct
 		qResponse.Error = triviaErr.Error()
 
 		// Update HTTP header
-		rw.WriteHeader(http.StatusInternalServerError)
+		rw.WriteHeader(questionErrorStatus(triviaErr))
 
 		// Write JSON to stream
-		encodeResponse(rw, qResponse)
+		encodeResponse(rw, r, qResponse)
 		return
 	}
 
+	// Questions belong to the player who requested them
+	triviaData.PlayerID = player.PlayerID
+
 	// Send request to model to insert api question
-	insertErr := th.triviaModel.AddQuestion(triviaData)
+	insertErr := th.triviaModel.AddQuestion(r.Context(), triviaData)
 
 	// Add question to data store
 	if insertErr != nil {
-		log.Print("Error encoding json...:", insertErr)
+		logger.Error("Error adding question", common.QUESTION_ID_FIELD, triviaData.QuestionID,
+			common.ERROR_FIELD, insertErr)
 
 		// Update QuestionResponse struct
 		qResponse.QuestionID = ""
@@ -74,18 +87,27 @@ func (th *TriviaHandler) GetQuestion(rw http.ResponseWriter, r *http.Request) {
 		rw.WriteHeader(http.StatusInternalServerError)
 
 		// Write JSON to stream
-		encodeResponse(rw, qResponse)
+		encodeResponse(rw, r, qResponse)
 		return
 	}
 
+	// Update QuestionResponse struct
+	qResponse.QuestionID = triviaData.QuestionID
+	qResponse.Category = triviaData.Category
+	qResponse.Question = triviaData.Question
+	qResponse.Type = triviaData.Type
+	qResponse.Choices = triviaData.Choices
+	qResponse.Timestamp = triviaData.Timestamp
+	qResponse.Provider = triviaData.Provider
+
 	// Update HTTP Header
 	rw.WriteHeader(http.StatusCreated)
 
 	// Write JSON to stream
-	encodeResponse(rw, qResponse)
+	encodeResponse(rw, r, qResponse)
 
-	// Display a log message
-	log.Print("data sent back to client...")
+	logger.Info("Question issued", common.QUESTION_ID_FIELD, triviaData.QuestionID, "provider",
+		triviaData.Provider)
 }
 
 // SubmitTriviaAnswer is a http handler that receives a response message from the client.
@@ -93,7 +115,7 @@ func (th *TriviaHandler) GetQuestion(rw http.ResponseWriter, r *http.Request) {
 // The request uses the form of: 'http://<server-name>:8080//api/v1/api/questions' including a
 // json object:
 //        "questionid": "<id received in the question response>",
-//        "response": "<answer question from list of choices>"
+//        "response": "<one of the choices, True or False, or free text depending on the question type>"
 // The client will receive a response in the form of the following:
 //       "question": "<the question the client provided the answer for>",
 //       "timestamp": "<formatted string of when the API returned the question>",
@@ -107,10 +129,12 @@ func (th *TriviaHandler) AnswerQuestion(rw http.ResponseWriter, r *http.Request)
 	var aRequest messages.AnswerRequest
 	var aResponse messages.AnswerResponse
 
+	logger := common.LoggerFromContext(r.Context(), th.logger)
+
 	// Read JSON from stream
 	decodeErr := json.NewDecoder(r.Body).Decode(&aRequest)
 	if decodeErr != nil {
-		log.Print("Error decoding json...: ", decodeErr)
+		logger.Warn("Error decoding json", common.ERROR_FIELD, decodeErr)
 
 		// Update AnswerResponse
 		aResponse.Error = decodeErr.Error()
@@ -119,76 +143,141 @@ func (th *TriviaHandler) AnswerQuestion(rw http.ResponseWriter, r *http.Request)
 		rw.WriteHeader(http.StatusInternalServerError)
 
 		// Write JSON to stream
-		encodeResponse(rw, aResponse)
+		encodeResponse(rw, r, aResponse)
 		return
 	}
 
+	// Answers are attributed to the authenticated player
+	player, _ := PlayerFromContext(r.Context())
+	aRequest.PlayerID = player.PlayerID
+	aRequest.Locales = requestLocales(r)
+	logger = logger.With(common.QUESTION_ID_FIELD, aRequest.QuestionID)
+
 	// Send a request to the model for the answer
 	var getErr error
-	aResponse, getErr = th.triviaModel.GetAnswer(aRequest)
+	aResponse, getErr = th.triviaModel.GetAnswer(r.Context(), aRequest)
 
 	if getErr != nil {
-		log.Print("Error getting api answer...: ", getErr)
+		logger.Warn("Error getting answer", common.ERROR_FIELD, getErr)
 
 		// Update AnswerResponse
 		aResponse.Error = getErr.Error()
 
+		// Late answers cannot be retried, so the question is removed
+		if errors.Is(getErr, models.ErrAnswerTooLate) {
+			_ = th.triviaModel.DeleteQuestion(r.Context(), aRequest.QuestionID)
+		}
+
 		// Update HTTP Header
-		rw.WriteHeader(http.StatusInternalServerError)
+		rw.WriteHeader(answerErrorStatus(getErr))
 
 		// Write JSON to stream
-		encodeResponse(rw, aResponse)
+		encodeResponse(rw, r, aResponse)
 		return
 	}
 
-	// Send a request to the model to delete the question
-	deleteErr := th.triviaModel.DeleteQuestion(aRequest.QuestionID)
+	// Send a request to the model to delete the question. Only the request that deletes the question is
+	// scored; concurrent answers to the same question find it gone.
+	deleteErr := th.triviaModel.DeleteQuestion(r.Context(), aRequest.QuestionID)
 
-	if deleteErr != nil {
-		log.Print("Error deleting api question...: ", deleteErr)
+	if errors.Is(deleteErr, models.ErrItemNotFound) {
+		logger.Warn("Question was answered by another request")
+	} else if deleteErr != nil {
+		logger.Error("Error deleting question", common.ERROR_FIELD, deleteErr)
+	}
 
-		// Update AnswerResponse
-		aResponse.Error = deleteErr.Error()
+	if deleteErr != nil {
+		// The answer is not given away to requests that lost the question
+		aResponse = messages.AnswerResponse{QuestionID: aRequest.QuestionID, Error: deleteErr.Error()}
 
 		// Update HTTP Header
-		rw.WriteHeader(http.StatusInternalServerError)
+		rw.WriteHeader(answerErrorStatus(deleteErr))
 
 		// Write JSON to stream
-		encodeResponse(rw, aResponse)
+		encodeResponse(rw, r, aResponse)
 		return
 	}
 
+	// Update leaderboards for identified players
+	recordScore(r.Context(), th.leaderboardModel, aRequest, aResponse)
+
 	// Send OK status
 	rw.WriteHeader(http.StatusOK)
 
 	// Encode response
-	encodeResponse(rw, aResponse)
+	encodeResponse(rw, r, aResponse)
+
+	logger.Info("Question answered", common.CATEGORY_FIELD, aResponse.Category, "correct", aResponse.Correct)
+}
+
+// answerErrorStatus maps answer errors to a HTTP status
+func answerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrInvalidResponse):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrAnswerTooLate):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
 
-	// Display a log message
-	log.Print("data sent back to client...")
+// questionErrorStatus maps errors getting a question from the trivia provider to a HTTP status.
+// Upstream APIs that time out answer 504, upstream APIs that fail answer 502 and no available provider
+// answers 503.
+func questionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, external.ErrNoProviderAvailable):
+		return http.StatusServiceUnavailable
+	case common.IsUpstreamTimeout(err):
+		return http.StatusGatewayTimeout
+	case common.IsUpstreamError(err):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 type MessageSet interface {
-	messages.QuestionResponse | messages.AnswerResponse
+	messages.QuestionResponse | messages.AnswerResponse | messages.GameResponse | messages.GameQuestionResponse |
+		messages.GameAnswerResponse | messages.GameSummaryResponse | messages.LeaderboardResponse |
+		messages.PlayerRankResponse | messages.PlayerResponse | messages.SeenQuestionsResponse |
+		messages.HealthResponse
 }
 
-func encodeResponse[T MessageSet](rw http.ResponseWriter, response T) {
+// encodeResponse writes response as JSON, encoding errors are logged for request r
+func encodeResponse[T MessageSet](rw http.ResponseWriter, r *http.Request, response T) {
 	// Write JSON to stream
 	encodeErr := json.NewEncoder(rw).Encode(response)
 	if encodeErr != nil {
-		log.Print("Error encoding json...:", encodeErr)
+		common.LoggerFromContext(r.Context(), nil).Error("Error encoding json", common.ERROR_FIELD, encodeErr)
 		rw.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
-func NewTriviaHandler() *TriviaHandler {
+// NewTriviaHandler creates a trivia handler that gets questions from triviaProvider,
+// keeps them in triviaModel, adds correct answers to leaderboardModel and avoids
+// questions the player has seen with seenModel. Requests without a logger of their own log with logger.
+func NewTriviaHandler(triviaProvider external.TriviaProvider, triviaModel *models.TriviaModel,
+	leaderboardModel *models.LeaderboardModel, seenModel *models.SeenModel, logger *slog.Logger) *TriviaHandler {
 	triviaHandler := new(TriviaHandler)
 
-	// Create api api
-	triviaHandler.openTrivia = OpenTriviaAPI.NewOpenTrivia()
+	// Set logger
+	triviaHandler.logger = logger
+
+	// Set trivia provider
+	triviaHandler.triviaProvider = triviaProvider
+
+	// Set api model
+	triviaHandler.triviaModel = triviaModel
+
+	// Set leaderboard model
+	triviaHandler.leaderboardModel = leaderboardModel
 
-	// Create api model
-	triviaHandler.triviaModel = models.NewTriviaModel()
+	// Set seen question model
+	triviaHandler.seenModel = seenModel
 
 	return triviaHandler
 }
@@ -0,0 +1,88 @@
+package messages
+
+// Game is a multi-question round played by a client
+type Game struct {
+	GameID            string       `json:"gameid"`
+	PlayerID          string       `json:"playerid"`
+	Category          string       `json:"category"`
+	Difficulty        string       `json:"difficulty"`
+	TotalQuestions    int          `json:"totalquestions"`
+	CurrentQuestionID string       `json:"currentquestionid"`
+	Score             int          `json:"score"`
+	Points            int          `json:"points"`
+	Results           []GameResult `json:"results"`
+	Started           string       `json:"started"`
+	Finished          string       `json:"finished"`
+
+	// Version is incremented each time the game is saved, so a game read before another request saved it
+	// cannot overwrite that save
+	Version int `json:"version"`
+}
+
+// GameResult is the outcome of a single question within a game
+type GameResult struct {
+	QuestionID string `json:"questionid"`
+	Question   string `json:"question"`
+	Response   string `json:"response"`
+	Answer     string `json:"answer"`
+	Correct    bool   `json:"correct"`
+	Points     int    `json:"points,omitempty"`
+}
+
+// GameRequest Request-Response messaging
+type GameRequest struct {
+	Category       string `json:"category"`
+	TotalQuestions int    `json:"totalquestions"`
+	Difficulty     string `json:"difficulty"`
+
+	// PlayerID is set from the authenticated player, never from the request body
+	PlayerID string `json:"-"`
+}
+
+// GameResponse Request-Response messaging
+type GameResponse struct {
+	GameID         string `json:"gameid"`
+	Category       string `json:"category"`
+	Difficulty     string `json:"difficulty"`
+	TotalQuestions int    `json:"totalquestions"`
+	Answered       int    `json:"answered"`
+	Score          int    `json:"score"`
+	Warning        string `json:"warning,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// GameQuestionResponse Request-Response messaging
+type GameQuestionResponse struct {
+	GameID         string `json:"gameid"`
+	QuestionNumber int    `json:"questionnumber"`
+	TotalQuestions int    `json:"totalquestions"`
+	QuestionResponse
+}
+
+// GameAnswerResponse Request-Response messaging
+type GameAnswerResponse struct {
+	GameID      string `json:"gameid"`
+	Answered    int    `json:"answered"`
+	Score       int    `json:"score"`
+	TotalPoints int    `json:"totalpoints"`
+	Completed   bool   `json:"completed"`
+	AnswerResponse
+}
+
+// GameSummaryResponse Request-Response messaging
+type GameSummaryResponse struct {
+	GameID         string       `json:"gameid"`
+	Category       string       `json:"category"`
+	Difficulty     string       `json:"difficulty"`
+	TotalQuestions int          `json:"totalquestions"`
+	Answered       int          `json:"answered"`
+	Score          int          `json:"score"`
+	Points         int          `json:"points"`
+	Accuracy       float64      `json:"accuracy"`
+	Completed      bool         `json:"completed"`
+	Results        []GameResult `json:"results"`
+	Started        string       `json:"started"`
+	Finished       string       `json:"finished,omitempty"`
+	Warning        string       `json:"warning,omitempty"`
+	Error          string       `json:"error,omitempty"`
+}
@@ -0,0 +1,25 @@
+package messages
+
+// Health statuses of the service and its dependencies
+const (
+	STATUS_OK       string = "ok"
+	STATUS_FAILING  string = "failing"
+	STATUS_STARTING string = "starting"
+)
+
+// DependencyStatus is the result of checking a dependency of the service. Latency is in milliseconds.
+// Required dependencies must be reachable for the service to be ready.
+type DependencyStatus struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Required bool    `json:"required"`
+	Latency  float64 `json:"latencyms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// HealthResponse Request-Response messaging, returned by the liveness and readiness checks
+type HealthResponse struct {
+	Status       string             `json:"status"`
+	Timestamp    string             `json:"timestamp"`
+	Dependencies []DependencyStatus `json:"dependencies,omitempty"`
+}
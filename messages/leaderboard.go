@@ -0,0 +1,26 @@
+package messages
+
+// LeaderboardEntry is a ranked player on a leaderboard
+type LeaderboardEntry struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"playerid"`
+	Score    float64 `json:"score"`
+}
+
+// LeaderboardResponse Request-Response messaging
+type LeaderboardResponse struct {
+	Board    string             `json:"board"`
+	Category string             `json:"category,omitempty"`
+	Entries  []LeaderboardEntry `json:"entries"`
+	Warning  string             `json:"warning,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// PlayerRankResponse Request-Response messaging
+type PlayerRankResponse struct {
+	Board    string `json:"board"`
+	Category string `json:"category,omitempty"`
+	LeaderboardEntry
+	Warning string `json:"warning,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,30 @@
+package messages
+
+// Player is a registered client. API keys are issued to a player and are not part of the record.
+type Player struct {
+	PlayerID string `json:"playerid"`
+	Name     string `json:"name"`
+	Created  string `json:"created"`
+}
+
+// PlayerRequest Request-Response messaging
+type PlayerRequest struct {
+	Name string `json:"name"`
+}
+
+// PlayerResponse Request-Response messaging. The API key is only returned when the player registers.
+type PlayerResponse struct {
+	PlayerID string `json:"playerid"`
+	Name     string `json:"name"`
+	APIKey   string `json:"apikey,omitempty"`
+	Created  string `json:"created"`
+	Warning  string `json:"warning,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SeenQuestionsResponse Request-Response messaging, returned when the seen questions of a player are reset
+type SeenQuestionsResponse struct {
+	PlayerID  string `json:"playerid"`
+	Forgotten int    `json:"forgotten"`
+	Error     string `json:"error,omitempty"`
+}
@@ -1,5 +1,7 @@
 package messages
 
+import "time"
+
 const MAKE_SELECTION_MSG string = "Make Selection from list..."
 
 const (
@@ -7,8 +9,62 @@ const (
 	ONE_SET int    = 1
 )
 
-type Trivia struct{}
-type TriviaTable struct{}
+const TIMESTAMP_FORMAT string = "Mon Jan 2 15:04:05 2006"
+
+// Question types. Multiple choice questions are answered from a list of choices, boolean questions with
+// True or False and free text questions with any text.
+const (
+	MULTIPLE_CHOICE string = "multiple"
+	BOOLEAN         string = "boolean"
+	FREE_TEXT       string = "text"
+)
+
+// Answers to boolean questions
+const (
+	TRUE_ANSWER  string = "True"
+	FALSE_ANSWER string = "False"
+)
+
+const (
+	CONGRATS_MSG  string = "Congratulations! That is correct"
+	TRY_AGAIN_MSG string = "Nice try! Better luck on the next question..."
+	TOO_LATE_MSG  string = "Time is up! Answer faster on the next question..."
+)
+
+// Trivia is a question produced by a trivia provider
+type Trivia struct {
+	QuestionID  string    `json:"questionid"`
+	Question    string    `json:"question"`
+	Category    string    `json:"category"`
+	Difficulty  string    `json:"difficulty,omitempty"`
+	Type        string    `json:"type"`
+	Answer      string    `json:"answer"`
+	Distractors []string  `json:"distractors,omitempty"`
+	Alternates  []string  `json:"alternates,omitempty"`
+	Choices     []string  `json:"choices"`
+	Timestamp   string    `json:"timestamp"`
+	IssuedAt    time.Time `json:"issuedat"`
+	PlayerID    string    `json:"playerid,omitempty"`
+
+	// Provider is the name of the provider that served the question
+	Provider string `json:"provider,omitempty"`
+}
+
+// TriviaTable is the trivia record stored in the data store, keyed by question ID
+type TriviaTable struct {
+	QuestionID  string    `json:"questionid"`
+	Question    string    `json:"question"`
+	Category    string    `json:"category"`
+	Difficulty  string    `json:"difficulty,omitempty"`
+	Type        string    `json:"type"`
+	Answer      string    `json:"answer"`
+	Distractors []string  `json:"distractors,omitempty"`
+	Alternates  []string  `json:"alternates,omitempty"`
+	Choices     []string  `json:"choices"`
+	Timestamp   string    `json:"timestamp"`
+	IssuedAt    time.Time `json:"issuedat"`
+	PlayerID    string    `json:"playerid,omitempty"`
+}
 
 // QuestionResponse Request-Response messaging
 type QuestionRequest struct {
@@ -25,8 +81,10 @@ type QuestionResponse struct {
 	QuestionID string   `json:"questionid"`
 	Question   string   `json:"question"`
 	Category   string   `json:"category"`
+	Type       string   `json:"type"`
 	Choices    []string `json:"choices"`
 	Timestamp  string   `json:"timestamp"`
+	Provider   string   `json:"provider,omitempty"`
 	Warning    string   `json:"warning,omitempty"`
 	Error      string   `json:"error,omitempty"`
 }
@@ -34,16 +92,25 @@ type QuestionResponse struct {
 type AnswerRequest struct {
 	QuestionID string `json:"questionid"`
 	Response   string `json:"response"`
+
+	// PlayerID is set from the authenticated player, never from the request body
+	PlayerID string `json:"-"`
+
+	// Locales are the client's preferred locales for the answer message, most preferred first
+	Locales []string `json:"-"`
 }
 
 type AnswerResponse struct {
-	Question  string `json:"question"`
-	Timestamp string `json:"timestamp"`
-	Category  string `json:"category"`
-	Response  string `json:"response"`
-	Answer    string `json:"answer"`
-	Correct   bool   `json:"correct"`
-	Message   string `json:"message,omitempty"`
-	Warning   string `json:"warning,omitempty"`
-	Error     string `json:"error,omitempty"`
+	QuestionID string `json:"questionid"`
+	Question   string `json:"question"`
+	Timestamp  string `json:"timestamp"`
+	Category   string `json:"category"`
+	Response   string `json:"response"`
+	Answer     string `json:"answer"`
+	Correct    bool   `json:"correct"`
+	Match      string `json:"match,omitempty"`
+	Points     int    `json:"points,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Warning    string `json:"warning,omitempty"`
+	Error      string `json:"error,omitempty"`
 }
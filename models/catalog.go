@@ -0,0 +1,176 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/messages"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Answer outcomes with a message in the catalog
+const (
+	CORRECT_ANSWER   string = "congrats"
+	INCORRECT_ANSWER string = "tryagain"
+	LATE_ANSWER      string = "toolate"
+)
+
+// LocaleMessages holds the message variants for each answer outcome in a single locale
+type LocaleMessages struct {
+	Congrats []string `json:"congrats" yaml:"congrats"`
+	TryAgain []string `json:"tryagain" yaml:"tryagain"`
+	TooLate  []string `json:"toolate" yaml:"toolate"`
+}
+
+// variants returns the message variants for an answer outcome
+func (lm LocaleMessages) variants(outcome string) []string {
+	switch outcome {
+	case CORRECT_ANSWER:
+		return lm.Congrats
+	case INCORRECT_ANSWER:
+		return lm.TryAgain
+	case LATE_ANSWER:
+		return lm.TooLate
+	default:
+		return nil
+	}
+}
+
+// MessageCatalog holds the messages sent with answers, keyed by locale.
+// Each outcome can have several variants, one is picked at random for every answer.
+type MessageCatalog struct {
+	defaultLocale string
+	locales       map[string]LocaleMessages
+}
+
+// Message returns a message for the answer outcome in the first of the preferred locales found in the
+// catalog. Locales are matched exactly first, then by language ('es-MX' matches 'es'). The default locale
+// is used when none of the preferred locales are in the catalog or the locale has no message for the outcome.
+func (mc *MessageCatalog) Message(preferredLocales []string, outcome string) string {
+	variants := mc.locales[mc.MatchLocale(preferredLocales)].variants(outcome)
+	if len(variants) == 0 {
+		variants = mc.locales[mc.defaultLocale].variants(outcome)
+	}
+
+	if len(variants) == 0 {
+		return ""
+	}
+
+	return variants[rand.Intn(len(variants))]
+}
+
+// MatchLocale returns the catalog locale for the first of the preferred locales found in the catalog,
+// or the default locale
+func (mc *MessageCatalog) MatchLocale(preferredLocales []string) string {
+	for _, preferredLocale := range preferredLocales {
+		locale := normalizeLocale(preferredLocale)
+		if _, found := mc.locales[locale]; found {
+			return locale
+		}
+
+		language, _, _ := strings.Cut(locale, "-")
+		if _, found := mc.locales[language]; found {
+			return language
+		}
+	}
+
+	return mc.defaultLocale
+}
+
+// NewMessageCatalog creates the message catalog. The catalog starts with the built-in English messages,
+// then adds the locales in the catalog file set in config. The congrats and tryagain settings replace the
+// messages of the default locale.
+func NewMessageCatalog(cfgData *config.CfgData) (*MessageCatalog, error) {
+	logger := slog.Default()
+	logger.Info("Creating message catalog object")
+
+	messageCatalog := new(MessageCatalog)
+	messageCatalog.defaultLocale = normalizeLocale(cfgData.DefaultLocale)
+	if len(messageCatalog.defaultLocale) == 0 {
+		messageCatalog.defaultLocale = config.DEFAULT_LOCALE
+	}
+
+	messageCatalog.locales = map[string]LocaleMessages{
+		config.DEFAULT_LOCALE: {
+			Congrats: []string{messages.CONGRATS_MSG},
+			TryAgain: []string{messages.TRY_AGAIN_MSG},
+			TooLate:  []string{messages.TOO_LATE_MSG},
+		},
+	}
+
+	if len(cfgData.MessageCatalogPath) > 0 {
+		locales, loadErr := loadMessageCatalog(cfgData.MessageCatalogPath)
+		if loadErr != nil {
+			logger.Error("Error loading message catalog", "path", cfgData.MessageCatalogPath, common.ERROR_FIELD,
+				loadErr)
+			return nil, loadErr
+		}
+
+		for locale, localeMessages := range locales {
+			messageCatalog.locales[normalizeLocale(locale)] = mergeLocaleMessages(
+				messageCatalog.locales[normalizeLocale(locale)], localeMessages)
+		}
+	}
+
+	defaultMessages := messageCatalog.locales[messageCatalog.defaultLocale]
+	if len(cfgData.CongratsMsg) > 0 {
+		defaultMessages.Congrats = []string{cfgData.CongratsMsg}
+	}
+	if len(cfgData.TryAgainMsg) > 0 {
+		defaultMessages.TryAgain = []string{cfgData.TryAgainMsg}
+	}
+	messageCatalog.locales[messageCatalog.defaultLocale] = defaultMessages
+
+	return messageCatalog, nil
+}
+
+// unexported functions
+// loadMessageCatalog reads the locales from a JSON or YAML catalog file in the form:
+//
+//	{"en": {"congrats": ["..."], "tryagain": ["..."], "toolate": ["..."]}, "es": {...}}
+func loadMessageCatalog(path string) (map[string]LocaleMessages, error) {
+	byteStream, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	locales := make(map[string]LocaleMessages)
+	var unmarshalErr error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		unmarshalErr = yaml.Unmarshal(byteStream, &locales)
+	default:
+		unmarshalErr = json.Unmarshal(byteStream, &locales)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("message catalog %s: %w", path, unmarshalErr)
+	}
+
+	return locales, nil
+}
+
+// mergeLocaleMessages replaces the outcomes in base that have variants in override
+func mergeLocaleMessages(base LocaleMessages, override LocaleMessages) LocaleMessages {
+	if len(override.Congrats) > 0 {
+		base.Congrats = override.Congrats
+	}
+	if len(override.TryAgain) > 0 {
+		base.TryAgain = override.TryAgain
+	}
+	if len(override.TooLate) > 0 {
+		base.TooLate = override.TooLate
+	}
+
+	return base
+}
+
+// normalizeLocale lower cases a locale and uses '-' as the separator, 'pt_BR' becomes 'pt-br'
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// DEADLINE_KEY_SEPARATOR separates a category from a difficulty in a deadline key
+	DEADLINE_KEY_SEPARATOR string = "/"
+
+	// Points awarded for a correct answer when speed scoring is enabled
+	MAX_ANSWER_POINTS int = 1000
+	MIN_ANSWER_POINTS int = 100
+
+	// LATE_ANSWER_GRACE is how long questions are kept after their deadline, so late answers are told the
+	// deadline passed instead of that the question does not exist
+	LATE_ANSWER_GRACE time.Duration = time.Minute
+)
+
+// ErrAnswerTooLate is returned when an answer is submitted after the question deadline
+var ErrAnswerTooLate = errors.New("answer deadline has passed")
+
+// AnswerDeadlines holds how long a client has to answer a question. Deadlines can be set for a
+// category, a difficulty or a category and difficulty pair; the most specific one is used.
+type AnswerDeadlines struct {
+	defaultDeadline time.Duration
+	deadlines       map[string]time.Duration
+}
+
+// Deadline returns the answer deadline for a question. Zero means the question has no deadline.
+func (ad AnswerDeadlines) Deadline(category string, difficulty string) time.Duration {
+	keys := []string{
+		strings.ToLower(category + DEADLINE_KEY_SEPARATOR + difficulty),
+		strings.ToLower(category),
+		strings.ToLower(difficulty),
+	}
+
+	for _, key := range keys {
+		if deadline, found := ad.deadlines[key]; found {
+			return deadline
+		}
+	}
+
+	return ad.defaultDeadline
+}
+
+// NewAnswerDeadlines creates the answer deadlines from the default deadline and the deadline overrides.
+// Overrides are keyed by category, difficulty or 'category/difficulty', for example 'geography/hard'.
+func NewAnswerDeadlines(defaultDeadline time.Duration, deadlines map[string]time.Duration) AnswerDeadlines {
+	answerDeadlines := AnswerDeadlines{defaultDeadline: defaultDeadline, deadlines: make(map[string]time.Duration)}
+
+	for key, deadline := range deadlines {
+		answerDeadlines.deadlines[strings.ToLower(key)] = deadline
+	}
+
+	return answerDeadlines
+}
+
+// QuestionTTL returns how long to keep a question with the deadline: the question TTL, or the deadline
+// and the late answer grace period when that is longer
+func QuestionTTL(questionTTL time.Duration, deadline time.Duration) time.Duration {
+	if deadline > 0 && deadline+LATE_ANSWER_GRACE > questionTTL {
+		return deadline + LATE_ANSWER_GRACE
+	}
+
+	return questionTTL
+}
+
+// SpeedPoints scales the points for a correct answer by how quickly it was given.
+// Answers to questions without a deadline receive the maximum points.
+func SpeedPoints(elapsed time.Duration, deadline time.Duration) int {
+	if deadline <= 0 {
+		return MAX_ANSWER_POINTS
+	}
+
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	if elapsed >= deadline {
+		return MIN_ANSWER_POINTS
+	}
+
+	remaining := float64(deadline-elapsed) / float64(deadline)
+	return MIN_ANSWER_POINTS + int(remaining*float64(MAX_ANSWER_POINTS-MIN_ANSWER_POINTS))
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/sflewis2970/trivia-api/messages"
+	"testing"
+	"time"
+)
+
+func TestQuestionTTL(t *testing.T) {
+	tests := []struct {
+		questionTTL time.Duration
+		deadline    time.Duration
+		want        time.Duration
+	}{
+		{5 * time.Minute, 0, 5 * time.Minute},
+		{5 * time.Minute, 30 * time.Second, 5 * time.Minute},
+		{5 * time.Minute, 5 * time.Minute, 5*time.Minute + LATE_ANSWER_GRACE},
+		{time.Minute, 10 * time.Minute, 10*time.Minute + LATE_ANSWER_GRACE},
+	}
+
+	for _, test := range tests {
+		if got := QuestionTTL(test.questionTTL, test.deadline); got != test.want {
+			t.Errorf("QuestionTTL(%s, %s) = %s, want %s", test.questionTTL, test.deadline, got, test.want)
+		}
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	answerDeadlines := NewAnswerDeadlines(time.Minute, map[string]time.Duration{
+		"Geography/Hard": 3 * time.Minute,
+		"geography":      2 * time.Minute,
+		"easy":           30 * time.Second,
+	})
+
+	tests := []struct {
+		category   string
+		difficulty string
+		want       time.Duration
+	}{
+		{"geography", "hard", 3 * time.Minute},
+		{"Geography", "easy", 2 * time.Minute},
+		{"history", "easy", 30 * time.Second},
+		{"history", "hard", time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := answerDeadlines.Deadline(test.category, test.difficulty); got != test.want {
+			t.Errorf("Deadline(%q, %q) = %s, want %s", test.category, test.difficulty, got, test.want)
+		}
+	}
+}
+
+func TestLateAnswerOutlivesQuestionTTL(t *testing.T) {
+	memoryModel := NewMemoryModel()
+	defer memoryModel.Close()
+
+	gameModel := newTestGameModel(t, memoryModel)
+	triviaModel := gameModel.triviaModel
+
+	// The question TTL is shorter than the deadline
+	triviaModel.questionTTL = 20 * time.Millisecond
+	triviaModel.answerDeadlines = NewAnswerDeadlines(50*time.Millisecond, nil)
+
+	ctx := context.Background()
+	trivia := messages.Trivia{QuestionID: "question-1", Question: "What is 2 + 2?", Category: "math",
+		Type: messages.MULTIPLE_CHOICE, Answer: "4", Choices: []string{"4", "3", "5", "22"}}
+	if addErr := triviaModel.AddQuestion(ctx, trivia); addErr != nil {
+		t.Fatalf("AddQuestion() error = %v", addErr)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	aRequest := messages.AnswerRequest{QuestionID: trivia.QuestionID, Response: "4"}
+	if _, answerErr := triviaModel.GetAnswer(ctx, aRequest); !errors.Is(answerErr, ErrAnswerTooLate) {
+		t.Errorf("GetAnswer() after the deadline error = %v, want %v", answerErr, ErrAnswerTooLate)
+	}
+}
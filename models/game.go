@@ -0,0 +1,306 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"time"
+)
+
+const (
+	DEFAULT_GAME_QUESTIONS int = 10
+	MAX_GAME_QUESTIONS     int = 50
+
+	// DEFAULT_GAME_TTL is used when the game TTL is not configured
+	DEFAULT_GAME_TTL time.Duration = time.Hour
+)
+
+var (
+	// ErrInvalidGameRequest is returned when a game cannot be started with the requested settings
+	ErrInvalidGameRequest = errors.New("invalid game request")
+
+	// ErrGameFinished is returned when a question is requested or answered after the last question
+	ErrGameFinished = errors.New("game is finished")
+
+	// ErrQuestionNotInGame is returned when an answer is not for the current question of the game
+	ErrQuestionNotInGame = errors.New("question is not the current question for the game")
+)
+
+type GameModel struct {
+	cfgData     *config.CfgData
+	gameStore   GameStore
+	triviaModel *TriviaModel
+	gameTTL     time.Duration
+	logger      *slog.Logger
+}
+
+// StartGame creates a new game session
+func (gm *GameModel) StartGame(ctx context.Context, gRequest messages.GameRequest) (messages.Game, error) {
+	logger := common.LoggerFromContext(ctx, gm.logger)
+
+	totalQuestions := gRequest.TotalQuestions
+	if totalQuestions == 0 {
+		totalQuestions = DEFAULT_GAME_QUESTIONS
+	}
+
+	if totalQuestions < 0 || totalQuestions > MAX_GAME_QUESTIONS {
+		errMsg := fmt.Sprintf("number of questions must be between 1 and %d", MAX_GAME_QUESTIONS)
+		logger.Warn(errMsg)
+		return messages.Game{}, fmt.Errorf("%w: %s", ErrInvalidGameRequest, errMsg)
+	}
+
+	var game messages.Game
+	game.GameID = uuid.New().String()
+	game.PlayerID = gRequest.PlayerID
+	game.Category = gRequest.Category
+	game.Difficulty = gRequest.Difficulty
+	game.TotalQuestions = totalQuestions
+	game.Results = []messages.GameResult{}
+	game.Started = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+
+	insertErr := gm.gameStore.InsertGame(game, gm.gameTTL)
+	if insertErr != nil {
+		logger.Error("Error inserting game", common.GAME_ID_FIELD, game.GameID, common.ERROR_FIELD, insertErr)
+		return messages.Game{}, insertErr
+	}
+
+	return game, nil
+}
+
+// GetGame returns the game session for gameID. Games can only be played by the player who started them.
+func (gm *GameModel) GetGame(ctx context.Context, gameID string, playerID string) (messages.Game, error) {
+	logger := common.LoggerFromContext(ctx, gm.logger).With(common.GAME_ID_FIELD, gameID)
+
+	game, getErr := gm.gameStore.GetGame(gameID)
+	if getErr != nil {
+		logger.Warn("Get game error", common.ERROR_FIELD, getErr)
+		return messages.Game{}, getErr
+	}
+
+	if game.PlayerID != playerID {
+		logger.Warn("Game was started by another player")
+		return messages.Game{}, ErrGameNotFound
+	}
+
+	return game, nil
+}
+
+// PendingQuestion returns the question issued to the game that has not been answered yet.
+// When the question expired before it was answered it is recorded as incorrect and
+// found is false.
+func (gm *GameModel) PendingQuestion(ctx context.Context, game messages.Game) (messages.Game, messages.Trivia, bool, error) {
+	if len(game.CurrentQuestionID) == 0 {
+		return game, messages.Trivia{}, false, nil
+	}
+
+	tTable, getErr := gm.triviaModel.GetQuestion(ctx, game.CurrentQuestionID)
+	if errors.Is(getErr, ErrItemNotFound) {
+		var updateErr error
+		game, updateErr = gm.expireQuestion(ctx, game)
+		return game, messages.Trivia{}, false, updateErr
+	} else if getErr != nil {
+		return game, messages.Trivia{}, false, getErr
+	}
+
+	trivia := messages.Trivia{
+		QuestionID:  tTable.QuestionID,
+		Question:    tTable.Question,
+		Category:    tTable.Category,
+		Difficulty:  tTable.Difficulty,
+		Type:        tTable.Type,
+		Answer:      tTable.Answer,
+		Distractors: tTable.Distractors,
+		Alternates:  tTable.Alternates,
+		Choices:     tTable.Choices,
+		Timestamp:   tTable.Timestamp,
+		IssuedAt:    tTable.IssuedAt,
+		PlayerID:    tTable.PlayerID,
+	}
+
+	return game, trivia, true, nil
+}
+
+// AddQuestion issues trivia as the current question of the game
+func (gm *GameModel) AddQuestion(ctx context.Context, game messages.Game, trivia messages.Trivia) (messages.Game, error) {
+	if GameCompleted(game) {
+		return game, ErrGameFinished
+	}
+
+	// Questions in a game belong to the player of the game
+	trivia.PlayerID = game.PlayerID
+
+	insertErr := gm.triviaModel.AddQuestion(ctx, trivia)
+	if insertErr != nil {
+		return game, insertErr
+	}
+
+	game.CurrentQuestionID = trivia.QuestionID
+
+	game, updateErr := gm.saveGame(ctx, game)
+	if updateErr != nil {
+		// The question was not issued, another request issued or answered one first
+		_ = gm.triviaModel.DeleteQuestion(ctx, trivia.QuestionID)
+		return game, updateErr
+	}
+
+	return game, nil
+}
+
+// AnswerQuestion checks the answer to the current question of the game and tallies the result.
+// Concurrent answers to the same question are only tallied once; the others return ErrGameChanged.
+func (gm *GameModel) AnswerQuestion(ctx context.Context, gameID string,
+	aRequest messages.AnswerRequest) (messages.Game, messages.AnswerResponse, error) {
+	game, getErr := gm.GetGame(ctx, gameID, aRequest.PlayerID)
+	if getErr != nil {
+		return messages.Game{}, messages.AnswerResponse{}, getErr
+	}
+
+	if GameCompleted(game) {
+		return game, messages.AnswerResponse{}, ErrGameFinished
+	}
+
+	if len(game.CurrentQuestionID) == 0 || aRequest.QuestionID != game.CurrentQuestionID {
+		return game, messages.AnswerResponse{}, ErrQuestionNotInGame
+	}
+
+	aResponse, answerErr := gm.triviaModel.GetAnswer(ctx, aRequest)
+	if errors.Is(answerErr, ErrItemNotFound) {
+		// The question expired before it was answered
+		var updateErr error
+		game, updateErr = gm.expireQuestion(ctx, game)
+		if updateErr != nil {
+			return game, aResponse, updateErr
+		}
+		return game, aResponse, answerErr
+	} else if answerErr != nil && !errors.Is(answerErr, ErrAnswerTooLate) {
+		return game, aResponse, answerErr
+	}
+
+	// Late answers are recorded as incorrect
+	game.Results = append(game.Results, messages.GameResult{
+		QuestionID: aResponse.QuestionID,
+		Question:   aResponse.Question,
+		Response:   aResponse.Response,
+		Answer:     aResponse.Answer,
+		Correct:    aResponse.Correct,
+		Points:     aResponse.Points,
+	})
+	if aResponse.Correct {
+		game.Score++
+	}
+	game.Points += aResponse.Points
+
+	game, updateErr := gm.finishQuestion(ctx, game)
+	if updateErr != nil {
+		return game, aResponse, updateErr
+	}
+
+	// The question is no longer needed once it has been answered
+	deleteErr := gm.triviaModel.DeleteQuestion(ctx, aRequest.QuestionID)
+	if deleteErr != nil && !errors.Is(deleteErr, ErrItemNotFound) {
+		return game, aResponse, deleteErr
+	}
+
+	return game, aResponse, answerErr
+}
+
+// unexported type methods
+// expireQuestion records the current question of the game as unanswered
+func (gm *GameModel) expireQuestion(ctx context.Context, game messages.Game) (messages.Game, error) {
+	common.LoggerFromContext(ctx, gm.logger).Info("Question expired before it was answered",
+		common.GAME_ID_FIELD, game.GameID, common.QUESTION_ID_FIELD, game.CurrentQuestionID)
+
+	game.Results = append(game.Results, messages.GameResult{QuestionID: game.CurrentQuestionID})
+
+	return gm.finishQuestion(ctx, game)
+}
+
+// finishQuestion clears the current question and saves the game, marking it finished after the last question
+func (gm *GameModel) finishQuestion(ctx context.Context, game messages.Game) (messages.Game, error) {
+	game.CurrentQuestionID = ""
+	if GameCompleted(game) {
+		game.Finished = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+	}
+
+	return gm.saveGame(ctx, game)
+}
+
+// saveGame saves the game unless another request saved it after it was read, which returns ErrGameChanged
+func (gm *GameModel) saveGame(ctx context.Context, game messages.Game) (messages.Game, error) {
+	updateErr := gm.gameStore.UpdateGame(game)
+	if updateErr != nil {
+		common.LoggerFromContext(ctx, gm.logger).Warn("Update game error", common.GAME_ID_FIELD, game.GameID,
+			common.ERROR_FIELD, updateErr)
+		return game, updateErr
+	}
+
+	// The store increments the version of the saved game
+	game.Version++
+
+	return game, nil
+}
+
+// GameCompleted reports whether every question in the game has been answered
+func GameCompleted(game messages.Game) bool {
+	return len(game.Results) >= game.TotalQuestions
+}
+
+// NewGameSummary builds the summary of a game
+func NewGameSummary(game messages.Game) messages.GameSummaryResponse {
+	var gsResponse messages.GameSummaryResponse
+	gsResponse.GameID = game.GameID
+	gsResponse.Category = game.Category
+	gsResponse.Difficulty = game.Difficulty
+	gsResponse.TotalQuestions = game.TotalQuestions
+	gsResponse.Answered = len(game.Results)
+	gsResponse.Score = game.Score
+	gsResponse.Points = game.Points
+	gsResponse.Completed = GameCompleted(game)
+	gsResponse.Results = game.Results
+	gsResponse.Started = game.Started
+	gsResponse.Finished = game.Finished
+
+	// Accuracy is the percentage of answered questions that were correct
+	if gsResponse.Answered > 0 {
+		gsResponse.Accuracy = float64(game.Score) * 100 / float64(gsResponse.Answered)
+	}
+
+	return gsResponse
+}
+
+// NewGameModel creates a game model that keeps games in triviaStore, which must also implement GameStore
+func NewGameModel(triviaStore TriviaStore, triviaModel *TriviaModel) (*GameModel, error) {
+	logger := slog.Default()
+	logger.Info("Creating game model object")
+
+	gameStore, ok := triviaStore.(GameStore)
+	if !ok {
+		errMsg := "trivia store does not support games"
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+
+	gameModel := new(GameModel)
+	gameModel.logger = logger
+
+	// Get config data
+	gameModel.cfgData = config.NewConfig().LoadCfgData()
+
+	gameModel.gameStore = gameStore
+	gameModel.triviaModel = triviaModel
+
+	// Games expire when they are abandoned
+	gameModel.gameTTL = gameModel.cfgData.GameTTL
+	if gameModel.gameTTL <= 0 {
+		logger.Warn("Invalid game TTL, using default", "game_ttl", gameModel.cfgData.GameTTL, "default",
+			DEFAULT_GAME_TTL)
+		gameModel.gameTTL = DEFAULT_GAME_TTL
+	}
+
+	return gameModel, nil
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/messages"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// barrierStore holds back every game read until the set number of reads have been made, so requests
+// read the same game before any of them saves it
+type barrierStore struct {
+	TriviaStore
+	GameStore
+	reads sync.WaitGroup
+}
+
+func (bs *barrierStore) GetGame(gameID string) (messages.Game, error) {
+	game, getErr := bs.GameStore.GetGame(gameID)
+	bs.reads.Done()
+	bs.reads.Wait()
+
+	return game, getErr
+}
+
+// newTestGameModel creates a game model keeping games and questions in triviaStore
+func newTestGameModel(t *testing.T, triviaStore TriviaStore) *GameModel {
+	cfgData := config.NewDefaultCfgData()
+	messageCatalog, catalogErr := NewMessageCatalog(cfgData)
+	if catalogErr != nil {
+		t.Fatalf("NewMessageCatalog() error = %v", catalogErr)
+	}
+	answerMatcher, matcherErr := NewAnswerMatcher(cfgData)
+	if matcherErr != nil {
+		t.Fatalf("NewAnswerMatcher() error = %v", matcherErr)
+	}
+
+	logger := common.NewLogger(io.Discard, "error", "text")
+	triviaModel := NewTriviaModel(triviaStore, messageCatalog, answerMatcher, logger)
+	gameModel, gameErr := NewGameModel(triviaStore, triviaModel)
+	if gameErr != nil {
+		t.Fatalf("NewGameModel() error = %v", gameErr)
+	}
+
+	return gameModel
+}
+
+// newTestStores returns a memory store and a sqlite store
+func newTestStores(t *testing.T) map[string]TriviaStore {
+	memoryModel := NewMemoryModel()
+	t.Cleanup(func() { memoryModel.Close() })
+
+	sqlModel, sqlErr := NewSQLModel("sqlite3", filepath.Join(t.TempDir(), "trivia.db"))
+	if sqlErr != nil {
+		t.Fatalf("NewSQLModel() error = %v", sqlErr)
+	}
+	t.Cleanup(func() { sqlModel.Close() })
+
+	return map[string]TriviaStore{MEMORY_STORE: memoryModel, SQL_STORE: sqlModel}
+}
+
+func TestUpdateGameVersion(t *testing.T) {
+	for storeType, triviaStore := range newTestStores(t) {
+		t.Run(storeType, func(t *testing.T) {
+			gameStore := triviaStore.(GameStore)
+			game := messages.Game{GameID: "game-1", PlayerID: "player-1", TotalQuestions: 2}
+			if insertErr := gameStore.InsertGame(game, time.Hour); insertErr != nil {
+				t.Fatalf("InsertGame() error = %v", insertErr)
+			}
+
+			// Two requests read the game, only the first to save it succeeds
+			first, _ := gameStore.GetGame(game.GameID)
+			second, _ := gameStore.GetGame(game.GameID)
+
+			first.Score = 1
+			if updateErr := gameStore.UpdateGame(first); updateErr != nil {
+				t.Fatalf("UpdateGame(first) error = %v", updateErr)
+			}
+
+			second.Score = 2
+			if updateErr := gameStore.UpdateGame(second); !errors.Is(updateErr, ErrGameChanged) {
+				t.Errorf("UpdateGame(second) error = %v, want %v", updateErr, ErrGameChanged)
+			}
+
+			stored, _ := gameStore.GetGame(game.GameID)
+			if stored.Score != 1 || stored.Version != 1 {
+				t.Errorf("stored game score = %d, version = %d, want 1, 1", stored.Score, stored.Version)
+			}
+
+			missing := messages.Game{GameID: "game-2"}
+			if updateErr := gameStore.UpdateGame(missing); !errors.Is(updateErr, ErrGameNotFound) {
+				t.Errorf("UpdateGame(missing) error = %v, want %v", updateErr, ErrGameNotFound)
+			}
+		})
+	}
+}
+
+func TestInsertGameExists(t *testing.T) {
+	for storeType, triviaStore := range newTestStores(t) {
+		t.Run(storeType, func(t *testing.T) {
+			gameStore := triviaStore.(GameStore)
+			game := messages.Game{GameID: "game-1", PlayerID: "player-1", TotalQuestions: 2}
+			if insertErr := gameStore.InsertGame(game, time.Hour); insertErr != nil {
+				t.Fatalf("InsertGame() error = %v", insertErr)
+			}
+
+			// A game with the same ID does not replace the game
+			other := messages.Game{GameID: "game-1", PlayerID: "player-2", TotalQuestions: 5}
+			if insertErr := gameStore.InsertGame(other, time.Hour); !errors.Is(insertErr, ErrGameExists) {
+				t.Errorf("InsertGame(other) error = %v, want %v", insertErr, ErrGameExists)
+			}
+
+			stored, getErr := gameStore.GetGame(game.GameID)
+			if getErr != nil || stored.PlayerID != game.PlayerID {
+				t.Errorf("GetGame() = %+v, %v, want the game of %s", stored, getErr, game.PlayerID)
+			}
+		})
+	}
+}
+
+func TestStartGameID(t *testing.T) {
+	gameModel := newTestGameModel(t, NewMemoryModel())
+	game, startErr := gameModel.StartGame(context.Background(), messages.GameRequest{PlayerID: "player-1"})
+	if startErr != nil {
+		t.Fatalf("StartGame() error = %v", startErr)
+	}
+
+	if _, parseErr := uuid.Parse(game.GameID); parseErr != nil {
+		t.Errorf("game ID %q is not a full UUID: %v", game.GameID, parseErr)
+	}
+}
+
+func TestAnswerQuestionConcurrently(t *testing.T) {
+	const answers = 10
+
+	for storeType, triviaStore := range newTestStores(t) {
+		t.Run(storeType, func(t *testing.T) {
+			gameStore := &barrierStore{TriviaStore: triviaStore, GameStore: triviaStore.(GameStore)}
+			gameModel := newTestGameModel(t, gameStore)
+			ctx := context.Background()
+
+			game, startErr := gameModel.StartGame(ctx, messages.GameRequest{PlayerID: "player-1", TotalQuestions: 3})
+			if startErr != nil {
+				t.Fatalf("StartGame() error = %v", startErr)
+			}
+
+			trivia := messages.Trivia{QuestionID: "question-1", Question: "What is 2 + 2?", Category: "math",
+				Type: messages.MULTIPLE_CHOICE, Answer: "4", Choices: []string{"4", "3", "5", "22"}}
+			game, addErr := gameModel.AddQuestion(ctx, game, trivia)
+			if addErr != nil {
+				t.Fatalf("AddQuestion() error = %v", addErr)
+			}
+
+			// The same answer is sent several times at once
+			aRequest := messages.AnswerRequest{QuestionID: trivia.QuestionID, Response: "4", PlayerID: "player-1"}
+			gameStore.reads.Add(answers)
+			var waitGroup sync.WaitGroup
+			results := make(chan error, answers)
+			for idx := 0; idx < answers; idx++ {
+				waitGroup.Add(1)
+				go func() {
+					defer waitGroup.Done()
+					_, _, answerErr := gameModel.AnswerQuestion(ctx, game.GameID, aRequest)
+					results <- answerErr
+				}()
+			}
+			waitGroup.Wait()
+			close(results)
+
+			tallied := 0
+			for answerErr := range results {
+				if answerErr == nil {
+					tallied++
+				}
+			}
+			if tallied != 1 {
+				t.Errorf("%d answers were tallied, want 1", tallied)
+			}
+
+			stored, _ := triviaStore.(GameStore).GetGame(game.GameID)
+			if stored.Score != 1 || len(stored.Results) != 1 {
+				t.Errorf("game score = %d with %d results, want 1 with 1 result", stored.Score, len(stored.Results))
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"time"
+)
+
+// Leaderboards
+const (
+	OVERALL_BOARD  string = "overall"
+	DAILY_BOARD    string = "daily"
+	WEEKLY_BOARD   string = "weekly"
+	CATEGORY_BOARD string = "category"
+)
+
+const (
+	LEADERBOARD_KEY_PREFIX string = "leaderboard:"
+	DAILY_KEY_FORMAT       string = "2006-01-02"
+
+	DEFAULT_LEADERBOARD_COUNT int = 10
+	MAX_LEADERBOARD_COUNT     int = 100
+
+	// Windowed leaderboards are kept a little longer than their window so the previous window can be read
+	DAILY_BOARD_TTL  time.Duration = 2 * 24 * time.Hour
+	WEEKLY_BOARD_TTL time.Duration = 2 * 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidLeaderboard is returned when a leaderboard request is not valid
+	ErrInvalidLeaderboard = errors.New("invalid leaderboard")
+
+	// ErrPlayerNotRanked is returned when a player has no score on a leaderboard
+	ErrPlayerNotRanked = errors.New("player is not ranked")
+)
+
+// LeaderboardModel keeps player scores in sorted sets in a score store
+type LeaderboardModel struct {
+	scoreStore ScoreStore
+	logger     *slog.Logger
+}
+
+// RecordCorrectAnswer adds points to the player on the overall, category, daily and weekly leaderboards
+func (lm *LeaderboardModel) RecordCorrectAnswer(ctx context.Context, playerID string, category string,
+	points int) error {
+	if len(playerID) == 0 {
+		return nil
+	}
+
+	// Every correct answer is worth at least one point
+	if points <= 0 {
+		points = 1
+	}
+
+	now := time.Now()
+	keys := []ScoreKey{
+		{Key: leaderboardKey(OVERALL_BOARD, "", now)},
+		{Key: leaderboardKey(DAILY_BOARD, "", now), TTL: DAILY_BOARD_TTL},
+		{Key: leaderboardKey(WEEKLY_BOARD, "", now), TTL: WEEKLY_BOARD_TTL},
+	}
+	if len(category) > 0 {
+		keys = append(keys, ScoreKey{Key: leaderboardKey(CATEGORY_BOARD, category, now)})
+	}
+
+	incrErr := lm.scoreStore.IncrementScore(keys, playerID, float64(points))
+	if incrErr != nil {
+		common.LoggerFromContext(ctx, lm.logger).Error("Error updating leaderboards", common.PLAYER_ID_FIELD, playerID,
+			common.ERROR_FIELD, incrErr)
+		return incrErr
+	}
+
+	return nil
+}
+
+// TopPlayers returns the highest scoring players on a leaderboard
+func (lm *LeaderboardModel) TopPlayers(board string, category string, count int) ([]messages.LeaderboardEntry, error) {
+	validateErr := validateLeaderboard(board, category)
+	if validateErr != nil {
+		return nil, validateErr
+	}
+
+	if count == 0 {
+		count = DEFAULT_LEADERBOARD_COUNT
+	}
+
+	if count < 0 || count > MAX_LEADERBOARD_COUNT {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidLeaderboard, MAX_LEADERBOARD_COUNT)
+	}
+
+	scores, topErr := lm.scoreStore.TopScores(leaderboardKey(board, category, time.Now()), int64(count))
+	if topErr != nil {
+		return nil, topErr
+	}
+
+	entries := make([]messages.LeaderboardEntry, 0, len(scores))
+	for idx, score := range scores {
+		entries = append(entries, messages.LeaderboardEntry{Rank: idx + 1, PlayerID: score.Member, Score: score.Score})
+	}
+
+	return entries, nil
+}
+
+// PlayerRank returns the rank and score of a player on a leaderboard
+func (lm *LeaderboardModel) PlayerRank(board string, category string, playerID string) (messages.LeaderboardEntry, error) {
+	validateErr := validateLeaderboard(board, category)
+	if validateErr != nil {
+		return messages.LeaderboardEntry{}, validateErr
+	}
+
+	rank, score, rankErr := lm.scoreStore.ScoreRank(leaderboardKey(board, category, time.Now()), playerID)
+	if errors.Is(rankErr, ErrItemNotFound) {
+		return messages.LeaderboardEntry{}, ErrPlayerNotRanked
+	} else if rankErr != nil {
+		return messages.LeaderboardEntry{}, rankErr
+	}
+
+	return messages.LeaderboardEntry{Rank: int(rank) + 1, PlayerID: playerID, Score: score}, nil
+}
+
+// NewLeaderboardModel creates a leaderboard model that keeps scores in scoreStore
+func NewLeaderboardModel(scoreStore ScoreStore) *LeaderboardModel {
+	logger := slog.Default()
+	logger.Info("Creating leaderboard model object")
+	leaderboardModel := new(LeaderboardModel)
+	leaderboardModel.logger = logger
+	leaderboardModel.scoreStore = scoreStore
+
+	return leaderboardModel
+}
+
+// unexported functions
+func validateLeaderboard(board string, category string) error {
+	switch board {
+	case OVERALL_BOARD, DAILY_BOARD, WEEKLY_BOARD:
+		return nil
+	case CATEGORY_BOARD:
+		if len(category) == 0 {
+			return fmt.Errorf("%w: category is required", ErrInvalidLeaderboard)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s is not a leaderboard", ErrInvalidLeaderboard, board)
+	}
+}
+
+// leaderboardKey returns the score store key for a leaderboard. Daily and weekly leaderboards use UTC windows.
+func leaderboardKey(board string, category string, now time.Time) string {
+	now = now.UTC()
+
+	switch board {
+	case DAILY_BOARD:
+		return LEADERBOARD_KEY_PREFIX + DAILY_BOARD + ":" + now.Format(DAILY_KEY_FORMAT)
+	case WEEKLY_BOARD:
+		year, week := now.ISOWeek()
+		return fmt.Sprintf("%s%s:%d-W%02d", LEADERBOARD_KEY_PREFIX, WEEKLY_BOARD, year, week)
+	case CATEGORY_BOARD:
+		return LEADERBOARD_KEY_PREFIX + CATEGORY_BOARD + ":" + category
+	default:
+		return LEADERBOARD_KEY_PREFIX + OVERALL_BOARD
+	}
+}
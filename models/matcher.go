@@ -0,0 +1,321 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// How a response matched the answer, reported in AnswerResponse
+const (
+	EXACT_MATCH string = "exact"
+	FUZZY_MATCH string = "fuzzy"
+	NO_MATCH    string = ""
+)
+
+// Typos are tolerated word by word: one for every TYPO_LENGTH characters in a word, in words of at least
+// MIN_TYPO_WORD_LENGTH characters
+const (
+	TYPO_LENGTH          int = 4
+	MIN_TYPO_WORD_LENGTH int = 5
+)
+
+// articles are ignored when answers are compared
+var articles = map[string]bool{"a": true, "an": true, "the": true}
+
+// numberWords are the words used to spell out numbers, with their value
+var numberWords = map[string]int{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+	"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+	"seventeen": 17, "eighteen": 18, "nineteen": 19, "twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+// romanNumeralPattern matches roman numerals written in their usual form, "vii" or "xiv"
+var romanNumeralPattern = regexp.MustCompile(`^m{0,3}(cm|cd|d?c{0,3})(xc|xl|l?x{0,3})(ix|iv|v?i{0,3})$`)
+
+// numberScales multiply the number spelled out before them
+var numberScales = map[string]int{"hundred": 100, "thousand": 1000, "million": 1000000}
+
+// diacritics maps accented latin letters to the letters they are compared as
+var diacritics = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a", 'æ': "ae",
+	'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// AnswerMatcher decides whether a free text response matches an answer. Responses match exactly when they
+// are the answer, or an alternate answer, apart from case and surrounding spaces. Responses match fuzzily
+// when they are the same once case, punctuation, articles, diacritics and spelled out numbers are ignored,
+// or when each word is within a few typos of the word in the answer. Numbers, roman numerals and short words
+// must be given correctly.
+type AnswerMatcher struct {
+	maxTypos   int
+	alternates map[string][]string
+}
+
+// Match returns how the response matched the answer or one of the alternate answers,
+// NO_MATCH when it did not
+func (am *AnswerMatcher) Match(response string, answer string, alternates []string) string {
+	response = strings.TrimSpace(response)
+	if len(response) == 0 {
+		return NO_MATCH
+	}
+
+	// Alternates for the question come first, then the alternates configured for the answer
+	answers := []string{answer}
+	answers = append(answers, alternates...)
+	answers = append(answers, am.alternates[NormalizeAnswer(answer)]...)
+
+	for _, candidate := range answers {
+		if strings.EqualFold(response, strings.TrimSpace(candidate)) {
+			return EXACT_MATCH
+		}
+	}
+
+	normalizedResponse := NormalizeAnswer(response)
+	if len(normalizedResponse) == 0 {
+		return NO_MATCH
+	}
+
+	for _, candidate := range answers {
+		normalizedCandidate := NormalizeAnswer(candidate)
+		if len(normalizedCandidate) == 0 {
+			continue
+		}
+
+		if normalizedResponse == normalizedCandidate || am.withinTypos(normalizedResponse, normalizedCandidate) {
+			return FUZZY_MATCH
+		}
+	}
+
+	return NO_MATCH
+}
+
+// NormalizeAnswer returns the form answers are compared in: lower case without diacritics, punctuation
+// or articles, with spelled out numbers as digits. "The Forty-Two Café" becomes "42 cafe".
+func NormalizeAnswer(answer string) string {
+	var builder strings.Builder
+	runes := []rune(strings.ToLower(answer))
+	for idx, r := range runes {
+		if folded, found := diacritics[r]; found {
+			builder.WriteString(folded)
+			continue
+		}
+
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			builder.WriteRune(r)
+		case (r == ',' || r == '.') && idx > 0 && idx < len(runes)-1 &&
+			unicode.IsDigit(runes[idx-1]) && unicode.IsDigit(runes[idx+1]):
+			// Keep decimal points, drop thousands separators
+			if r == '.' {
+				builder.WriteRune(r)
+			}
+		case r == '\'' || r == '’':
+			// Apostrophes join words: "o'neill" is compared as "oneill"
+		default:
+			builder.WriteRune(' ')
+		}
+	}
+
+	var words []string
+	for _, word := range strings.Fields(builder.String()) {
+		if !articles[word] {
+			words = append(words, word)
+		}
+	}
+
+	return strings.Join(spellNumbers(words), " ")
+}
+
+// unexported type methods
+// withinTypos reports whether a normalized response has the words of a normalized answer, each within the
+// typos tolerated for it and no more than maxTypos in all
+func (am *AnswerMatcher) withinTypos(normalizedResponse string, normalizedAnswer string) bool {
+	responseWords, answerWords := strings.Fields(normalizedResponse), strings.Fields(normalizedAnswer)
+	if len(responseWords) != len(answerWords) {
+		return false
+	}
+
+	typos := 0
+	for idx, answerWord := range answerWords {
+		responseWord := responseWords[idx]
+		if responseWord == answerWord {
+			continue
+		}
+
+		distance := editDistance(responseWord, answerWord)
+		if distance > wordTolerance(responseWord, answerWord) {
+			return false
+		}
+
+		typos += distance
+		if typos > am.maxTypos {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewAnswerMatcher creates the answer matcher with the typo tolerance and alternate answers file set in
+// config. The alternate answers file is JSON or YAML, mapping answers to the other answers accepted for them:
+//
+//	{"United States": ["USA", "US", "America"]}
+func NewAnswerMatcher(cfgData *config.CfgData) (*AnswerMatcher, error) {
+	logger := slog.Default()
+	logger.Info("Creating answer matcher object")
+
+	answerMatcher := new(AnswerMatcher)
+	answerMatcher.maxTypos = cfgData.MaxAnswerTypos
+	answerMatcher.alternates = make(map[string][]string)
+
+	if len(cfgData.AlternateAnswersPath) > 0 {
+		alternates, loadErr := loadAlternateAnswers(cfgData.AlternateAnswersPath)
+		if loadErr != nil {
+			logger.Error("Error loading alternate answers", "path", cfgData.AlternateAnswersPath, common.ERROR_FIELD,
+				loadErr)
+			return nil, loadErr
+		}
+
+		for answer, answerAlternates := range alternates {
+			key := NormalizeAnswer(answer)
+			answerMatcher.alternates[key] = append(answerMatcher.alternates[key], answerAlternates...)
+		}
+	}
+
+	return answerMatcher, nil
+}
+
+// unexported functions
+func loadAlternateAnswers(path string) (map[string][]string, error) {
+	byteStream, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	alternates := make(map[string][]string)
+	var unmarshalErr error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		unmarshalErr = yaml.Unmarshal(byteStream, &alternates)
+	default:
+		unmarshalErr = json.Unmarshal(byteStream, &alternates)
+	}
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("alternate answers %s: %w", path, unmarshalErr)
+	}
+
+	return alternates, nil
+}
+
+// spellNumbers replaces each run of number words with its value: "twenty one" becomes "21"
+func spellNumbers(words []string) []string {
+	var spelled []string
+	total, current, inNumber := 0, 0, false
+
+	endNumber := func() {
+		if inNumber {
+			spelled = append(spelled, strconv.Itoa(total+current))
+		}
+		total, current, inNumber = 0, 0, false
+	}
+
+	for _, word := range words {
+		if value, found := numberWords[word]; found {
+			current += value
+			inNumber = true
+		} else if scale, found := numberScales[word]; found && inNumber {
+			if scale == 100 {
+				current *= scale
+			} else {
+				total += current * scale
+				current = 0
+			}
+		} else if word == "and" && inNumber {
+			// "one hundred and five"
+		} else {
+			endNumber()
+			spelled = append(spelled, word)
+		}
+	}
+	endNumber()
+
+	return spelled
+}
+
+// wordTolerance returns the number of typos tolerated between a response word and an answer word, going by the
+// shorter of the two so a word is not taken for a longer one: "austria" is not "australia". Numbers, roman
+// numerals and short words must be spelled correctly.
+func wordTolerance(responseWord string, answerWord string) int {
+	for _, word := range []string{responseWord, answerWord} {
+		if hasDigit(word) || romanNumeralPattern.MatchString(word) {
+			return 0
+		}
+	}
+
+	length := minInt(len([]rune(responseWord)), len([]rune(answerWord)))
+	if length < MIN_TYPO_WORD_LENGTH {
+		return 0
+	}
+
+	return length / TYPO_LENGTH
+}
+
+func hasDigit(word string) bool {
+	return strings.IndexFunc(word, unicode.IsDigit) >= 0
+}
+
+// editDistance returns the Levenshtein distance between two strings, counting an insertion, deletion or
+// substitution of a single character as one edit
+func editDistance(first string, second string) int {
+	firstRunes, secondRunes := []rune(first), []rune(second)
+
+	previous := make([]int, len(secondRunes)+1)
+	current := make([]int, len(secondRunes)+1)
+	for idx := range previous {
+		previous[idx] = idx
+	}
+
+	for firstIdx := 1; firstIdx <= len(firstRunes); firstIdx++ {
+		current[0] = firstIdx
+		for secondIdx := 1; secondIdx <= len(secondRunes); secondIdx++ {
+			cost := 1
+			if firstRunes[firstIdx-1] == secondRunes[secondIdx-1] {
+				cost = 0
+			}
+
+			current[secondIdx] = minInt(previous[secondIdx]+1, current[secondIdx-1]+1, previous[secondIdx-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(secondRunes)]
+}
+
+func minInt(values ...int) int {
+	minValue := values[0]
+	for _, value := range values[1:] {
+		if value < minValue {
+			minValue = value
+		}
+	}
+
+	return minValue
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"github.com/sflewis2970/trivia-api/config"
+	"testing"
+)
+
+func TestAnswerMatcherMatch(t *testing.T) {
+	answerMatcher, matcherErr := NewAnswerMatcher(config.NewDefaultCfgData())
+	if matcherErr != nil {
+		t.Fatalf("NewAnswerMatcher() error = %v", matcherErr)
+	}
+
+	tests := []struct {
+		response   string
+		answer     string
+		alternates []string
+		want       string
+	}{
+		// Exact and normalized answers
+		{"Paris", "Paris", nil, EXACT_MATCH},
+		{"  paris ", "Paris", nil, EXACT_MATCH},
+		{"USA", "United States", []string{"USA"}, EXACT_MATCH},
+		{"the beatles", "Beatles", nil, FUZZY_MATCH},
+		{"Forty-Two", "42", nil, FUZZY_MATCH},
+		{"Pele", "Pelé", nil, FUZZY_MATCH},
+		{"World War II", "World War II", nil, EXACT_MATCH},
+
+		// Typos in long words
+		{"Mississipi", "Mississippi", nil, FUZZY_MATCH},
+		{"Shakespear", "William Shakespeare", nil, NO_MATCH},
+		{"William Shakespear", "William Shakespeare", nil, FUZZY_MATCH},
+		{"Einstien", "Einstein", nil, FUZZY_MATCH},
+		{"Leonardo da Vinchi", "Leonardo da Vinci", nil, FUZZY_MATCH},
+		{"Jupyter", "Jupiter", nil, FUZZY_MATCH},
+
+		// Numbers and roman numerals must be given correctly
+		{"World War 1", "World War 2", nil, NO_MATCH},
+		{"Apollo 11", "Apollo 13", nil, NO_MATCH},
+		{"Henry VII", "Henry VIII", nil, NO_MATCH},
+		{"Louis XIV", "Louis XVI", nil, NO_MATCH},
+		{"1066", "1067", nil, NO_MATCH},
+
+		// Words are not taken for longer or shorter words
+		{"Austria", "Australia", nil, NO_MATCH},
+		{"Australia", "Austria", nil, NO_MATCH},
+
+		// Short words must be spelled correctly
+		{"Mars", "Mary", nil, NO_MATCH},
+		{"Rome", "Roma", nil, NO_MATCH},
+
+		// Typos are capped across the answer
+		{"Leonardu da Vinchi", "Leonardo da Vinci", nil, FUZZY_MATCH},
+		{"Leonardu da Vinchi Airpert", "Leonardo da Vinci Airport", nil, NO_MATCH},
+
+		{"", "Paris", nil, NO_MATCH},
+		{"London", "Paris", nil, NO_MATCH},
+	}
+
+	for _, test := range tests {
+		got := answerMatcher.Match(test.response, test.answer, test.alternates)
+		if got != test.want {
+			t.Errorf("Match(%q, %q, %q) = %q, want %q", test.response, test.answer, test.alternates, got, test.want)
+		}
+	}
+}
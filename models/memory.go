@@ -0,0 +1,324 @@
+package models
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	MEMORY_ITEM_NOT_FOUND_ERROR string = "Item not found"
+
+	// MEMORY_SWEEP_INTERVAL is how often expired records are removed
+	MEMORY_SWEEP_INTERVAL time.Duration = time.Minute
+)
+
+type memoryItem struct {
+	tTable    messages.TriviaTable
+	expiresAt time.Time
+}
+
+type memoryGame struct {
+	game      messages.Game
+	expiresAt time.Time
+}
+
+// expired reports whether the game has expired at time now. Games without an expiry never expire.
+func (mg memoryGame) expired(now time.Time) bool {
+	return !mg.expiresAt.IsZero() && !now.Before(mg.expiresAt)
+}
+
+// expired reports whether the item has expired at time now. Items without an expiry never expire.
+func (mi memoryItem) expired(now time.Time) bool {
+	return !mi.expiresAt.IsZero() && !now.Before(mi.expiresAt)
+}
+
+// MemoryModel is an in-process trivia store, safe for concurrent use
+type MemoryModel struct {
+	mutex     sync.RWMutex
+	items     map[string]memoryItem
+	games     map[string]memoryGame
+	players   map[string]messages.Player
+	apiKeys   map[string]string
+	stopSweep chan struct{}
+	closeOnce sync.Once
+	logger    *slog.Logger
+}
+
+// Ping always succeeds since the data is local to the server
+func (mm *MemoryModel) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Insert a single record into the map, the record expires after ttl
+func (mm *MemoryModel) Insert(ctx context.Context, trivia messages.Trivia, ttl time.Duration) error {
+	mm.logFor(ctx).Debug("Adding a new record to map", common.QUESTION_ID_FIELD, trivia.QuestionID)
+
+	item := memoryItem{tTable: NewTriviaTable(trivia)}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+
+	mm.mutex.Lock()
+	mm.items[trivia.QuestionID] = item
+	mm.mutex.Unlock()
+
+	return nil
+}
+
+// Get a single record from the map
+func (mm *MemoryModel) Get(ctx context.Context, questionID string) (messages.TriviaTable, error) {
+	logger := mm.logFor(ctx).With(common.QUESTION_ID_FIELD, questionID)
+	logger.Debug("Getting record from the map")
+
+	mm.mutex.RLock()
+	item, found := mm.items[questionID]
+	mm.mutex.RUnlock()
+
+	if !found || item.expired(time.Now()) {
+		logger.Debug(MEMORY_ITEM_NOT_FOUND_ERROR)
+		return messages.TriviaTable{}, ErrItemNotFound
+	}
+
+	return item.tTable, nil
+}
+
+// Update a single record in the map, keeping the remaining TTL of the record
+func (mm *MemoryModel) Update(ctx context.Context, updatedRec messages.Trivia) error {
+	logger := mm.logFor(ctx).With(common.QUESTION_ID_FIELD, updatedRec.QuestionID)
+	logger.Debug("Updating record in the map")
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	item, found := mm.items[updatedRec.QuestionID]
+	if !found || item.expired(time.Now()) {
+		logger.Debug(MEMORY_ITEM_NOT_FOUND_ERROR)
+		return ErrItemNotFound
+	}
+
+	item.tTable = NewTriviaTable(updatedRec)
+	mm.items[updatedRec.QuestionID] = item
+
+	return nil
+}
+
+// Delete a single record from the map
+func (mm *MemoryModel) Delete(ctx context.Context, questionID string) error {
+	logger := mm.logFor(ctx).With(common.QUESTION_ID_FIELD, questionID)
+	logger.Debug("Deleting record from the map")
+
+	mm.mutex.Lock()
+	item, found := mm.items[questionID]
+	delete(mm.items, questionID)
+	mm.mutex.Unlock()
+
+	if !found || item.expired(time.Now()) {
+		logger.Debug(MEMORY_ITEM_NOT_FOUND_ERROR)
+		return ErrItemNotFound
+	}
+
+	return nil
+}
+
+// InsertGame adds a game session to the map, the game expires after ttl
+func (mm *MemoryModel) InsertGame(game messages.Game, ttl time.Duration) error {
+	mm.logger.Debug("Adding a new game to map", common.GAME_ID_FIELD, game.GameID)
+
+	mGame := memoryGame{game: copyGame(game)}
+	if ttl > 0 {
+		mGame.expiresAt = time.Now().Add(ttl)
+	}
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	if storedGame, found := mm.games[game.GameID]; found && !storedGame.expired(time.Now()) {
+		mm.logger.Debug("Game already in the map", common.GAME_ID_FIELD, game.GameID)
+		return ErrGameExists
+	}
+
+	mm.games[game.GameID] = mGame
+
+	return nil
+}
+
+// GetGame gets a single game session from the map
+func (mm *MemoryModel) GetGame(gameID string) (messages.Game, error) {
+	mm.logger.Debug("Getting game from the map", common.GAME_ID_FIELD, gameID)
+
+	mm.mutex.RLock()
+	mGame, found := mm.games[gameID]
+	mm.mutex.RUnlock()
+
+	if !found || mGame.expired(time.Now()) {
+		mm.logger.Debug(MEMORY_ITEM_NOT_FOUND_ERROR, common.GAME_ID_FIELD, gameID)
+		return messages.Game{}, ErrGameNotFound
+	}
+
+	return copyGame(mGame.game), nil
+}
+
+// UpdateGame replaces a game session in the map, keeping the remaining TTL of the game
+func (mm *MemoryModel) UpdateGame(game messages.Game) error {
+	logger := mm.logger.With(common.GAME_ID_FIELD, game.GameID)
+	logger.Debug("Updating game in the map")
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	mGame, found := mm.games[game.GameID]
+	if !found || mGame.expired(time.Now()) {
+		logger.Debug(MEMORY_ITEM_NOT_FOUND_ERROR)
+		return ErrGameNotFound
+	}
+
+	if mGame.game.Version != game.Version {
+		logger.Debug("Game was changed by another request")
+		return ErrGameChanged
+	}
+
+	mGame.game = copyGame(game)
+	mGame.game.Version++
+	mm.games[game.GameID] = mGame
+
+	return nil
+}
+
+// DeleteGame deletes a single game session from the map
+func (mm *MemoryModel) DeleteGame(gameID string) error {
+	mm.logger.Debug("Deleting game from the map", common.GAME_ID_FIELD, gameID)
+
+	mm.mutex.Lock()
+	delete(mm.games, gameID)
+	mm.mutex.Unlock()
+
+	return nil
+}
+
+// InsertPlayer adds a player to the map along with the hash of the player's API key
+func (mm *MemoryModel) InsertPlayer(player messages.Player, apiKeyHash string) error {
+	mm.logger.Debug("Adding a new player to map", common.PLAYER_ID_FIELD, player.PlayerID)
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	_, playerFound := mm.players[player.PlayerID]
+	_, keyFound := mm.apiKeys[apiKeyHash]
+	if playerFound || keyFound {
+		mm.logger.Debug("Player already in the map", common.PLAYER_ID_FIELD, player.PlayerID)
+		return ErrPlayerExists
+	}
+
+	mm.players[player.PlayerID] = player
+	mm.apiKeys[apiKeyHash] = player.PlayerID
+
+	return nil
+}
+
+// GetPlayer gets a single player from the map
+func (mm *MemoryModel) GetPlayer(playerID string) (messages.Player, error) {
+	mm.mutex.RLock()
+	player, found := mm.players[playerID]
+	mm.mutex.RUnlock()
+
+	if !found {
+		mm.logger.Debug(MEMORY_ITEM_NOT_FOUND_ERROR, common.PLAYER_ID_FIELD, playerID)
+		return messages.Player{}, ErrPlayerNotFound
+	}
+
+	return player, nil
+}
+
+// GetPlayerByKey gets the player an API key hash was issued to
+func (mm *MemoryModel) GetPlayerByKey(apiKeyHash string) (messages.Player, error) {
+	mm.mutex.RLock()
+	defer mm.mutex.RUnlock()
+
+	playerID, found := mm.apiKeys[apiKeyHash]
+	if !found {
+		return messages.Player{}, ErrPlayerNotFound
+	}
+
+	player, found := mm.players[playerID]
+	if !found {
+		return messages.Player{}, ErrPlayerNotFound
+	}
+
+	return player, nil
+}
+
+// Close stops the expiry sweeper
+func (mm *MemoryModel) Close() error {
+	mm.closeOnce.Do(func() {
+		close(mm.stopSweep)
+	})
+
+	return nil
+}
+
+// unexported type methods
+// logFor returns the logger for a request to the store
+func (mm *MemoryModel) logFor(ctx context.Context) *slog.Logger {
+	return storeLogger(ctx, mm.logger, MEMORY_STORE)
+}
+
+// sweep removes expired records until Close is called
+func (mm *MemoryModel) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mm.stopSweep:
+			return
+		case now := <-ticker.C:
+			mm.removeExpired(now)
+		}
+	}
+}
+
+func (mm *MemoryModel) removeExpired(now time.Time) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	for questionID, item := range mm.items {
+		if item.expired(now) {
+			delete(mm.items, questionID)
+		}
+	}
+
+	for gameID, mGame := range mm.games {
+		if mGame.expired(now) {
+			delete(mm.games, gameID)
+		}
+	}
+}
+
+// NewMemoryModel creates an in-process trivia store
+func NewMemoryModel() *MemoryModel {
+	logger := slog.Default().With(common.STORE_FIELD, MEMORY_STORE)
+	logger.Info("Creating in-memory store object")
+	memoryModel := new(MemoryModel)
+	memoryModel.logger = logger
+	memoryModel.items = make(map[string]memoryItem)
+	memoryModel.games = make(map[string]memoryGame)
+	memoryModel.players = make(map[string]messages.Player)
+	memoryModel.apiKeys = make(map[string]string)
+	memoryModel.stopSweep = make(chan struct{})
+
+	// Start removing expired records
+	go memoryModel.sweep(MEMORY_SWEEP_INTERVAL)
+
+	return memoryModel
+}
+
+// unexported functions
+// copyGame copies a game so the results of a stored game are not shared with the caller
+func copyGame(game messages.Game) messages.Game {
+	game.Results = append([]messages.GameResult{}, game.Results...)
+	return game
+}
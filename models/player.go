@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	MAX_PLAYER_NAME_LENGTH int = 32
+
+	// API keys are API_KEY_PREFIX followed by API_KEY_BYTES random bytes, hex encoded
+	API_KEY_PREFIX string = "tk_"
+	API_KEY_BYTES  int    = 32
+)
+
+var (
+	// ErrInvalidPlayerRequest is returned when a player cannot be registered with the requested settings
+	ErrInvalidPlayerRequest = errors.New("invalid player request")
+
+	// ErrInvalidAPIKey is returned when an API key is missing or was not issued to a player
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// PlayerModel registers players and authenticates them by API key
+type PlayerModel struct {
+	playerStore PlayerStore
+	logger      *slog.Logger
+}
+
+// RegisterPlayer creates a player and issues the player's API key. The key is only returned here;
+// the data store keeps a hash of the key.
+func (pm *PlayerModel) RegisterPlayer(ctx context.Context, pRequest messages.PlayerRequest) (messages.Player, string,
+	error) {
+	logger := common.LoggerFromContext(ctx, pm.logger)
+
+	name := strings.TrimSpace(pRequest.Name)
+	if len(name) == 0 || len(name) > MAX_PLAYER_NAME_LENGTH {
+		errMsg := fmt.Sprintf("name must be between 1 and %d characters", MAX_PLAYER_NAME_LENGTH)
+		logger.Warn(errMsg)
+		return messages.Player{}, "", fmt.Errorf("%w: %s", ErrInvalidPlayerRequest, errMsg)
+	}
+
+	apiKey, keyErr := newAPIKey()
+	if keyErr != nil {
+		logger.Error("Error creating api key", common.ERROR_FIELD, keyErr)
+		return messages.Player{}, "", keyErr
+	}
+
+	var player messages.Player
+	player.PlayerID = uuid.New().String()
+	player.Name = name
+	player.Created = common.GetFormattedTime(time.Now(), messages.TIMESTAMP_FORMAT)
+
+	insertErr := pm.playerStore.InsertPlayer(player, HashAPIKey(apiKey))
+	if insertErr != nil {
+		logger.Error("Error inserting player", common.PLAYER_ID_FIELD, player.PlayerID, common.ERROR_FIELD,
+			insertErr)
+		return messages.Player{}, "", insertErr
+	}
+
+	return player, apiKey, nil
+}
+
+// GetPlayer returns the player for playerID
+func (pm *PlayerModel) GetPlayer(playerID string) (messages.Player, error) {
+	return pm.playerStore.GetPlayer(playerID)
+}
+
+// AuthenticatePlayer returns the player the API key was issued to
+func (pm *PlayerModel) AuthenticatePlayer(ctx context.Context, apiKey string) (messages.Player, error) {
+	if !strings.HasPrefix(apiKey, API_KEY_PREFIX) {
+		return messages.Player{}, ErrInvalidAPIKey
+	}
+
+	player, getErr := pm.playerStore.GetPlayerByKey(HashAPIKey(apiKey))
+	if errors.Is(getErr, ErrPlayerNotFound) {
+		return messages.Player{}, ErrInvalidAPIKey
+	} else if getErr != nil {
+		common.LoggerFromContext(ctx, pm.logger).Error("Get player error", common.ERROR_FIELD, getErr)
+		return messages.Player{}, getErr
+	}
+
+	return player, nil
+}
+
+// NewPlayerModel creates a player model that keeps players in triviaStore, which must also implement PlayerStore
+func NewPlayerModel(triviaStore TriviaStore) (*PlayerModel, error) {
+	logger := slog.Default()
+	logger.Info("Creating player model object")
+
+	playerStore, ok := triviaStore.(PlayerStore)
+	if !ok {
+		errMsg := "trivia store does not support players"
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+
+	playerModel := new(PlayerModel)
+	playerModel.playerStore = playerStore
+	playerModel.logger = logger
+
+	return playerModel, nil
+}
+
+// HashAPIKey returns the hash kept in the data store in place of the API key
+func HashAPIKey(apiKey string) string {
+	keyHash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(keyHash[:])
+}
+
+// unexported functions
+func newAPIKey() (string, error) {
+	keyBytes := make([]byte, API_KEY_BYTES)
+	_, readErr := rand.Read(keyBytes)
+	if readErr != nil {
+		return "", readErr
+	}
+
+	return API_KEY_PREFIX + hex.EncodeToString(keyBytes), nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/messages"
+	"testing"
+)
+
+func TestRegisterPlayerID(t *testing.T) {
+	playerModel, playerErr := NewPlayerModel(NewMemoryModel())
+	if playerErr != nil {
+		t.Fatalf("NewPlayerModel() error = %v", playerErr)
+	}
+
+	player, _, registerErr := playerModel.RegisterPlayer(context.Background(), messages.PlayerRequest{Name: "alice"})
+	if registerErr != nil {
+		t.Fatalf("RegisterPlayer() error = %v", registerErr)
+	}
+
+	if _, parseErr := uuid.Parse(player.PlayerID); parseErr != nil {
+		t.Errorf("player ID %q is not a full UUID: %v", player.PlayerID, parseErr)
+	}
+}
+
+func TestInsertPlayerExists(t *testing.T) {
+	for storeType, triviaStore := range newTestStores(t) {
+		t.Run(storeType, func(t *testing.T) {
+			playerStore := triviaStore.(PlayerStore)
+			alice := messages.Player{PlayerID: "player-1", Name: "alice"}
+			if insertErr := playerStore.InsertPlayer(alice, "hash-1"); insertErr != nil {
+				t.Fatalf("InsertPlayer(alice) error = %v", insertErr)
+			}
+
+			// A player with the same ID or API key hash does not replace alice
+			tests := []struct {
+				player     messages.Player
+				apiKeyHash string
+			}{
+				{messages.Player{PlayerID: "player-1", Name: "bob"}, "hash-2"},
+				{messages.Player{PlayerID: "player-2", Name: "bob"}, "hash-1"},
+			}
+
+			for _, test := range tests {
+				insertErr := playerStore.InsertPlayer(test.player, test.apiKeyHash)
+				if !errors.Is(insertErr, ErrPlayerExists) {
+					t.Errorf("InsertPlayer(%s, %s) error = %v, want %v", test.player.PlayerID, test.apiKeyHash, insertErr,
+						ErrPlayerExists)
+				}
+			}
+
+			stored, getErr := playerStore.GetPlayerByKey("hash-1")
+			if getErr != nil || stored.PlayerID != alice.PlayerID || stored.Name != alice.Name {
+				t.Errorf("GetPlayerByKey(hash-1) = %+v, %v, want %+v", stored, getErr, alice)
+			}
+
+			if _, getErr := playerStore.GetPlayerByKey("hash-2"); !errors.Is(getErr, ErrPlayerNotFound) {
+				t.Errorf("GetPlayerByKey(hash-2) error = %v, want %v", getErr, ErrPlayerNotFound)
+			}
+			if _, getErr := playerStore.GetPlayer("player-2"); !errors.Is(getErr, ErrPlayerNotFound) {
+				t.Errorf("GetPlayer(player-2) error = %v, want %v", getErr, ErrPlayerNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"github.com/sflewis2970/trivia-api/messages"
+	"strings"
+)
+
+// ErrInvalidResponse is returned when a response is not a valid answer for the type of question
+var ErrInvalidResponse = errors.New("response is not a valid answer for the question")
+
+// QuestionType returns the type of a stored question. Records stored before question types were
+// added are multiple choice.
+func QuestionType(tTable messages.TriviaTable) string {
+	if len(tTable.Type) == 0 {
+		return messages.MULTIPLE_CHOICE
+	}
+
+	return tTable.Type
+}
+
+// AnswerChoices returns the answers a multiple choice question can be answered with
+func AnswerChoices(tTable messages.TriviaTable) []string {
+	var choices []string
+	for _, choice := range tTable.Choices {
+		if choice != messages.MAKE_SELECTION_MSG {
+			choices = append(choices, choice)
+		}
+	}
+
+	if len(choices) == 0 {
+		choices = append(choices, tTable.Answer)
+		choices = append(choices, tTable.Distractors...)
+	}
+
+	return choices
+}
+
+// unexported functions
+// checkResponse validates a response for the type of question and returns how it matched the answer,
+// NO_MATCH when it is incorrect. Only free text responses can match fuzzily.
+// ErrInvalidResponse is returned when the response cannot answer the question:
+//
+//	multiple choice: the response is not one of the choices
+//	boolean: the response is not True or False, in any case
+//	free text: the response is empty
+func checkResponse(tTable messages.TriviaTable, response string, answerMatcher *AnswerMatcher) (string, error) {
+	switch QuestionType(tTable) {
+	case messages.BOOLEAN:
+		response = strings.TrimSpace(response)
+		if !strings.EqualFold(response, messages.TRUE_ANSWER) && !strings.EqualFold(response, messages.FALSE_ANSWER) {
+			return NO_MATCH, ErrInvalidResponse
+		}
+		if strings.EqualFold(response, strings.TrimSpace(tTable.Answer)) {
+			return EXACT_MATCH, nil
+		}
+		return NO_MATCH, nil
+	case messages.FREE_TEXT:
+		if len(strings.TrimSpace(response)) == 0 {
+			return NO_MATCH, ErrInvalidResponse
+		}
+		return answerMatcher.Match(response, tTable.Answer, tTable.Alternates), nil
+	default:
+		for _, choice := range AnswerChoices(tTable) {
+			if response == choice {
+				if response == tTable.Answer {
+					return EXACT_MATCH, nil
+				}
+				return NO_MATCH, nil
+			}
+		}
+		return NO_MATCH, ErrInvalidResponse
+	}
+}
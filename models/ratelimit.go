@@ -0,0 +1,169 @@
+package models
+
+import (
+	"context"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// REDIS_RATE_LIMIT_KEY_PREFIX keeps the token buckets of clients apart
+const REDIS_RATE_LIMIT_KEY_PREFIX string = "ratelimit:"
+
+// TokenBucket holds up to Capacity tokens and is refilled at Rate tokens per second. Each request takes a
+// token, so bursts of up to Capacity requests are allowed while requests average out at Rate.
+type TokenBucket struct {
+	Capacity int
+	Rate     float64
+}
+
+// RateLimitResult is the outcome of taking a token from a client's bucket
+type RateLimitResult struct {
+	// Allowed reports whether a token was taken
+	Allowed bool
+
+	// Limit is the capacity of the bucket and Remaining the tokens left in it
+	Limit     int
+	Remaining int
+
+	// RetryAfter is the time until a token is available, when none was. ResetAfter is the time until the
+	// bucket is full again.
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// RateLimiter takes tokens from the token bucket of each client
+type RateLimiter interface {
+	// Take takes a token from the bucket for key
+	Take(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// MemoryRateLimiter keeps token buckets in memory, limiting requests to a single server
+type MemoryRateLimiter struct {
+	bucket    TokenBucket
+	mutex     sync.Mutex
+	buckets   map[string]*bucketState
+	lastSweep time.Time
+}
+
+// bucketState is the tokens in a bucket when it was last updated
+type bucketState struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Take takes a token from the bucket for key
+func (ml *MemoryRateLimiter) Take(_ context.Context, key string) (RateLimitResult, error) {
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+
+	now := time.Now()
+	ml.sweep(now)
+
+	state, found := ml.buckets[key]
+	if !found {
+		state = &bucketState{tokens: float64(ml.bucket.Capacity), updated: now}
+		ml.buckets[key] = state
+	}
+
+	// Refill the bucket for the time since it was last updated
+	elapsed := now.Sub(state.updated).Seconds()
+	state.tokens = math.Min(float64(ml.bucket.Capacity), state.tokens+elapsed*ml.bucket.Rate)
+	state.updated = now
+
+	allowed := state.tokens >= 1
+	if allowed {
+		state.tokens--
+	}
+
+	return bucketResult(ml.bucket, allowed, state.tokens), nil
+}
+
+// unexported type methods
+// sweep forgets the buckets that have refilled, once every time it takes to refill a bucket
+func (ml *MemoryRateLimiter) sweep(now time.Time) {
+	fillDuration := secondsDuration(float64(ml.bucket.Capacity) / ml.bucket.Rate)
+	if now.Sub(ml.lastSweep) < fillDuration {
+		return
+	}
+
+	for key, state := range ml.buckets {
+		if now.Sub(state.updated) >= fillDuration {
+			delete(ml.buckets, key)
+		}
+	}
+	ml.lastSweep = now
+}
+
+// RedisRateLimiter keeps token buckets in Redis, so requests are limited across every server
+type RedisRateLimiter struct {
+	bucket     TokenBucket
+	redisModel *RedisModel
+}
+
+// Take takes a token from the bucket for key
+func (rl *RedisRateLimiter) Take(ctx context.Context, key string) (RateLimitResult, error) {
+	return rl.redisModel.TakeToken(ctx, REDIS_RATE_LIMIT_KEY_PREFIX+key, rl.bucket)
+}
+
+// NewTokenBucket creates a token bucket allowing requests each period, in bursts of up to burst requests.
+// A burst below 1 is the number of requests.
+func NewTokenBucket(requests int, period time.Duration, burst int) TokenBucket {
+	if burst < 1 {
+		burst = requests
+	}
+
+	return TokenBucket{Capacity: burst, Rate: float64(requests) / period.Seconds()}
+}
+
+// NewMemoryRateLimiter creates a rate limiter keeping buckets in memory
+func NewMemoryRateLimiter(bucket TokenBucket) *MemoryRateLimiter {
+	memoryRateLimiter := new(MemoryRateLimiter)
+	memoryRateLimiter.bucket = bucket
+	memoryRateLimiter.buckets = make(map[string]*bucketState)
+	memoryRateLimiter.lastSweep = time.Now()
+
+	return memoryRateLimiter
+}
+
+// NewRedisRateLimiter creates a rate limiter keeping buckets in redisModel
+func NewRedisRateLimiter(bucket TokenBucket, redisModel *RedisModel) *RedisRateLimiter {
+	redisRateLimiter := new(RedisRateLimiter)
+	redisRateLimiter.bucket = bucket
+	redisRateLimiter.redisModel = redisModel
+
+	return redisRateLimiter
+}
+
+// NewRateLimiter creates the rate limiter selected in config, keeping buckets in memory or in redisModel
+func NewRateLimiter(cfgData *config.CfgData, redisModel *RedisModel) RateLimiter {
+	logger := slog.Default()
+	bucket := NewTokenBucket(cfgData.RateLimitRequests, cfgData.RateLimitPeriod, cfgData.RateLimitBurst)
+	logger.Info("Creating rate limiter", common.STORE_FIELD, cfgData.RateLimitStore, "requests",
+		cfgData.RateLimitRequests, "period", cfgData.RateLimitPeriod, "burst", bucket.Capacity)
+
+	if cfgData.RateLimitStore == REDIS_STORE {
+		return NewRedisRateLimiter(bucket, redisModel)
+	}
+
+	return NewMemoryRateLimiter(bucket)
+}
+
+// unexported functions
+// bucketResult builds the result of taking a token from bucket, leaving tokens in it
+func bucketResult(bucket TokenBucket, allowed bool, tokens float64) RateLimitResult {
+	result := RateLimitResult{Allowed: allowed, Limit: bucket.Capacity, Remaining: int(math.Floor(tokens))}
+	result.ResetAfter = secondsDuration((float64(bucket.Capacity) - tokens) / bucket.Rate)
+	if !allowed {
+		result.RetryAfter = secondsDuration((1 - tokens) / bucket.Rate)
+	}
+
+	return result
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
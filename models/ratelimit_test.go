@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewTokenBucket(t *testing.T) {
+	tests := []struct {
+		requests int
+		period   time.Duration
+		burst    int
+		want     TokenBucket
+	}{
+		{60, time.Minute, 0, TokenBucket{Capacity: 60, Rate: 1}},
+		{60, time.Minute, 10, TokenBucket{Capacity: 10, Rate: 1}},
+		{10, time.Second, 5, TokenBucket{Capacity: 5, Rate: 10}},
+	}
+
+	for _, test := range tests {
+		if got := NewTokenBucket(test.requests, test.period, test.burst); got != test.want {
+			t.Errorf("NewTokenBucket(%d, %s, %d) = %+v, want %+v", test.requests, test.period, test.burst, got,
+				test.want)
+		}
+	}
+}
+
+func TestMemoryRateLimiterBurst(t *testing.T) {
+	rateLimiter := NewMemoryRateLimiter(TokenBucket{Capacity: 3, Rate: 1})
+	ctx := context.Background()
+
+	// A full bucket allows a burst of requests
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, _ := rateLimiter.Take(ctx, "client")
+		if !result.Allowed || result.Limit != 3 || result.Remaining != remaining {
+			t.Fatalf("Take() = %+v, want allowed with %d remaining", result, remaining)
+		}
+	}
+
+	result, _ := rateLimiter.Take(ctx, "client")
+	if result.Allowed || result.Remaining != 0 {
+		t.Errorf("Take() over the burst = %+v, want refused", result)
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Second {
+		t.Errorf("Take() RetryAfter = %s, want up to 1s", result.RetryAfter)
+	}
+	if result.ResetAfter <= 2*time.Second || result.ResetAfter > 3*time.Second {
+		t.Errorf("Take() ResetAfter = %s, want about 3s", result.ResetAfter)
+	}
+
+	// Other clients have buckets of their own
+	if result, _ := rateLimiter.Take(ctx, "other"); !result.Allowed {
+		t.Errorf("Take() for another client = %+v, want allowed", result)
+	}
+}
+
+func TestMemoryRateLimiterRefill(t *testing.T) {
+	rateLimiter := NewMemoryRateLimiter(TokenBucket{Capacity: 2, Rate: 1})
+	ctx := context.Background()
+
+	rateLimiter.Take(ctx, "client")
+	rateLimiter.Take(ctx, "client")
+	if result, _ := rateLimiter.Take(ctx, "client"); result.Allowed {
+		t.Fatalf("Take() from an empty bucket = %+v, want refused", result)
+	}
+
+	// A second later the bucket has refilled by one token
+	rateLimiter.buckets["client"].updated = rateLimiter.buckets["client"].updated.Add(-time.Second)
+	if result, _ := rateLimiter.Take(ctx, "client"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Take() after a second = %+v, want allowed with 0 remaining", result)
+	}
+
+	// The bucket never refills past its capacity
+	rateLimiter.buckets["client"].updated = rateLimiter.buckets["client"].updated.Add(-time.Hour)
+	if result, _ := rateLimiter.Take(ctx, "client"); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Take() after an hour = %+v, want allowed with 1 remaining", result)
+	}
+}
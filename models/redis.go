@@ -3,30 +3,78 @@ package models
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/sflewis2970/trivia-api/common"
 	"github.com/sflewis2970/trivia-api/config"
 	"github.com/sflewis2970/trivia-api/messages"
-	"log"
+	"log/slog"
+	"strconv"
 	"time"
 )
 
 const (
 	// REDIS_TLS_URL Redis Constants
 	REDIS_PASSWORD         string = "REDIS_PASSWORD"
-	REDIS_DB_NAME_MSG      string = "GO_REDIS: "
-	REDIS_CREATE_CACHE_MSG string = "Creating in-memory map to store data..."
+	REDIS_CREATE_CACHE_MSG string = "Creating in-memory map to store data"
+
+	// REDIS_GAME_KEY_PREFIX keeps game keys apart from question keys
+	REDIS_GAME_KEY_PREFIX string = "game:"
+
+	// Players are kept by ID, API key hashes map to the player ID
+	REDIS_PLAYER_KEY_PREFIX  string = "player:"
+	REDIS_API_KEY_KEY_PREFIX string = "apikey:"
+
+	// REDIS_POOL_KEY_PREFIX keeps the lists of prefetched questions apart
+	REDIS_POOL_KEY_PREFIX string = "pool:"
 )
 
 const (
-	REDIS_MARSHAL_ERROR        string = "Marshaling error...: "
-	REDIS_UNMARSHAL_ERROR      string = "Unmarshalling error...: "
-	REDIS_INSERT_ERROR         string = "Insert error...: "
-	REDIS_ITEM_NOT_FOUND_ERROR string = "Item not found...: "
-	REDIS_GET_ERROR            string = "Get error...: "
-	REDIS_DELETE_ERROR         string = "Delete error...: "
-	REDIS_PING_ERROR           string = "Error pinging in-memory cache server...: "
+	REDIS_MARSHAL_ERROR        string = "Marshaling error"
+	REDIS_UNMARSHAL_ERROR      string = "Unmarshalling error"
+	REDIS_INSERT_ERROR         string = "Insert error"
+	REDIS_ITEM_NOT_FOUND_ERROR string = "Item not found"
+	REDIS_GET_ERROR            string = "Get error"
+	REDIS_UPDATE_ERROR         string = "Update error"
+	REDIS_DELETE_ERROR         string = "Delete error"
+	REDIS_PING_ERROR           string = "Error pinging in-memory cache server"
 )
 
+// takeTokenScript refills a token bucket for the time since it was last updated and takes a token from it,
+// as a single operation. Times are in milliseconds. It returns whether a token was taken, the whole tokens
+// left, the time until a token is available and the time until the bucket is full.
+var takeTokenScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+	tokens = capacity
+	updated = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+local retryAfter = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retryAfter = math.ceil((1 - tokens) / rate)
+end
+
+local resetAfter = math.ceil((capacity - tokens) / rate)
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], resetAfter + 1000)
+
+return {allowed, math.floor(tokens), retryAfter, resetAfter}
+`)
+
 type Redis struct {
 	TLS_URL  string `json:"tls_url"`
 	URL      string `json:"host"`
@@ -36,44 +84,40 @@ type Redis struct {
 
 type RedisModel struct {
 	cfgData  *config.CfgData
+	logger   *slog.Logger
 	memCache *redis.Client
 }
 
 var redisModel *RedisModel
 
 // Ping database server, since this is local to the server make sure the object for storing data is created
-func (rm *RedisModel) Ping() error {
-	ctx := context.Background()
-
+func (rm *RedisModel) Ping(ctx context.Context) error {
 	statusCmd := rm.memCache.Ping(ctx)
 	pingErr := statusCmd.Err()
 	if pingErr != nil {
-		log.Print(REDIS_DB_NAME_MSG+REDIS_PING_ERROR, pingErr)
+		rm.logFor(ctx).Error(REDIS_PING_ERROR, common.ERROR_FIELD, pingErr)
 		return pingErr
 	}
 
 	return nil
 }
 
-// Insert a single record into table
-func (rm *RedisModel) Insert(trivia messages.Trivia) error {
-	ctx := context.Background()
+// Insert a single record into table, the record expires after ttl
+func (rm *RedisModel) Insert(ctx context.Context, trivia messages.Trivia, ttl time.Duration) error {
+	logger := rm.logFor(ctx).With(common.QUESTION_ID_FIELD, trivia.QuestionID)
 
-	// var tTable messages.TriviaTable
-	// tTable.Question = trivia.Question
-	// tTable.Category = trivia.Category
-	// tTable.Answer = trivia.Answer
+	tTable := NewTriviaTable(trivia)
 
-	byteStream, marshalErr := json.Marshal(messages.Trivia{})
+	byteStream, marshalErr := json.Marshal(tTable)
 	if marshalErr != nil {
-		log.Print(REDIS_DB_NAME_MSG+REDIS_MARSHAL_ERROR, marshalErr)
+		logger.Error(REDIS_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
 		return marshalErr
 	}
 
-	// log.Print("Adding a new record to map, ID: ", trivia.QuestionID)
-	setErr := rm.memCache.Set(ctx, "", byteStream, time.Duration(0)).Err()
+	logger.Debug("Adding a new record to map")
+	setErr := rm.memCache.Set(ctx, trivia.QuestionID, byteStream, ttl).Err()
 	if setErr != nil {
-		log.Print(REDIS_DB_NAME_MSG+REDIS_INSERT_ERROR, setErr)
+		logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, setErr)
 		return setErr
 	}
 
@@ -81,69 +125,481 @@ func (rm *RedisModel) Insert(trivia messages.Trivia) error {
 }
 
 // Get a single record from table
-func (rm *RedisModel) Get(questionID string) (messages.TriviaTable, error) {
-	log.Print("Getting record from the map, with ID: ", questionID)
+func (rm *RedisModel) Get(ctx context.Context, questionID string) (messages.TriviaTable, error) {
+	logger := rm.logFor(ctx).With(common.QUESTION_ID_FIELD, questionID)
+	logger.Debug("Getting record from the map")
 
 	var tTable messages.TriviaTable
-	ctx := context.Background()
 	getResult, getErr := rm.memCache.Get(ctx, questionID).Result()
 	if getErr == redis.Nil {
-		log.Print(REDIS_DB_NAME_MSG + REDIS_ITEM_NOT_FOUND_ERROR)
-		return messages.TriviaTable{}, nil
+		logger.Debug(REDIS_ITEM_NOT_FOUND_ERROR)
+		return messages.TriviaTable{}, ErrItemNotFound
 	} else if getErr != nil {
-		log.Print(REDIS_DB_NAME_MSG+REDIS_GET_ERROR, getErr)
+		logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, getErr)
 		return messages.TriviaTable{}, getErr
 	} else {
 		unmarshalErr := json.Unmarshal([]byte(getResult), &tTable)
 		if unmarshalErr != nil {
-			log.Print(REDIS_DB_NAME_MSG+REDIS_UNMARSHAL_ERROR, unmarshalErr)
+			logger.Error(REDIS_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
 			return messages.TriviaTable{}, unmarshalErr
 		}
+
+		// Records stored before the question ID was kept are keyed by it
+		if len(tTable.QuestionID) == 0 {
+			tTable.QuestionID = questionID
+		}
 	}
 
 	return tTable, nil
 }
 
-// Update a single record in table
-func (rm *RedisModel) Update(updatedRec messages.Trivia) {
-	log.Println("Updating record in the map")
-
-	ctx := context.Background()
+// Update a single record in table, keeping the remaining TTL of the record
+func (rm *RedisModel) Update(ctx context.Context, updatedRec messages.Trivia) error {
+	logger := rm.logFor(ctx).With(common.QUESTION_ID_FIELD, updatedRec.QuestionID)
+	logger.Debug("Updating record in the map")
 
-	var tTable messages.TriviaTable
-	// tTable.Question = updatedRec.Question
-	// tTable.Category = updatedRec.Category
-	// tTable.Answer = updatedRec.Answer
+	byteStream, marshalErr := json.Marshal(NewTriviaTable(updatedRec))
+	if marshalErr != nil {
+		logger.Error(REDIS_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
 
 	// Send update message to cache
-	rm.memCache.Set(ctx, "", tTable, 0)
+	setErr := rm.memCache.Set(ctx, updatedRec.QuestionID, byteStream, redis.KeepTTL).Err()
+	if setErr != nil {
+		logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, setErr)
+		return setErr
+	}
+
+	return nil
 }
 
 // Delete a single record from table
-func (rm *RedisModel) Delete(questionID string) error {
-	log.Print("Deleting record with ID: ", questionID)
+func (rm *RedisModel) Delete(ctx context.Context, questionID string) error {
+	logger := rm.logFor(ctx).With(common.QUESTION_ID_FIELD, questionID)
+	logger.Debug("Deleting record from the map")
 
-	// Delete the record from map
-	ctx := context.Background()
-	delErr := rm.memCache.Del(ctx, questionID).Err()
+	// Delete the record from map, the count tells whether this request removed it
+	delCount, delErr := rm.memCache.Del(ctx, questionID).Result()
 	if delErr != nil {
-		log.Print(REDIS_DB_NAME_MSG+REDIS_DELETE_ERROR, delErr)
+		logger.Error(REDIS_DELETE_ERROR, common.ERROR_FIELD, delErr)
 		return delErr
 	}
 
+	if delCount == 0 {
+		logger.Debug(REDIS_ITEM_NOT_FOUND_ERROR)
+		return ErrItemNotFound
+	}
+
+	return nil
+}
+
+// InsertGame adds a game session that expires after ttl. The game is not set when the game ID is taken.
+func (rm *RedisModel) InsertGame(game messages.Game, ttl time.Duration) error {
+	logger := rm.logger.With(common.GAME_ID_FIELD, game.GameID)
+	logger.Debug("Saving game in the map")
+
+	byteStream, marshalErr := json.Marshal(game)
+	if marshalErr != nil {
+		logger.Error(REDIS_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	ctx := context.Background()
+	gameSet, setErr := rm.memCache.SetNX(ctx, REDIS_GAME_KEY_PREFIX+game.GameID, byteStream, ttl).Result()
+	if setErr != nil {
+		logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, setErr)
+		return setErr
+	}
+	if !gameSet {
+		logger.Debug("Game already in the map")
+		return ErrGameExists
+	}
+
+	return nil
+}
+
+// GetGame gets a single game session
+func (rm *RedisModel) GetGame(gameID string) (messages.Game, error) {
+	rm.logger.Debug("Getting game from the map", common.GAME_ID_FIELD, gameID)
+
+	var game messages.Game
+	ctx := context.Background()
+	getResult, getErr := rm.memCache.Get(ctx, REDIS_GAME_KEY_PREFIX+gameID).Result()
+	if getErr == redis.Nil {
+		rm.logger.Debug(REDIS_ITEM_NOT_FOUND_ERROR)
+		return messages.Game{}, ErrGameNotFound
+	} else if getErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, getErr)
+		return messages.Game{}, getErr
+	}
+
+	unmarshalErr := json.Unmarshal([]byte(getResult), &game)
+	if unmarshalErr != nil {
+		rm.logger.Error(REDIS_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
+		return messages.Game{}, unmarshalErr
+	}
+
+	return game, nil
+}
+
+// UpdateGame replaces a game session, keeping the remaining TTL of the game. The game key is watched so the
+// game is not replaced when another request saves it first.
+func (rm *RedisModel) UpdateGame(game messages.Game) error {
+	rm.logger.Debug("Updating game in the map", common.GAME_ID_FIELD, game.GameID)
+
+	ctx := context.Background()
+	gameKey := REDIS_GAME_KEY_PREFIX + game.GameID
+	txErr := rm.memCache.Watch(ctx, func(tx *redis.Tx) error {
+		var storedGame messages.Game
+		getResult, getErr := tx.Get(ctx, gameKey).Result()
+		if getErr == redis.Nil {
+			return ErrGameNotFound
+		} else if getErr != nil {
+			return getErr
+		}
+
+		unmarshalErr := json.Unmarshal([]byte(getResult), &storedGame)
+		if unmarshalErr != nil {
+			return unmarshalErr
+		}
+
+		if storedGame.Version != game.Version {
+			return ErrGameChanged
+		}
+
+		game.Version++
+		byteStream, marshalErr := json.Marshal(game)
+		if marshalErr != nil {
+			return marshalErr
+		}
+
+		_, setErr := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, gameKey, byteStream, redis.KeepTTL)
+			return nil
+		})
+		return setErr
+	}, gameKey)
+
+	switch {
+	case errors.Is(txErr, redis.TxFailedErr), errors.Is(txErr, ErrGameChanged):
+		rm.logger.Debug("Game was changed by another request", common.GAME_ID_FIELD, game.GameID)
+		return ErrGameChanged
+	case errors.Is(txErr, ErrGameNotFound):
+		rm.logger.Debug(REDIS_ITEM_NOT_FOUND_ERROR)
+		return ErrGameNotFound
+	case txErr != nil:
+		rm.logger.Error(REDIS_UPDATE_ERROR, common.ERROR_FIELD, txErr)
+		return txErr
+	}
+
+	return nil
+}
+
+// DeleteGame deletes a single game session
+func (rm *RedisModel) DeleteGame(gameID string) error {
+	deleteErr := rm.Delete(context.Background(), REDIS_GAME_KEY_PREFIX+gameID)
+	if errors.Is(deleteErr, ErrItemNotFound) {
+		return nil
+	}
+
+	return deleteErr
+}
+
+// InsertPlayer adds a player along with the hash of the player's API key. Players do not expire.
+// Neither key is set when the player ID or API key hash is already taken.
+func (rm *RedisModel) InsertPlayer(player messages.Player, apiKeyHash string) error {
+	logger := rm.logger.With(common.PLAYER_ID_FIELD, player.PlayerID)
+	logger.Debug("Adding a new player to the map")
+
+	byteStream, marshalErr := json.Marshal(player)
+	if marshalErr != nil {
+		logger.Error(REDIS_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	ctx := context.Background()
+	playerKey := REDIS_PLAYER_KEY_PREFIX + player.PlayerID
+	playerSet, playerErr := rm.memCache.SetNX(ctx, playerKey, byteStream, 0).Result()
+	if playerErr != nil {
+		logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, playerErr)
+		return playerErr
+	}
+	if !playerSet {
+		logger.Debug("Player already in the map")
+		return ErrPlayerExists
+	}
+
+	// The player is removed again when the API key cannot be set, so the player ID is not left taken
+	keySet, keyErr := rm.memCache.SetNX(ctx, REDIS_API_KEY_KEY_PREFIX+apiKeyHash, player.PlayerID, 0).Result()
+	if keyErr != nil || !keySet {
+		rm.memCache.Del(ctx, playerKey)
+		if keyErr != nil {
+			logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, keyErr)
+			return keyErr
+		}
+
+		logger.Debug("API key already in the map")
+		return ErrPlayerExists
+	}
+
+	return nil
+}
+
+// GetPlayer gets a single player
+func (rm *RedisModel) GetPlayer(playerID string) (messages.Player, error) {
+	var player messages.Player
+	ctx := context.Background()
+	getResult, getErr := rm.memCache.Get(ctx, REDIS_PLAYER_KEY_PREFIX+playerID).Result()
+	if getErr == redis.Nil {
+		rm.logger.Debug(REDIS_ITEM_NOT_FOUND_ERROR)
+		return messages.Player{}, ErrPlayerNotFound
+	} else if getErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, getErr)
+		return messages.Player{}, getErr
+	}
+
+	unmarshalErr := json.Unmarshal([]byte(getResult), &player)
+	if unmarshalErr != nil {
+		rm.logger.Error(REDIS_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
+		return messages.Player{}, unmarshalErr
+	}
+
+	return player, nil
+}
+
+// GetPlayerByKey gets the player an API key hash was issued to
+func (rm *RedisModel) GetPlayerByKey(apiKeyHash string) (messages.Player, error) {
+	ctx := context.Background()
+	playerID, getErr := rm.memCache.Get(ctx, REDIS_API_KEY_KEY_PREFIX+apiKeyHash).Result()
+	if getErr == redis.Nil {
+		return messages.Player{}, ErrPlayerNotFound
+	} else if getErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, getErr)
+		return messages.Player{}, getErr
+	}
+
+	return rm.GetPlayer(playerID)
+}
+
+// IncrementScore adds score to member in every sorted set in keys.
+// Keys with a TTL have their expiry refreshed.
+func (rm *RedisModel) IncrementScore(keys []ScoreKey, member string, score float64) error {
+	ctx := context.Background()
+
+	pipeline := rm.memCache.TxPipeline()
+	for _, scoreKey := range keys {
+		pipeline.ZIncrBy(ctx, scoreKey.Key, score, member)
+		if scoreKey.TTL > 0 {
+			pipeline.Expire(ctx, scoreKey.Key, scoreKey.TTL)
+		}
+	}
+
+	_, execErr := pipeline.Exec(ctx)
+	if execErr != nil {
+		rm.logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, execErr)
+		return execErr
+	}
+
+	return nil
+}
+
+// TopScores returns the count highest scoring members of a sorted set, highest first
+func (rm *RedisModel) TopScores(key string, count int64) ([]MemberScore, error) {
+	ctx := context.Background()
+
+	zScores, rangeErr := rm.memCache.ZRevRangeWithScores(ctx, key, 0, count-1).Result()
+	if rangeErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, rangeErr)
+		return nil, rangeErr
+	}
+
+	scores := make([]MemberScore, 0, len(zScores))
+	for _, zScore := range zScores {
+		member, _ := zScore.Member.(string)
+		scores = append(scores, MemberScore{Member: member, Score: zScore.Score})
+	}
+
+	return scores, nil
+}
+
+// ScoreRank returns the zero based rank and score of member in a sorted set, highest score first.
+// ErrItemNotFound is returned when member is not in the set.
+func (rm *RedisModel) ScoreRank(key string, member string) (int64, float64, error) {
+	ctx := context.Background()
+
+	rank, rankErr := rm.memCache.ZRevRank(ctx, key, member).Result()
+	if rankErr == redis.Nil {
+		return 0, 0, ErrItemNotFound
+	} else if rankErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, rankErr)
+		return 0, 0, rankErr
+	}
+
+	score, scoreErr := rm.memCache.ZScore(ctx, key, member).Result()
+	if scoreErr == redis.Nil {
+		return 0, 0, ErrItemNotFound
+	} else if scoreErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, scoreErr)
+		return 0, 0, scoreErr
+	}
+
+	return rank, score, nil
+}
+
+// AddTimedMember adds member to a sorted set scored by the time it was added, or refreshes its time.
+// Members added before the window are removed and the set expires once nothing was added for the window.
+func (rm *RedisModel) AddTimedMember(key string, member string, addedAt time.Time, window time.Duration) error {
+	ctx := context.Background()
+	oldest := strconv.FormatInt(addedAt.Add(-window).Unix(), 10)
+
+	pipeline := rm.memCache.TxPipeline()
+	pipeline.ZAdd(ctx, key, &redis.Z{Score: float64(addedAt.Unix()), Member: member})
+	pipeline.ZRemRangeByScore(ctx, key, "-inf", "("+oldest)
+	pipeline.Expire(ctx, key, window)
+
+	_, execErr := pipeline.Exec(ctx)
+	if execErr != nil {
+		rm.logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, execErr)
+		return execErr
+	}
+
 	return nil
 }
 
-func NewRedisModel() *RedisModel {
+// MemberTime returns the time member was added to a sorted set by AddTimedMember.
+// ErrItemNotFound is returned when member is not in the set.
+func (rm *RedisModel) MemberTime(key string, member string) (time.Time, error) {
+	ctx := context.Background()
+
+	score, scoreErr := rm.memCache.ZScore(ctx, key, member).Result()
+	if scoreErr == redis.Nil {
+		return time.Time{}, ErrItemNotFound
+	} else if scoreErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, scoreErr)
+		return time.Time{}, scoreErr
+	}
+
+	return time.Unix(int64(score), 0), nil
+}
+
+// DeleteSet deletes a sorted set and returns the number of members it held
+func (rm *RedisModel) DeleteSet(key string) (int64, error) {
+	ctx := context.Background()
+
+	pipeline := rm.memCache.TxPipeline()
+	cardCmd := pipeline.ZCard(ctx, key)
+	pipeline.Del(ctx, key)
+
+	_, execErr := pipeline.Exec(ctx)
+	if execErr != nil {
+		rm.logger.Error(REDIS_DELETE_ERROR, common.ERROR_FIELD, execErr)
+		return 0, execErr
+	}
+
+	return cardCmd.Val(), nil
+}
+
+// PushQuestion adds a prefetched question to the end of the list for the pool key. The list is shared
+// by every server using the same Redis.
+func (rm *RedisModel) PushQuestion(poolKey string, trivia messages.Trivia) error {
+	byteStream, marshalErr := json.Marshal(trivia)
+	if marshalErr != nil {
+		rm.logger.Error(REDIS_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	ctx := context.Background()
+	pushErr := rm.memCache.RPush(ctx, REDIS_POOL_KEY_PREFIX+poolKey, byteStream).Err()
+	if pushErr != nil {
+		rm.logger.Error(REDIS_INSERT_ERROR, common.ERROR_FIELD, pushErr)
+		return pushErr
+	}
+
+	return nil
+}
+
+// PopQuestion takes the first prefetched question from the list for the pool key
+func (rm *RedisModel) PopQuestion(poolKey string) (messages.Trivia, bool, error) {
+	ctx := context.Background()
+	popResult, popErr := rm.memCache.LPop(ctx, REDIS_POOL_KEY_PREFIX+poolKey).Result()
+	if popErr == redis.Nil {
+		return messages.Trivia{}, false, nil
+	} else if popErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, popErr)
+		return messages.Trivia{}, false, popErr
+	}
+
+	var trivia messages.Trivia
+	unmarshalErr := json.Unmarshal([]byte(popResult), &trivia)
+	if unmarshalErr != nil {
+		rm.logger.Error(REDIS_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
+		return messages.Trivia{}, false, unmarshalErr
+	}
+
+	return trivia, true, nil
+}
+
+// QuestionCount returns the number of prefetched questions in the list for the pool key
+func (rm *RedisModel) QuestionCount(poolKey string) (int, error) {
+	ctx := context.Background()
+	count, lenErr := rm.memCache.LLen(ctx, REDIS_POOL_KEY_PREFIX+poolKey).Result()
+	if lenErr != nil {
+		rm.logger.Error(REDIS_GET_ERROR, common.ERROR_FIELD, lenErr)
+		return 0, lenErr
+	}
+
+	return int(count), nil
+}
+
+// TakeToken takes a token from the token bucket kept at key. Buckets expire once they are full again.
+func (rm *RedisModel) TakeToken(ctx context.Context, key string, bucket TokenBucket) (RateLimitResult, error) {
+	ratePerMs := bucket.Rate / float64(time.Second/time.Millisecond)
+	values, scriptErr := takeTokenScript.Run(ctx, rm.memCache, []string{key}, bucket.Capacity, ratePerMs,
+		time.Now().UnixMilli()).Int64Slice()
+	if scriptErr != nil {
+		rm.logFor(ctx).Error(REDIS_GET_ERROR, common.ERROR_FIELD, scriptErr)
+		return RateLimitResult{}, scriptErr
+	}
+	if len(values) != 4 {
+		rm.logFor(ctx).Error(REDIS_UNMARSHAL_ERROR, "values", values)
+		return RateLimitResult{}, fmt.Errorf("rate limit script returned %d values", len(values))
+	}
+
+	var result RateLimitResult
+	result.Allowed = values[0] == 1
+	result.Limit = bucket.Capacity
+	result.Remaining = int(values[1])
+	result.RetryAfter = time.Duration(values[2]) * time.Millisecond
+	result.ResetAfter = time.Duration(values[3]) * time.Millisecond
+
+	return result, nil
+}
+
+// Close the connection pool of the Redis client
+func (rm *RedisModel) Close() error {
+	rm.logger.Info("Closing redis client")
+	return rm.memCache.Close()
+}
+
+// unexported type methods
+// logFor returns the logger of the request ctx belongs to, tagged as the Redis store
+func (rm *RedisModel) logFor(ctx context.Context) *slog.Logger {
+	return storeLogger(ctx, rm.logger, REDIS_STORE)
+}
+
+func NewRedisModel(logger *slog.Logger) *RedisModel {
 	// Initialize go-cache in-memory cache model
-	log.Print("Creating goRedis dbModel object...")
+	logger = logger.With(common.STORE_FIELD, REDIS_STORE)
+	logger.Info("Creating goRedis dbModel object")
 	redisModel = new(RedisModel)
+	redisModel.logger = logger
 
 	// Get config data
 	redisModel.cfgData = config.NewConfig().LoadCfgData()
 
 	// Define go-redis cache settings
-	log.Print(REDIS_DB_NAME_MSG + REDIS_CREATE_CACHE_MSG)
+	logger.Info(REDIS_CREATE_CACHE_MSG)
 
 	// Define connection variables
 	var redisOptions *redis.Options
@@ -151,8 +607,8 @@ func NewRedisModel() *RedisModel {
 	// The config package handles reading the environment variables and parsing the url.
 	// Once the external packages access the values, the environment has already been taken
 	// care of.
-	redisAddr := redisModel.cfgData.RedisURL + ":" + redisModel.cfgData.RedisPort
-	log.Print("The redis address is...: ", redisAddr)
+	redisAddr := redisModel.cfgData.RedisURL + ":" + strconv.Itoa(redisModel.cfgData.RedisPort)
+	logger.Info("Connecting to redis", "address", redisAddr)
 
 	redisOptions = &redis.Options{
 		Addr:     redisAddr, // redis Server Address,
@@ -162,6 +618,7 @@ func NewRedisModel() *RedisModel {
 
 	// Create go-redis in-memory cache
 	redisModel.memCache = redis.NewClient(redisOptions)
+	redisModel.memCache.AddHook(redisMetricsHook{})
 
 	return redisModel
 }
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"github.com/sflewis2970/trivia-api/common"
+	"time"
+)
+
+// REDIS_PIPELINE_OPERATION is the operation pipelines and transactions are timed as
+const REDIS_PIPELINE_OPERATION string = "pipeline"
+
+// Redis metrics, labelled by command name
+var (
+	redisDuration = common.Metrics.NewHistogramVec("trivia_redis_operation_duration_seconds",
+		"Time taken by Redis operations.", common.LATENCY_BUCKETS, "operation")
+	redisErrors = common.Metrics.NewCounterVec("trivia_redis_errors_total",
+		"Redis commands that failed.", "operation")
+)
+
+// redisStartKey is the context key holding the time a Redis operation started
+type redisStartKey struct{}
+
+// redisMetricsHook times the operations sent to Redis and counts the commands that fail.
+// A missing key is not a failure.
+type redisMetricsHook struct{}
+
+func (redisMetricsHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (redisMetricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observeRedisOperation(ctx, cmd.Name(), []redis.Cmder{cmd})
+	return nil
+}
+
+func (redisMetricsHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (redisMetricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	observeRedisOperation(ctx, REDIS_PIPELINE_OPERATION, cmds)
+	return nil
+}
+
+// unexported functions
+func observeRedisOperation(ctx context.Context, operation string, cmds []redis.Cmder) {
+	if started, found := ctx.Value(redisStartKey{}).(time.Time); found {
+		redisDuration.ObserveDuration(started, operation)
+	}
+
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			redisErrors.Inc(cmd.Name())
+		}
+	}
+}
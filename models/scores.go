@@ -0,0 +1,258 @@
+package models
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MEMORY_SCORE_SWEEP_INTERVAL is how often expired sets are removed from a memory score store
+const MEMORY_SCORE_SWEEP_INTERVAL time.Duration = time.Minute
+
+// ScoreKey is a sorted set to increment along with the time the set should be kept for
+type ScoreKey struct {
+	Key string
+	TTL time.Duration
+}
+
+// MemberScore is a member of a sorted set and its score
+type MemberScore struct {
+	Member string
+	Score  float64
+}
+
+// ScoreStore keeps sorted sets of members ordered by score. Leaderboards and seen questions are kept in a
+// score store.
+type ScoreStore interface {
+	// IncrementScore adds score to member in every sorted set in keys. Keys with a TTL have their expiry
+	// refreshed.
+	IncrementScore(keys []ScoreKey, member string, score float64) error
+
+	// TopScores returns the count highest scoring members of a sorted set, highest first
+	TopScores(key string, count int64) ([]MemberScore, error)
+
+	// ScoreRank returns the zero based rank and score of member in a sorted set, highest score first.
+	// ErrItemNotFound is returned when member is not in the set.
+	ScoreRank(key string, member string) (int64, float64, error)
+
+	// AddTimedMember adds member to a sorted set scored by the time it was added, or refreshes its time.
+	// Members added before the window are removed and the set expires once nothing was added for the window.
+	AddTimedMember(key string, member string, addedAt time.Time, window time.Duration) error
+
+	// MemberTime returns the time member was added to a sorted set by AddTimedMember.
+	// ErrItemNotFound is returned when member is not in the set.
+	MemberTime(key string, member string) (time.Time, error)
+
+	// DeleteSet deletes a sorted set and returns the number of members it held
+	DeleteSet(key string) (int64, error)
+}
+
+// memorySet is a sorted set kept in memory. Sets without an expiry never expire.
+type memorySet struct {
+	scores    map[string]float64
+	expiresAt time.Time
+}
+
+// expired reports whether the set has expired at time now
+func (ms *memorySet) expired(now time.Time) bool {
+	return !ms.expiresAt.IsZero() && !now.Before(ms.expiresAt)
+}
+
+// MemoryScoreStore is an in-process score store, safe for concurrent use. Scores are lost when the server
+// stops and are not shared with other servers.
+type MemoryScoreStore struct {
+	mutex     sync.Mutex
+	sets      map[string]*memorySet
+	lastSweep time.Time
+}
+
+// IncrementScore adds score to member in every sorted set in keys
+func (mss *MemoryScoreStore) IncrementScore(keys []ScoreKey, member string, score float64) error {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	now := time.Now()
+	mss.sweep(now)
+
+	for _, scoreKey := range keys {
+		set := mss.writeSet(scoreKey.Key, now)
+		set.scores[member] += score
+		if scoreKey.TTL > 0 {
+			set.expiresAt = now.Add(scoreKey.TTL)
+		}
+	}
+
+	return nil
+}
+
+// TopScores returns the count highest scoring members of a sorted set, highest first
+func (mss *MemoryScoreStore) TopScores(key string, count int64) ([]MemberScore, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	scores := mss.sortedScores(key)
+	if int64(len(scores)) > count {
+		scores = scores[:count]
+	}
+
+	return scores, nil
+}
+
+// ScoreRank returns the zero based rank and score of member in a sorted set, highest score first
+func (mss *MemoryScoreStore) ScoreRank(key string, member string) (int64, float64, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	for rank, memberScore := range mss.sortedScores(key) {
+		if memberScore.Member == member {
+			return int64(rank), memberScore.Score, nil
+		}
+	}
+
+	return 0, 0, ErrItemNotFound
+}
+
+// AddTimedMember adds member to a sorted set scored by the time it was added, or refreshes its time
+func (mss *MemoryScoreStore) AddTimedMember(key string, member string, addedAt time.Time, window time.Duration) error {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	now := time.Now()
+	mss.sweep(now)
+
+	set := mss.writeSet(key, now)
+	set.scores[member] = float64(addedAt.Unix())
+
+	oldest := float64(addedAt.Add(-window).Unix())
+	for setMember, score := range set.scores {
+		if score < oldest {
+			delete(set.scores, setMember)
+		}
+	}
+	set.expiresAt = now.Add(window)
+
+	return nil
+}
+
+// MemberTime returns the time member was added to a sorted set by AddTimedMember
+func (mss *MemoryScoreStore) MemberTime(key string, member string) (time.Time, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	set := mss.readSet(key)
+	if set == nil {
+		return time.Time{}, ErrItemNotFound
+	}
+
+	score, found := set.scores[member]
+	if !found {
+		return time.Time{}, ErrItemNotFound
+	}
+
+	return time.Unix(int64(score), 0), nil
+}
+
+// DeleteSet deletes a sorted set and returns the number of members it held
+func (mss *MemoryScoreStore) DeleteSet(key string) (int64, error) {
+	mss.mutex.Lock()
+	defer mss.mutex.Unlock()
+
+	set := mss.readSet(key)
+	if set == nil {
+		return 0, nil
+	}
+	delete(mss.sets, key)
+
+	return int64(len(set.scores)), nil
+}
+
+// unexported type methods
+// readSet returns the set for key, nil when there is none or it has expired
+func (mss *MemoryScoreStore) readSet(key string) *memorySet {
+	set, found := mss.sets[key]
+	if !found || set.expired(time.Now()) {
+		return nil
+	}
+
+	return set
+}
+
+// writeSet returns the set for key, replacing a set that has expired
+func (mss *MemoryScoreStore) writeSet(key string, now time.Time) *memorySet {
+	set, found := mss.sets[key]
+	if !found || set.expired(now) {
+		set = &memorySet{scores: make(map[string]float64)}
+		mss.sets[key] = set
+	}
+
+	return set
+}
+
+// sortedScores returns the members of the set for key ordered as Redis orders them, highest score first
+// and members with the same score in reverse order
+func (mss *MemoryScoreStore) sortedScores(key string) []MemberScore {
+	set := mss.readSet(key)
+	if set == nil {
+		return []MemberScore{}
+	}
+
+	scores := make([]MemberScore, 0, len(set.scores))
+	for member, score := range set.scores {
+		scores = append(scores, MemberScore{Member: member, Score: score})
+	}
+
+	sort.Slice(scores, func(idx1, idx2 int) bool {
+		if scores[idx1].Score != scores[idx2].Score {
+			return scores[idx1].Score > scores[idx2].Score
+		}
+		return scores[idx1].Member > scores[idx2].Member
+	})
+
+	return scores
+}
+
+// sweep removes the sets that have expired, once every sweep interval
+func (mss *MemoryScoreStore) sweep(now time.Time) {
+	if now.Sub(mss.lastSweep) < MEMORY_SCORE_SWEEP_INTERVAL {
+		return
+	}
+
+	for key, set := range mss.sets {
+		if set.expired(now) {
+			delete(mss.sets, key)
+		}
+	}
+	mss.lastSweep = now
+}
+
+// NewMemoryScoreStore creates an empty in-process score store
+func NewMemoryScoreStore() *MemoryScoreStore {
+	memoryScoreStore := new(MemoryScoreStore)
+	memoryScoreStore.sets = make(map[string]*memorySet)
+	memoryScoreStore.lastSweep = time.Now()
+
+	return memoryScoreStore
+}
+
+// NewScoreStore creates the score store selected in config, keeping scores in memory or in redisModel.
+// When none is selected scores are kept in Redis if it is the trivia store, and in memory otherwise.
+func NewScoreStore(scoreStoreType string, triviaStoreType string, redisModel *RedisModel) ScoreStore {
+	if ScoreStoreType(scoreStoreType, triviaStoreType) == REDIS_STORE {
+		return redisModel
+	}
+
+	return NewMemoryScoreStore()
+}
+
+// ScoreStoreType returns the type of score store used for the score store type selected in config
+func ScoreStoreType(scoreStoreType string, triviaStoreType string) string {
+	if len(scoreStoreType) > 0 {
+		return scoreStoreType
+	}
+
+	if len(triviaStoreType) == 0 || triviaStoreType == REDIS_STORE {
+		return REDIS_STORE
+	}
+
+	return MEMORY_STORE
+}
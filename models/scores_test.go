@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryScoreStoreTopScores(t *testing.T) {
+	scoreStore := NewMemoryScoreStore()
+	keys := []ScoreKey{{Key: "alltime"}, {Key: "daily", TTL: time.Hour}}
+
+	for _, memberScore := range []MemberScore{{"alice", 3}, {"bob", 5}, {"carol", 3}, {"alice", 1}} {
+		if incrErr := scoreStore.IncrementScore(keys, memberScore.Member, memberScore.Score); incrErr != nil {
+			t.Fatalf("IncrementScore() error = %v", incrErr)
+		}
+	}
+
+	want := []MemberScore{{"bob", 5}, {"alice", 4}, {"carol", 3}}
+	for _, key := range []string{"alltime", "daily"} {
+		scores, topErr := scoreStore.TopScores(key, 10)
+		if topErr != nil {
+			t.Fatalf("TopScores(%q) error = %v", key, topErr)
+		}
+		if !reflect.DeepEqual(scores, want) {
+			t.Errorf("TopScores(%q) = %v, want %v", key, scores, want)
+		}
+	}
+
+	scores, _ := scoreStore.TopScores("alltime", 2)
+	if len(scores) != 2 {
+		t.Errorf("TopScores(2) returned %d scores, want 2", len(scores))
+	}
+
+	rank, score, rankErr := scoreStore.ScoreRank("alltime", "carol")
+	if rankErr != nil || rank != 2 || score != 3 {
+		t.Errorf("ScoreRank(carol) = %d, %v, %v, want 2, 3, nil", rank, score, rankErr)
+	}
+
+	if _, _, rankErr := scoreStore.ScoreRank("alltime", "dave"); !errors.Is(rankErr, ErrItemNotFound) {
+		t.Errorf("ScoreRank(dave) error = %v, want %v", rankErr, ErrItemNotFound)
+	}
+}
+
+func TestMemoryScoreStoreTiedScores(t *testing.T) {
+	scoreStore := NewMemoryScoreStore()
+	for _, member := range []string{"alice", "carol", "bob"} {
+		scoreStore.IncrementScore([]ScoreKey{{Key: "board"}}, member, 1)
+	}
+
+	// Members with the same score are ordered in reverse, as Redis orders them
+	scores, _ := scoreStore.TopScores("board", 10)
+	want := []MemberScore{{"carol", 1}, {"bob", 1}, {"alice", 1}}
+	if !reflect.DeepEqual(scores, want) {
+		t.Errorf("TopScores() = %v, want %v", scores, want)
+	}
+}
+
+func TestMemoryScoreStoreExpiry(t *testing.T) {
+	scoreStore := NewMemoryScoreStore()
+	scoreStore.IncrementScore([]ScoreKey{{Key: "daily", TTL: time.Hour}}, "alice", 1)
+
+	// Expire the set
+	scoreStore.sets["daily"].expiresAt = time.Now().Add(-time.Second)
+
+	scores, _ := scoreStore.TopScores("daily", 10)
+	if len(scores) != 0 {
+		t.Errorf("TopScores() after expiry = %v, want none", scores)
+	}
+
+	scoreStore.IncrementScore([]ScoreKey{{Key: "daily", TTL: time.Hour}}, "bob", 2)
+	scores, _ = scoreStore.TopScores("daily", 10)
+	want := []MemberScore{{"bob", 2}}
+	if !reflect.DeepEqual(scores, want) {
+		t.Errorf("TopScores() after new score = %v, want %v", scores, want)
+	}
+}
+
+func TestMemoryScoreStoreTimedMembers(t *testing.T) {
+	scoreStore := NewMemoryScoreStore()
+	now := time.Now().Truncate(time.Second)
+	window := time.Hour
+
+	scoreStore.AddTimedMember("seen", "old", now.Add(-2*window), window)
+	scoreStore.AddTimedMember("seen", "new", now, window)
+
+	addedAt, timeErr := scoreStore.MemberTime("seen", "new")
+	if timeErr != nil || !addedAt.Equal(now) {
+		t.Errorf("MemberTime(new) = %v, %v, want %v, nil", addedAt, timeErr, now)
+	}
+
+	if _, timeErr := scoreStore.MemberTime("seen", "old"); !errors.Is(timeErr, ErrItemNotFound) {
+		t.Errorf("MemberTime(old) error = %v, want %v", timeErr, ErrItemNotFound)
+	}
+
+	count, deleteErr := scoreStore.DeleteSet("seen")
+	if deleteErr != nil || count != 1 {
+		t.Errorf("DeleteSet() = %d, %v, want 1, nil", count, deleteErr)
+	}
+
+	if _, timeErr := scoreStore.MemberTime("seen", "new"); !errors.Is(timeErr, ErrItemNotFound) {
+		t.Errorf("MemberTime(new) after delete error = %v, want %v", timeErr, ErrItemNotFound)
+	}
+}
+
+func TestScoreStoreType(t *testing.T) {
+	tests := []struct {
+		scoreStoreType  string
+		triviaStoreType string
+		want            string
+	}{
+		{"", "", REDIS_STORE},
+		{"", REDIS_STORE, REDIS_STORE},
+		{"", MEMORY_STORE, MEMORY_STORE},
+		{"", "sql", MEMORY_STORE},
+		{REDIS_STORE, "sql", REDIS_STORE},
+		{MEMORY_STORE, REDIS_STORE, MEMORY_STORE},
+	}
+
+	for _, test := range tests {
+		got := ScoreStoreType(test.scoreStoreType, test.triviaStoreType)
+		if got != test.want {
+			t.Errorf("ScoreStoreType(%q, %q) = %q, want %q", test.scoreStoreType, test.triviaStoreType, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/cespare/xxhash/v2"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// SEEN_KEY_PREFIX keeps the seen question sets of players apart
+const SEEN_KEY_PREFIX string = "seen:"
+
+// SeenModel remembers the questions each player has been asked, so a player is not asked the same question
+// again within the seen question window. Questions are kept in a sorted set for each player in a score
+// store, keyed by a hash of the normalized question text so the same question from different providers
+// matches.
+type SeenModel struct {
+	scoreStore ScoreStore
+	window     time.Duration
+	retries    int
+	logger     *slog.Logger
+}
+
+// Enabled reports whether seen questions are remembered. A window of 0 turns it off.
+func (sm *SeenModel) Enabled() bool {
+	return sm.window > 0
+}
+
+// Retries returns the number of times a question the player has seen may be replaced by another question
+func (sm *SeenModel) Retries() int {
+	return sm.retries
+}
+
+// Seen reports whether the player has been asked the question within the window.
+// Questions without a player are never seen.
+func (sm *SeenModel) Seen(playerID string, question string) (bool, error) {
+	if !sm.Enabled() || len(playerID) == 0 {
+		return false, nil
+	}
+
+	seenAt, timeErr := sm.scoreStore.MemberTime(seenKey(playerID), QuestionHash(question))
+	if errors.Is(timeErr, ErrItemNotFound) {
+		return false, nil
+	} else if timeErr != nil {
+		return false, timeErr
+	}
+
+	return time.Since(seenAt) < sm.window, nil
+}
+
+// MarkSeen records that the player has been asked the question
+func (sm *SeenModel) MarkSeen(ctx context.Context, playerID string, question string) error {
+	if !sm.Enabled() || len(playerID) == 0 {
+		return nil
+	}
+
+	addErr := sm.scoreStore.AddTimedMember(seenKey(playerID), QuestionHash(question), time.Now(), sm.window)
+	if addErr != nil {
+		common.LoggerFromContext(ctx, sm.logger).Error("Error recording seen question", common.PLAYER_ID_FIELD,
+			playerID, common.ERROR_FIELD, addErr)
+		return addErr
+	}
+
+	return nil
+}
+
+// Reset forgets every question the player has been asked and returns the number of questions forgotten
+func (sm *SeenModel) Reset(ctx context.Context, playerID string) (int, error) {
+	forgotten, deleteErr := sm.scoreStore.DeleteSet(seenKey(playerID))
+	if deleteErr != nil {
+		common.LoggerFromContext(ctx, sm.logger).Error("Error resetting seen questions", common.PLAYER_ID_FIELD,
+			playerID, common.ERROR_FIELD, deleteErr)
+		return 0, deleteErr
+	}
+
+	return int(forgotten), nil
+}
+
+// QuestionHash returns the hash a question is remembered by, the same for questions that only differ in
+// case, punctuation or spacing
+func QuestionHash(question string) string {
+	return strconv.FormatUint(xxhash.Sum64String(NormalizeAnswer(question)), 16)
+}
+
+// NewSeenModel creates a seen question model that keeps seen questions in scoreStore for the window set in
+// config
+func NewSeenModel(cfgData *config.CfgData, scoreStore ScoreStore) *SeenModel {
+	logger := slog.Default()
+	logger.Info("Creating seen question model object")
+	seenModel := new(SeenModel)
+	seenModel.logger = logger
+	seenModel.scoreStore = scoreStore
+	seenModel.window = cfgData.SeenQuestionWindow
+	seenModel.retries = cfgData.SeenQuestionRetries
+
+	return seenModel
+}
+
+// unexported functions
+func seenKey(playerID string) string {
+	return SEEN_KEY_PREFIX + playerID
+}
@@ -0,0 +1,680 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	// database/sql drivers
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// Supported database/sql drivers
+	SQLITE_DRIVER   string = "sqlite3"
+	POSTGRES_DRIVER string = "postgres"
+
+	// DEFAULT_SQL_DSN is used with the SQLite driver when no DSN is configured
+	DEFAULT_SQL_DSN string = "trivia.db"
+)
+
+const (
+	SQL_OPEN_ERROR           string = "Open error"
+	SQL_MIGRATION_ERROR      string = "Migration error"
+	SQL_MARSHAL_ERROR        string = "Marshaling error"
+	SQL_UNMARSHAL_ERROR      string = "Unmarshalling error"
+	SQL_INSERT_ERROR         string = "Insert error"
+	SQL_ITEM_NOT_FOUND_ERROR string = "Item not found"
+	SQL_GET_ERROR            string = "Get error"
+	SQL_UPDATE_ERROR         string = "Update error"
+	SQL_DELETE_ERROR         string = "Delete error"
+	SQL_PING_ERROR           string = "Error pinging database server"
+)
+
+// sqlMigrations are applied in order at startup. Once released, a migration must not be
+// changed; add a new entry to the end of the list instead.
+var sqlMigrations = []string{
+	// 1: questions waiting for an answer, and the history of every issued question and answer
+	`CREATE TABLE IF NOT EXISTS active_questions (
+		question_id        TEXT PRIMARY KEY,
+		question           TEXT NOT NULL,
+		category           TEXT NOT NULL,
+		difficulty         TEXT NOT NULL,
+		answer             TEXT NOT NULL,
+		choices            TEXT NOT NULL,
+		question_timestamp TEXT NOT NULL,
+		expires_at         BIGINT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS question_history (
+		question_id        TEXT PRIMARY KEY,
+		question           TEXT NOT NULL,
+		category           TEXT NOT NULL,
+		difficulty         TEXT NOT NULL,
+		answer             TEXT NOT NULL,
+		choices            TEXT NOT NULL,
+		issued_at          BIGINT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS answer_history (
+		question_id        TEXT NOT NULL,
+		response           TEXT NOT NULL,
+		correct            BOOLEAN NOT NULL,
+		answered_at        BIGINT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS answer_history_question_id ON answer_history (question_id);`,
+
+	// 2: game sessions
+	`CREATE TABLE IF NOT EXISTS games (
+		game_id            TEXT PRIMARY KEY,
+		game               TEXT NOT NULL,
+		expires_at         BIGINT NOT NULL
+	);`,
+
+	// 3: time each question was issued, in unix milliseconds
+	`ALTER TABLE active_questions ADD COLUMN issued_at BIGINT NOT NULL DEFAULT 0;`,
+
+	// 4: registered players, and the player each question and answer belongs to
+	`CREATE TABLE IF NOT EXISTS players (
+		player_id          TEXT PRIMARY KEY,
+		name               TEXT NOT NULL,
+		api_key_hash       TEXT NOT NULL UNIQUE,
+		created            TEXT NOT NULL
+	);
+	ALTER TABLE active_questions ADD COLUMN player_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE question_history ADD COLUMN player_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE answer_history ADD COLUMN player_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS answer_history_player_id ON answer_history (player_id);`,
+
+	// 5: question types, and the incorrect answers offered with multiple choice questions
+	`ALTER TABLE active_questions ADD COLUMN question_type TEXT NOT NULL DEFAULT '';
+	ALTER TABLE active_questions ADD COLUMN distractors TEXT NOT NULL DEFAULT '[]';
+	ALTER TABLE question_history ADD COLUMN question_type TEXT NOT NULL DEFAULT '';`,
+
+	// 6: alternate answers accepted for free text questions
+	`ALTER TABLE active_questions ADD COLUMN alternates TEXT NOT NULL DEFAULT '[]';`,
+
+	// 7: game versions, incremented each time a game is saved
+	`ALTER TABLE games ADD COLUMN version INTEGER NOT NULL DEFAULT 0;`,
+
+	// 8: question history keyed by a history ID, so a question ID issued twice does not fail the insert.
+	// History times are kept in unix milliseconds like active_questions.issued_at.
+	`CREATE TABLE question_history_new (
+		history_id         TEXT PRIMARY KEY,
+		question_id        TEXT NOT NULL,
+		question           TEXT NOT NULL,
+		category           TEXT NOT NULL,
+		difficulty         TEXT NOT NULL,
+		answer             TEXT NOT NULL,
+		choices            TEXT NOT NULL,
+		issued_at          BIGINT NOT NULL,
+		player_id          TEXT NOT NULL DEFAULT '',
+		question_type      TEXT NOT NULL DEFAULT ''
+	);
+	INSERT INTO question_history_new (history_id, question_id, question, category, difficulty, answer, choices,
+		issued_at, player_id, question_type)
+		SELECT question_id, question_id, question, category, difficulty, answer, choices, issued_at * 1000,
+		player_id, question_type FROM question_history;
+	DROP TABLE question_history;
+	ALTER TABLE question_history_new RENAME TO question_history;
+	CREATE INDEX IF NOT EXISTS question_history_question_id ON question_history (question_id);
+	UPDATE answer_history SET answered_at = answered_at * 1000;`,
+}
+
+// AnswerRecorder is implemented by stores that keep a history of submitted answers
+type AnswerRecorder interface {
+	RecordAnswer(questionID string, playerID string, aResponse messages.AnswerResponse) error
+}
+
+// SQLModel is a database/sql trivia store that also keeps question and answer history
+type SQLModel struct {
+	db         *sql.DB
+	driverName string
+	logger     *slog.Logger
+}
+
+// Ping database server
+func (sm *SQLModel) Ping(ctx context.Context) error {
+	pingErr := sm.db.PingContext(ctx)
+	if pingErr != nil {
+		sm.logFor(ctx).Error(SQL_PING_ERROR, common.ERROR_FIELD, pingErr)
+		return pingErr
+	}
+
+	return nil
+}
+
+// Insert a single record into the active questions and question history tables.
+// The active record expires after ttl. A question is added to the history each time it is issued.
+func (sm *SQLModel) Insert(ctx context.Context, trivia messages.Trivia, ttl time.Duration) error {
+	logger := sm.logFor(ctx).With(common.QUESTION_ID_FIELD, trivia.QuestionID)
+	logger.Debug("Adding a new record")
+
+	choices, marshalErr := json.Marshal(trivia.Choices)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	distractors, marshalErr := json.Marshal(trivia.Distractors)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	alternates, marshalErr := json.Marshal(trivia.Alternates)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	now := time.Now()
+	expiresAt := int64(0)
+	if ttl > 0 {
+		expiresAt = now.Add(ttl).Unix()
+	}
+
+	tx, txErr := sm.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		logger.Error(SQL_INSERT_ERROR, common.ERROR_FIELD, txErr)
+		return txErr
+	}
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+
+	// Remove active questions that were never answered
+	_, sweepErr := tx.ExecContext(ctx, sm.rebind("DELETE FROM active_questions WHERE expires_at > 0 AND expires_at <= ?"),
+		now.Unix())
+	if sweepErr != nil {
+		logger.Error(SQL_DELETE_ERROR, common.ERROR_FIELD, sweepErr)
+		return sweepErr
+	}
+
+	_, insertErr := tx.ExecContext(ctx, sm.rebind(`INSERT INTO active_questions
+		(question_id, question, category, difficulty, answer, choices, question_timestamp, expires_at, issued_at,
+		player_id, question_type, distractors, alternates) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		trivia.QuestionID, trivia.Question, trivia.Category, trivia.Difficulty, trivia.Answer, string(choices),
+		trivia.Timestamp, expiresAt, unixMilli(trivia.IssuedAt), trivia.PlayerID, trivia.Type, string(distractors),
+		string(alternates))
+	if insertErr != nil {
+		logger.Error(SQL_INSERT_ERROR, common.ERROR_FIELD, insertErr)
+		return insertErr
+	}
+
+	_, insertErr = tx.ExecContext(ctx, sm.rebind(`INSERT INTO question_history
+		(history_id, question_id, question, category, difficulty, answer, choices, issued_at, player_id,
+		question_type) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		uuid.New().String(), trivia.QuestionID, trivia.Question, trivia.Category, trivia.Difficulty, trivia.Answer,
+		string(choices), now.UnixMilli(), trivia.PlayerID, trivia.Type)
+	if insertErr != nil {
+		logger.Error(SQL_INSERT_ERROR, common.ERROR_FIELD, insertErr)
+		return insertErr
+	}
+
+	return tx.Commit()
+}
+
+// Get a single record from the active questions table
+func (sm *SQLModel) Get(ctx context.Context, questionID string) (messages.TriviaTable, error) {
+	logger := sm.logFor(ctx).With(common.QUESTION_ID_FIELD, questionID)
+	logger.Debug("Getting record")
+
+	var tTable messages.TriviaTable
+	var choices string
+	var distractors string
+	var alternates string
+	var issuedAt int64
+
+	row := sm.db.QueryRowContext(ctx, sm.rebind(`SELECT question_id, question, category, difficulty, answer, choices,
+		question_timestamp, issued_at, player_id, question_type, distractors, alternates FROM active_questions
+		WHERE question_id = ? AND (expires_at = 0 OR expires_at > ?)`),
+		questionID, time.Now().Unix())
+	scanErr := row.Scan(&tTable.QuestionID, &tTable.Question, &tTable.Category, &tTable.Difficulty, &tTable.Answer,
+		&choices, &tTable.Timestamp, &issuedAt, &tTable.PlayerID, &tTable.Type, &distractors, &alternates)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		logger.Debug(SQL_ITEM_NOT_FOUND_ERROR)
+		return messages.TriviaTable{}, ErrItemNotFound
+	} else if scanErr != nil {
+		logger.Error(SQL_GET_ERROR, common.ERROR_FIELD, scanErr)
+		return messages.TriviaTable{}, scanErr
+	}
+
+	unmarshalErr := json.Unmarshal([]byte(choices), &tTable.Choices)
+	if unmarshalErr != nil {
+		logger.Error(SQL_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
+		return messages.TriviaTable{}, unmarshalErr
+	}
+
+	unmarshalErr = json.Unmarshal([]byte(distractors), &tTable.Distractors)
+	if unmarshalErr != nil {
+		logger.Error(SQL_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
+		return messages.TriviaTable{}, unmarshalErr
+	}
+
+	unmarshalErr = json.Unmarshal([]byte(alternates), &tTable.Alternates)
+	if unmarshalErr != nil {
+		logger.Error(SQL_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
+		return messages.TriviaTable{}, unmarshalErr
+	}
+
+	if issuedAt > 0 {
+		tTable.IssuedAt = time.UnixMilli(issuedAt)
+	}
+
+	return tTable, nil
+}
+
+// Update a single record in the active questions table, keeping its expiry
+func (sm *SQLModel) Update(ctx context.Context, updatedRec messages.Trivia) error {
+	logger := sm.logFor(ctx).With(common.QUESTION_ID_FIELD, updatedRec.QuestionID)
+	logger.Debug("Updating record")
+
+	choices, marshalErr := json.Marshal(updatedRec.Choices)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	distractors, marshalErr := json.Marshal(updatedRec.Distractors)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	alternates, marshalErr := json.Marshal(updatedRec.Alternates)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	result, updateErr := sm.db.ExecContext(ctx, sm.rebind(`UPDATE active_questions
+		SET question = ?, category = ?, difficulty = ?, answer = ?, choices = ?, question_timestamp = ?, issued_at = ?,
+		player_id = ?, question_type = ?, distractors = ?, alternates = ? WHERE question_id = ?`),
+		updatedRec.Question, updatedRec.Category, updatedRec.Difficulty, updatedRec.Answer, string(choices),
+		updatedRec.Timestamp, unixMilli(updatedRec.IssuedAt), updatedRec.PlayerID, updatedRec.Type,
+		string(distractors), string(alternates), updatedRec.QuestionID)
+	if updateErr != nil {
+		logger.Error(SQL_UPDATE_ERROR, common.ERROR_FIELD, updateErr)
+		return updateErr
+	}
+
+	rowCount, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		logger.Error(SQL_UPDATE_ERROR, common.ERROR_FIELD, rowsErr)
+		return rowsErr
+	}
+
+	if rowCount == 0 {
+		logger.Debug(SQL_ITEM_NOT_FOUND_ERROR)
+		return ErrItemNotFound
+	}
+
+	return nil
+}
+
+// Delete a single record from the active questions table. The question history is kept.
+func (sm *SQLModel) Delete(ctx context.Context, questionID string) error {
+	logger := sm.logFor(ctx).With(common.QUESTION_ID_FIELD, questionID)
+	logger.Debug("Deleting record")
+
+	result, deleteErr := sm.db.ExecContext(ctx, sm.rebind("DELETE FROM active_questions WHERE question_id = ?"),
+		questionID)
+	if deleteErr != nil {
+		logger.Error(SQL_DELETE_ERROR, common.ERROR_FIELD, deleteErr)
+		return deleteErr
+	}
+
+	rowCount, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		logger.Error(SQL_DELETE_ERROR, common.ERROR_FIELD, rowsErr)
+		return rowsErr
+	}
+
+	if rowCount == 0 {
+		logger.Debug(SQL_ITEM_NOT_FOUND_ERROR)
+		return ErrItemNotFound
+	}
+
+	return nil
+}
+
+// RecordAnswer adds a submitted answer to the answer history table
+func (sm *SQLModel) RecordAnswer(questionID string, playerID string, aResponse messages.AnswerResponse) error {
+	logger := sm.logger.With(common.QUESTION_ID_FIELD, questionID)
+	logger.Debug("Recording answer")
+
+	_, insertErr := sm.db.Exec(sm.rebind(`INSERT INTO answer_history
+		(question_id, response, correct, answered_at, player_id) VALUES (?, ?, ?, ?, ?)`),
+		questionID, aResponse.Response, aResponse.Correct, time.Now().UnixMilli(), playerID)
+	if insertErr != nil {
+		logger.Error(SQL_INSERT_ERROR, common.ERROR_FIELD, insertErr)
+		return insertErr
+	}
+
+	return nil
+}
+
+// InsertGame adds a game session that expires after ttl
+func (sm *SQLModel) InsertGame(game messages.Game, ttl time.Duration) error {
+	logger := sm.logger.With(common.GAME_ID_FIELD, game.GameID)
+	logger.Debug("Adding a new game")
+
+	byteStream, marshalErr := json.Marshal(game)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	expiresAt := int64(0)
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).Unix()
+	}
+
+	result, insertErr := sm.db.Exec(sm.rebind(`INSERT INTO games (game_id, game, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING`), game.GameID, string(byteStream), expiresAt)
+	if insertErr != nil {
+		logger.Error(SQL_INSERT_ERROR, common.ERROR_FIELD, insertErr)
+		return insertErr
+	}
+
+	return insertedRow(result, ErrGameExists, logger)
+}
+
+// GetGame gets a single game session
+func (sm *SQLModel) GetGame(gameID string) (messages.Game, error) {
+	logger := sm.logger.With(common.GAME_ID_FIELD, gameID)
+	logger.Debug("Getting game")
+
+	var byteStream string
+	var version int
+	row := sm.db.QueryRow(sm.rebind(`SELECT game, version FROM games
+		WHERE game_id = ? AND (expires_at = 0 OR expires_at > ?)`), gameID, time.Now().Unix())
+	scanErr := row.Scan(&byteStream, &version)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		logger.Debug(SQL_ITEM_NOT_FOUND_ERROR)
+		return messages.Game{}, ErrGameNotFound
+	} else if scanErr != nil {
+		logger.Error(SQL_GET_ERROR, common.ERROR_FIELD, scanErr)
+		return messages.Game{}, scanErr
+	}
+
+	var game messages.Game
+	unmarshalErr := json.Unmarshal([]byte(byteStream), &game)
+	if unmarshalErr != nil {
+		logger.Error(SQL_UNMARSHAL_ERROR, common.ERROR_FIELD, unmarshalErr)
+		return messages.Game{}, unmarshalErr
+	}
+	game.Version = version
+
+	return game, nil
+}
+
+// UpdateGame replaces a game session, keeping its expiry. The game is only replaced when it still has the
+// version it was read with.
+func (sm *SQLModel) UpdateGame(game messages.Game) error {
+	logger := sm.logger.With(common.GAME_ID_FIELD, game.GameID)
+	logger.Debug("Updating game")
+
+	version := game.Version
+	game.Version++
+	byteStream, marshalErr := json.Marshal(game)
+	if marshalErr != nil {
+		logger.Error(SQL_MARSHAL_ERROR, common.ERROR_FIELD, marshalErr)
+		return marshalErr
+	}
+
+	result, updateErr := sm.db.Exec(sm.rebind("UPDATE games SET game = ?, version = ? WHERE game_id = ? AND version = ?"),
+		string(byteStream), game.Version, game.GameID, version)
+	if updateErr != nil {
+		logger.Error(SQL_UPDATE_ERROR, common.ERROR_FIELD, updateErr)
+		return updateErr
+	}
+
+	rowCount, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		logger.Error(SQL_UPDATE_ERROR, common.ERROR_FIELD, rowsErr)
+		return rowsErr
+	}
+
+	if rowCount > 0 {
+		return nil
+	}
+
+	// Nothing was updated because the game is gone or was saved by another request
+	var gameCount int
+	countErr := sm.db.QueryRow(sm.rebind("SELECT COUNT(*) FROM games WHERE game_id = ?"), game.GameID).Scan(&gameCount)
+	if countErr != nil {
+		logger.Error(SQL_GET_ERROR, common.ERROR_FIELD, countErr)
+		return countErr
+	}
+
+	if gameCount == 0 {
+		logger.Debug(SQL_ITEM_NOT_FOUND_ERROR)
+		return ErrGameNotFound
+	}
+
+	logger.Debug("Game was changed by another request")
+	return ErrGameChanged
+}
+
+// DeleteGame deletes a single game session
+func (sm *SQLModel) DeleteGame(gameID string) error {
+	logger := sm.logger.With(common.GAME_ID_FIELD, gameID)
+	logger.Debug("Deleting game")
+
+	_, deleteErr := sm.db.Exec(sm.rebind("DELETE FROM games WHERE game_id = ?"), gameID)
+	if deleteErr != nil {
+		logger.Error(SQL_DELETE_ERROR, common.ERROR_FIELD, deleteErr)
+		return deleteErr
+	}
+
+	return nil
+}
+
+// InsertPlayer adds a player along with the hash of the player's API key
+func (sm *SQLModel) InsertPlayer(player messages.Player, apiKeyHash string) error {
+	logger := sm.logger.With(common.PLAYER_ID_FIELD, player.PlayerID)
+	logger.Debug("Adding a new player")
+
+	// Players already holding the player ID or API key hash are left in place
+	result, insertErr := sm.db.Exec(sm.rebind(`INSERT INTO players (player_id, name, api_key_hash, created)
+		VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING`), player.PlayerID, player.Name, apiKeyHash, player.Created)
+	if insertErr != nil {
+		logger.Error(SQL_INSERT_ERROR, common.ERROR_FIELD, insertErr)
+		return insertErr
+	}
+
+	return insertedRow(result, ErrPlayerExists, logger)
+}
+
+// GetPlayer gets a single player
+func (sm *SQLModel) GetPlayer(playerID string) (messages.Player, error) {
+	row := sm.db.QueryRow(sm.rebind("SELECT player_id, name, created FROM players WHERE player_id = ?"), playerID)
+	return sm.scanPlayer(row)
+}
+
+// GetPlayerByKey gets the player an API key hash was issued to
+func (sm *SQLModel) GetPlayerByKey(apiKeyHash string) (messages.Player, error) {
+	row := sm.db.QueryRow(sm.rebind("SELECT player_id, name, created FROM players WHERE api_key_hash = ?"),
+		apiKeyHash)
+	return sm.scanPlayer(row)
+}
+
+// Close the database connection pool
+func (sm *SQLModel) Close() error {
+	return sm.db.Close()
+}
+
+// unexported type methods
+// logFor returns the logger for a request to the store
+func (sm *SQLModel) logFor(ctx context.Context) *slog.Logger {
+	return storeLogger(ctx, sm.logger, SQL_STORE)
+}
+
+// rebind converts '?' placeholders into the placeholder style used by the driver
+func (sm *SQLModel) rebind(query string) string {
+	if sm.driverName != POSTGRES_DRIVER {
+		return query
+	}
+
+	var builder strings.Builder
+	paramIdx := 0
+	for _, char := range query {
+		if char == '?' {
+			paramIdx++
+			builder.WriteString("$" + strconv.Itoa(paramIdx))
+		} else {
+			builder.WriteRune(char)
+		}
+	}
+
+	return builder.String()
+}
+
+// scanPlayer reads a player from row, returning ErrPlayerNotFound when there is no player
+func (sm *SQLModel) scanPlayer(row *sql.Row) (messages.Player, error) {
+	var player messages.Player
+	scanErr := row.Scan(&player.PlayerID, &player.Name, &player.Created)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return messages.Player{}, ErrPlayerNotFound
+	} else if scanErr != nil {
+		sm.logger.Error(SQL_GET_ERROR, common.ERROR_FIELD, scanErr)
+		return messages.Player{}, scanErr
+	}
+
+	return player, nil
+}
+
+// migrate applies the migrations that have not been applied to the database yet
+func (sm *SQLModel) migrate() error {
+	_, createErr := sm.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)")
+	if createErr != nil {
+		return createErr
+	}
+
+	var version int
+	scanErr := sm.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if scanErr != nil {
+		return scanErr
+	}
+
+	for idx := version; idx < len(sqlMigrations); idx++ {
+		sm.logger.Info("Applying migration", "migration", idx+1)
+
+		tx, txErr := sm.db.Begin()
+		if txErr != nil {
+			return txErr
+		}
+
+		for _, statement := range strings.Split(sqlMigrations[idx], ";") {
+			if len(strings.TrimSpace(statement)) == 0 {
+				continue
+			}
+
+			_, execErr := tx.Exec(statement)
+			if execErr != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migration %d: %w", idx+1, execErr)
+			}
+		}
+
+		_, execErr := tx.Exec(sm.rebind("INSERT INTO schema_migrations (version) VALUES (?)"), idx+1)
+		if execErr != nil {
+			_ = tx.Rollback()
+			return execErr
+		}
+
+		commitErr := tx.Commit()
+		if commitErr != nil {
+			return commitErr
+		}
+	}
+
+	return nil
+}
+
+// NewSQLModel opens the database and applies any pending schema migrations.
+// When no driver is configured SQLite is used.
+func NewSQLModel(driverName string, dsn string) (*SQLModel, error) {
+	logger := slog.Default().With(common.STORE_FIELD, SQL_STORE)
+	logger.Info("Creating SQL dbModel object")
+
+	if len(driverName) == 0 {
+		driverName = SQLITE_DRIVER
+	}
+
+	if driverName != SQLITE_DRIVER && driverName != POSTGRES_DRIVER {
+		errMsg := fmt.Sprintf("sql driver %s is invalid", driverName)
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+
+	if len(dsn) == 0 {
+		if driverName != SQLITE_DRIVER {
+			errMsg := "sql dsn is not set"
+			logger.Error(errMsg)
+			return nil, errors.New(errMsg)
+		}
+		dsn = DEFAULT_SQL_DSN
+	}
+
+	db, openErr := sql.Open(driverName, dsn)
+	if openErr != nil {
+		logger.Error(SQL_OPEN_ERROR, common.ERROR_FIELD, openErr)
+		return nil, openErr
+	}
+
+	// SQLite only supports a single writer
+	if driverName == SQLITE_DRIVER {
+		db.SetMaxOpenConns(1)
+	}
+
+	sqlModel := new(SQLModel)
+	sqlModel.db = db
+	sqlModel.driverName = driverName
+	sqlModel.logger = logger
+
+	migrateErr := sqlModel.migrate()
+	if migrateErr != nil {
+		logger.Error(SQL_MIGRATION_ERROR, common.ERROR_FIELD, migrateErr)
+		_ = db.Close()
+		return nil, migrateErr
+	}
+
+	return sqlModel, nil
+}
+
+// unexported functions
+// unixMilli converts t to unix milliseconds, the zero time is stored as 0
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixMilli()
+}
+
+// insertedRow returns existsErr when an insert ignoring conflicts did not insert a row
+func insertedRow(result sql.Result, existsErr error, logger *slog.Logger) error {
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		logger.Error(SQL_INSERT_ERROR, common.ERROR_FIELD, rowsErr)
+		return rowsErr
+	}
+
+	if rowsAffected == 0 {
+		logger.Debug(existsErr.Error())
+		return existsErr
+	}
+
+	return nil
+}
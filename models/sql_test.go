@@ -0,0 +1,194 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/sflewis2970/trivia-api/messages"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestSQLModel opens a sqlite store in a file that is removed when the test ends
+func newTestSQLModel(t *testing.T, path string) *SQLModel {
+	sqlModel, sqlErr := NewSQLModel(SQLITE_DRIVER, path)
+	if sqlErr != nil {
+		t.Fatalf("NewSQLModel() error = %v", sqlErr)
+	}
+	t.Cleanup(func() { sqlModel.Close() })
+
+	return sqlModel
+}
+
+// schemaVersion returns the last migration applied to the store
+func schemaVersion(t *testing.T, sqlModel *SQLModel) int {
+	var version int
+	scanErr := sqlModel.db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if scanErr != nil {
+		t.Fatalf("schema version error = %v", scanErr)
+	}
+
+	return version
+}
+
+func TestSQLMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trivia.db")
+	sqlModel := newTestSQLModel(t, path)
+	if version := schemaVersion(t, sqlModel); version != len(sqlMigrations) {
+		t.Errorf("schema version = %d, want %d", version, len(sqlMigrations))
+	}
+	sqlModel.Close()
+
+	// Opening the store again does not apply the migrations again
+	reopened := newTestSQLModel(t, path)
+	if version := schemaVersion(t, reopened); version != len(sqlMigrations) {
+		t.Errorf("schema version after reopening = %d, want %d", version, len(sqlMigrations))
+	}
+}
+
+func TestSQLMigrateQuestionHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trivia.db")
+
+	// A store created before question history was keyed by history ID, with times in seconds
+	allMigrations := sqlMigrations
+	sqlMigrations = allMigrations[:7]
+	oldModel, sqlErr := NewSQLModel(SQLITE_DRIVER, path)
+	sqlMigrations = allMigrations
+	if sqlErr != nil {
+		t.Fatalf("NewSQLModel() error = %v", sqlErr)
+	}
+
+	issuedAt := time.Now().Truncate(time.Second)
+	_, insertErr := oldModel.db.Exec(`INSERT INTO question_history (question_id, question, category, difficulty,
+		answer, choices, issued_at) VALUES ('question-1', 'What is 2 + 2?', 'math', 'easy', '4', '[]', ?)`,
+		issuedAt.Unix())
+	if insertErr != nil {
+		t.Fatalf("insert question history error = %v", insertErr)
+	}
+	_, insertErr = oldModel.db.Exec(`INSERT INTO answer_history (question_id, response, correct, answered_at)
+		VALUES ('question-1', '4', true, ?)`, issuedAt.Unix())
+	if insertErr != nil {
+		t.Fatalf("insert answer history error = %v", insertErr)
+	}
+	oldModel.Close()
+
+	sqlModel := newTestSQLModel(t, path)
+
+	var historyID string
+	var historyIssuedAt, answeredAt int64
+	scanErr := sqlModel.db.QueryRow("SELECT history_id, issued_at FROM question_history WHERE question_id = ?",
+		"question-1").Scan(&historyID, &historyIssuedAt)
+	if scanErr != nil {
+		t.Fatalf("question history error = %v", scanErr)
+	}
+	if historyID != "question-1" || historyIssuedAt != issuedAt.UnixMilli() {
+		t.Errorf("question history = %q, %d, want %q, %d", historyID, historyIssuedAt, "question-1",
+			issuedAt.UnixMilli())
+	}
+
+	scanErr = sqlModel.db.QueryRow("SELECT answered_at FROM answer_history WHERE question_id = ?", "question-1").
+		Scan(&answeredAt)
+	if scanErr != nil {
+		t.Fatalf("answer history error = %v", scanErr)
+	}
+	if answeredAt != issuedAt.UnixMilli() {
+		t.Errorf("answered at = %d, want %d", answeredAt, issuedAt.UnixMilli())
+	}
+}
+
+func TestSQLInsertGet(t *testing.T) {
+	sqlModel := newTestSQLModel(t, filepath.Join(t.TempDir(), "trivia.db"))
+	ctx := context.Background()
+
+	trivia := messages.Trivia{QuestionID: "question-1", Question: "Capital of France?", Category: "geography",
+		Difficulty: "easy", Type: messages.FREE_TEXT, Answer: "Paris", Distractors: []string{"Lyon", "Nice"},
+		Alternates: []string{"Paree"}, Choices: []string{}, Timestamp: "Mon Jan 2 15:04:05 2006",
+		IssuedAt: time.UnixMilli(time.Now().UnixMilli()), PlayerID: "player-1"}
+	if insertErr := sqlModel.Insert(ctx, trivia, time.Hour); insertErr != nil {
+		t.Fatalf("Insert() error = %v", insertErr)
+	}
+
+	want := messages.TriviaTable{QuestionID: trivia.QuestionID, Question: trivia.Question, Category: trivia.Category,
+		Difficulty: trivia.Difficulty, Type: trivia.Type, Answer: trivia.Answer, Distractors: trivia.Distractors,
+		Alternates: trivia.Alternates, Choices: trivia.Choices, Timestamp: trivia.Timestamp,
+		IssuedAt: trivia.IssuedAt, PlayerID: trivia.PlayerID}
+	got, getErr := sqlModel.Get(ctx, trivia.QuestionID)
+	if getErr != nil {
+		t.Fatalf("Get() error = %v", getErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+
+	if _, getErr := sqlModel.Get(ctx, "question-2"); !errors.Is(getErr, ErrItemNotFound) {
+		t.Errorf("Get(question-2) error = %v, want %v", getErr, ErrItemNotFound)
+	}
+
+	// Expired questions are not returned
+	_, updateErr := sqlModel.db.Exec("UPDATE active_questions SET expires_at = ? WHERE question_id = ?",
+		time.Now().Add(-time.Second).Unix(), trivia.QuestionID)
+	if updateErr != nil {
+		t.Fatalf("expire question error = %v", updateErr)
+	}
+	if _, getErr := sqlModel.Get(ctx, trivia.QuestionID); !errors.Is(getErr, ErrItemNotFound) {
+		t.Errorf("Get(expired) error = %v, want %v", getErr, ErrItemNotFound)
+	}
+}
+
+func TestSQLQuestionHistory(t *testing.T) {
+	sqlModel := newTestSQLModel(t, filepath.Join(t.TempDir(), "trivia.db"))
+	ctx := context.Background()
+
+	// The same question ID is issued twice
+	trivia := messages.Trivia{QuestionID: "question-1", Question: "What is 2 + 2?", Category: "math",
+		Type: messages.MULTIPLE_CHOICE, Answer: "4", Choices: []string{"4", "3", "5", "22"}}
+	before := time.Now().UnixMilli()
+	for idx := 0; idx < 2; idx++ {
+		if insertErr := sqlModel.Insert(ctx, trivia, time.Hour); insertErr != nil {
+			t.Fatalf("Insert(%d) error = %v", idx, insertErr)
+		}
+		if deleteErr := sqlModel.Delete(ctx, trivia.QuestionID); deleteErr != nil {
+			t.Fatalf("Delete(%d) error = %v", idx, deleteErr)
+		}
+	}
+	after := time.Now().UnixMilli()
+
+	rows, queryErr := sqlModel.db.Query("SELECT issued_at FROM question_history WHERE question_id = ?",
+		trivia.QuestionID)
+	if queryErr != nil {
+		t.Fatalf("question history error = %v", queryErr)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var issuedAt int64
+		if scanErr := rows.Scan(&issuedAt); scanErr != nil {
+			t.Fatalf("question history scan error = %v", scanErr)
+		}
+		if issuedAt < before || issuedAt > after {
+			t.Errorf("issued at = %d, want unix milliseconds between %d and %d", issuedAt, before, after)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("question history rows = %d, want 2", count)
+	}
+
+	aResponse := messages.AnswerResponse{Response: "4", Correct: true}
+	if recordErr := sqlModel.RecordAnswer(trivia.QuestionID, "player-1", aResponse); recordErr != nil {
+		t.Fatalf("RecordAnswer() error = %v", recordErr)
+	}
+
+	var answeredAt int64
+	var correct bool
+	scanErr := sqlModel.db.QueryRow("SELECT answered_at, correct FROM answer_history WHERE player_id = ?",
+		"player-1").Scan(&answeredAt, &correct)
+	if scanErr != nil {
+		t.Fatalf("answer history error = %v", scanErr)
+	}
+	if !correct || answeredAt < before || answeredAt > time.Now().UnixMilli() {
+		t.Errorf("answer history = %d, %t, want unix milliseconds after %d, true", answeredAt, correct, before)
+	}
+}
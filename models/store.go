@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sflewis2970/trivia-api/common"
+	"github.com/sflewis2970/trivia-api/config"
+	"github.com/sflewis2970/trivia-api/messages"
+	"log/slog"
+	"time"
+)
+
+// Store types selectable in config
+const (
+	REDIS_STORE  string = "redis"
+	MEMORY_STORE string = "memory"
+	SQL_STORE    string = "sql"
+)
+
+// DEFAULT_QUESTION_TTL is used when the question TTL is not configured
+const DEFAULT_QUESTION_TTL time.Duration = 5 * time.Minute
+
+// ErrItemNotFound is returned when a question ID is not in the data store
+var ErrItemNotFound = errors.New("item not found")
+
+// ErrGameNotFound is returned when a game ID is not in the data store
+var ErrGameNotFound = errors.New("game not found")
+
+// ErrGameExists is returned when a game is inserted with the ID of another game
+var ErrGameExists = errors.New("game already exists")
+
+// ErrGameChanged is returned when a game is saved after another request saved it
+var ErrGameChanged = errors.New("game was changed by another request")
+
+// ErrPlayerNotFound is returned when a player ID or API key is not in the data store
+var ErrPlayerNotFound = errors.New("player not found")
+
+// ErrPlayerExists is returned when a player is inserted with the ID or API key of another player
+var ErrPlayerExists = errors.New("player already exists")
+
+// TriviaStore defines the operations a data store for trivia records must support.
+// Operations take the context of the request they are made for.
+type TriviaStore interface {
+	// Ping checks that the data store is reachable
+	Ping(ctx context.Context) error
+
+	// Insert adds a record that expires after ttl
+	Insert(ctx context.Context, trivia messages.Trivia, ttl time.Duration) error
+
+	// Get returns the record for questionID or ErrItemNotFound
+	Get(ctx context.Context, questionID string) (messages.TriviaTable, error)
+
+	// Update replaces a record, keeping its remaining TTL
+	Update(ctx context.Context, trivia messages.Trivia) error
+
+	// Delete removes the record for questionID, or returns ErrItemNotFound when there was no record to remove.
+	// Only one of several requests deleting the same record succeeds.
+	Delete(ctx context.Context, questionID string) error
+}
+
+// GameStore defines the operations a data store for game sessions must support
+type GameStore interface {
+	// InsertGame adds a game that expires after ttl, or returns ErrGameExists when the game ID is already
+	// in the data store
+	InsertGame(game messages.Game, ttl time.Duration) error
+
+	// GetGame returns the game for gameID or ErrGameNotFound
+	GetGame(gameID string) (messages.Game, error)
+
+	// UpdateGame replaces a game, keeping its remaining TTL, and increments its version. The game is only
+	// replaced when its version is the stored version, otherwise ErrGameChanged is returned.
+	UpdateGame(game messages.Game) error
+
+	// DeleteGame removes the game for gameID
+	DeleteGame(gameID string) error
+}
+
+// PlayerStore defines the operations a data store for players must support.
+// Players never expire; API keys are stored as a hash of the key.
+type PlayerStore interface {
+	// InsertPlayer adds a player along with the hash of the API key issued to the player, or returns
+	// ErrPlayerExists when the player ID or API key hash is already in the data store
+	InsertPlayer(player messages.Player, apiKeyHash string) error
+
+	// GetPlayer returns the player for playerID or ErrPlayerNotFound
+	GetPlayer(playerID string) (messages.Player, error)
+
+	// GetPlayerByKey returns the player the API key hash was issued to or ErrPlayerNotFound
+	GetPlayerByKey(apiKeyHash string) (messages.Player, error)
+}
+
+// NewTriviaStore creates the data store selected in config, logging with logger.
+// When no store is configured Redis is used.
+func NewTriviaStore(cfgData *config.CfgData, logger *slog.Logger) (TriviaStore, error) {
+	storeType := cfgData.StoreType
+	if len(storeType) == 0 {
+		storeType = REDIS_STORE
+	}
+
+	logger.Info("Creating trivia store", common.STORE_FIELD, storeType)
+
+	switch storeType {
+	case REDIS_STORE:
+		return NewRedisModel(logger), nil
+	case MEMORY_STORE:
+		return NewMemoryModel(), nil
+	case SQL_STORE:
+		sqlModel, sqlErr := NewSQLModel(cfgData.SQLDriver, cfgData.SQLDSN)
+		if sqlErr != nil {
+			return nil, sqlErr
+		}
+		return sqlModel, nil
+	default:
+		errMsg := fmt.Sprintf("trivia store %s is invalid", storeType)
+		logger.Error(errMsg)
+		return nil, errors.New(errMsg)
+	}
+}
+
+// NewTriviaTable builds the record stored for a trivia question
+func NewTriviaTable(trivia messages.Trivia) messages.TriviaTable {
+	var tTable messages.TriviaTable
+	tTable.QuestionID = trivia.QuestionID
+	tTable.Question = trivia.Question
+	tTable.Category = trivia.Category
+	tTable.Difficulty = trivia.Difficulty
+	tTable.Type = trivia.Type
+	tTable.Answer = trivia.Answer
+	tTable.Distractors = trivia.Distractors
+	tTable.Alternates = trivia.Alternates
+	tTable.Choices = trivia.Choices
+	tTable.Timestamp = trivia.Timestamp
+	tTable.IssuedAt = trivia.IssuedAt
+	tTable.PlayerID = trivia.PlayerID
+
+	return tTable
+}
+
+// unexported functions
+// storeLogger returns the logger of the request in ctx tagged with the store type, or logger when the
+// request has no logger of its own
+func storeLogger(ctx context.Context, logger *slog.Logger, storeType string) *slog.Logger {
+	if requestLogger := common.LoggerFromContext(ctx, nil); requestLogger != nil {
+		return requestLogger.With(common.STORE_FIELD, storeType)
+	}
+
+	return logger
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"github.com/sflewis2970/trivia-api/messages"
+	"testing"
+	"time"
+)
+
+func TestDeleteOnce(t *testing.T) {
+	for storeType, triviaStore := range newTestStores(t) {
+		t.Run(storeType, func(t *testing.T) {
+			ctx := context.Background()
+			trivia := messages.Trivia{QuestionID: "question-1", Question: "What is 2 + 2?", Category: "math",
+				Type: messages.MULTIPLE_CHOICE, Answer: "4", Choices: []string{"4", "3", "5", "22"}}
+			if insertErr := triviaStore.Insert(ctx, trivia, time.Hour); insertErr != nil {
+				t.Fatalf("Insert() error = %v", insertErr)
+			}
+
+			// Only the first delete removes the question
+			if deleteErr := triviaStore.Delete(ctx, trivia.QuestionID); deleteErr != nil {
+				t.Errorf("Delete() error = %v", deleteErr)
+			}
+			if deleteErr := triviaStore.Delete(ctx, trivia.QuestionID); !errors.Is(deleteErr, ErrItemNotFound) {
+				t.Errorf("Delete() again error = %v, want %v", deleteErr, ErrItemNotFound)
+			}
+		})
+	}
+}
@@ -1,57 +1,156 @@
 package models
 
 import (
+	"context"
+	"errors"
 	"github.com/sflewis2970/trivia-api/common"
 	"github.com/sflewis2970/trivia-api/config"
 	"github.com/sflewis2970/trivia-api/messages"
-	"log"
+	"log/slog"
 	"time"
 )
 
 type TriviaModel struct {
-	cfgData    *config.CfgData
-	redisModel *RedisModel
+	cfgData         *config.CfgData
+	logger          *slog.Logger
+	triviaStore     TriviaStore
+	questionTTL     time.Duration
+	answerDeadlines AnswerDeadlines
+	speedScoring    bool
+	messageCatalog  *MessageCatalog
+	answerMatcher   *AnswerMatcher
 }
 
 var triviaModel *TriviaModel
 
-func (tm *TriviaModel) AddQuestion(qRequest messages.Trivia) error {
-	insertErr := tm.redisModel.Insert(qRequest)
+// answersTotal counts answers by category and whether they were correct
+var answersTotal = common.Metrics.NewCounterVec("trivia_answers_total",
+	"Answers checked, by category and result.", "category", "result")
+
+func (tm *TriviaModel) AddQuestion(ctx context.Context, qRequest messages.Trivia) error {
+	logger := common.LoggerFromContext(ctx, tm.logger).With(common.QUESTION_ID_FIELD, qRequest.QuestionID,
+		common.CATEGORY_FIELD, qRequest.Category)
+
+	// Record when the question was issued so the answer can be timed
+	if qRequest.IssuedAt.IsZero() {
+		qRequest.IssuedAt = time.Now()
+	}
+
+	// Questions are kept past their deadline so late answers can be told they were late
+	deadline := tm.answerDeadlines.Deadline(qRequest.Category, qRequest.Difficulty)
+	insertErr := tm.triviaStore.Insert(ctx, qRequest, QuestionTTL(tm.questionTTL, deadline))
 	if insertErr != nil {
-		errMsg := "Error inserting record...: "
-		log.Print(errMsg, insertErr)
+		logger.Error("Error inserting record", common.ERROR_FIELD, insertErr)
 	}
 
 	return insertErr
 }
 
-func (tm *TriviaModel) GetAnswer(aRequest messages.AnswerRequest) (messages.AnswerResponse, error) {
+// GetQuestion returns the stored record for questionID
+func (tm *TriviaModel) GetQuestion(ctx context.Context, questionID string) (messages.TriviaTable, error) {
+	tTable, getErr := tm.triviaStore.Get(ctx, questionID)
+	if getErr != nil {
+		common.LoggerFromContext(ctx, tm.logger).Error("Get record error", common.QUESTION_ID_FIELD, questionID,
+			common.ERROR_FIELD, getErr)
+	}
+
+	return tTable, getErr
+}
+
+func (tm *TriviaModel) GetAnswer(ctx context.Context, aRequest messages.AnswerRequest) (messages.AnswerResponse, error) {
+	logger := common.LoggerFromContext(ctx, tm.logger).With(common.QUESTION_ID_FIELD, aRequest.QuestionID)
+
 	// AnswerResponse
 	var aResponse messages.AnswerResponse
 
-	// Get timestamp right after receiving a valid request
-	common.GetFormattedTime(time.Now(), "Mon Jan 2 15:04:05 2006")
-
-	// Send request to get question from Redis cache
-	_, getErr := tm.redisModel.Get(aRequest.QuestionID)
+	// Send request to get question from the data store
+	tTable, getErr := tm.triviaStore.Get(ctx, aRequest.QuestionID)
 	if getErr != nil {
 		errMsg := "Get record error...: "
-		log.Print(errMsg, getErr)
+		logger.Error("Get record error", common.ERROR_FIELD, getErr)
 		aResponse.Error = errMsg
 		return aResponse, getErr
+	}
+
+	logger = logger.With(common.CATEGORY_FIELD, tTable.Category)
+	if tTable.PlayerID != aRequest.PlayerID {
+		// Questions can only be answered by the player they were issued to
+		logger.Warn("Question was issued to another player")
+		return aResponse, ErrItemNotFound
 	} else {
 		// Build AnswerResponse message
+		aResponse.QuestionID = tTable.QuestionID
+		aResponse.Question = tTable.Question
+		aResponse.Timestamp = tTable.Timestamp
+		aResponse.Category = tTable.Category
+		aResponse.Response = aRequest.Response
+
+		// Responses that cannot answer the question can be corrected and sent again,
+		// so the answer is not given away
+		match, checkErr := checkResponse(tTable, aRequest.Response, tm.answerMatcher)
+		if checkErr != nil {
+			logger.Info("Invalid response for question", "type", QuestionType(tTable))
+			return aResponse, checkErr
+		}
+
+		aResponse.Answer = tTable.Answer
+
+		// Reject answers given after the deadline for the question
+		elapsed := time.Since(tTable.IssuedAt)
+		deadline := tm.answerDeadlines.Deadline(tTable.Category, tTable.Difficulty)
+		if !tTable.IssuedAt.IsZero() && deadline > 0 && elapsed > deadline {
+			logger.Info("Answer received after deadline", "elapsed", elapsed, "deadline", deadline)
+			aResponse.Message = tm.messageCatalog.Message(aRequest.Locales, LATE_ANSWER)
+			tm.recordAnswer(logger, aRequest, aResponse)
+			return aResponse, ErrAnswerTooLate
+		}
+
+		aResponse.Correct = match != NO_MATCH
+		aResponse.Match = match
+
+		if aResponse.Correct {
+			aResponse.Message = tm.messageCatalog.Message(aRequest.Locales, CORRECT_ANSWER)
+		} else {
+			aResponse.Message = tm.messageCatalog.Message(aRequest.Locales, INCORRECT_ANSWER)
+		}
+
+		// Faster correct answers are worth more points
+		if tm.speedScoring && aResponse.Correct {
+			aResponse.Points = SpeedPoints(elapsed, deadline)
+		}
 	}
 
+	logger.Debug("Answer checked", "correct", aResponse.Correct, "match", aResponse.Match)
+	tm.recordAnswer(logger, aRequest, aResponse)
+
 	return aResponse, nil
 }
 
-func (tm *TriviaModel) DeleteQuestion(questionID string) error {
-	// Send request to delete question from Redis cache
-	deleteErr := tm.redisModel.Delete(questionID)
-	if deleteErr != nil {
-		errMsg := "Delete record error...: "
-		log.Print(errMsg, deleteErr)
+// recordAnswer counts the answer and keeps a history of answers when the data store supports it
+func (tm *TriviaModel) recordAnswer(logger *slog.Logger, aRequest messages.AnswerRequest,
+	aResponse messages.AnswerResponse) {
+	if aResponse.Correct {
+		answersTotal.Inc(aResponse.Category, "correct")
+	} else {
+		answersTotal.Inc(aResponse.Category, "incorrect")
+	}
+
+	if answerRecorder, ok := tm.triviaStore.(AnswerRecorder); ok {
+		recordErr := answerRecorder.RecordAnswer(aRequest.QuestionID, aRequest.PlayerID, aResponse)
+		if recordErr != nil {
+			logger.Error("Record answer error", common.ERROR_FIELD, recordErr)
+		}
+	}
+}
+
+// DeleteQuestion removes the question from the data store. ErrItemNotFound is returned when the question
+// was already removed, so of several requests answering the same question only one succeeds.
+func (tm *TriviaModel) DeleteQuestion(ctx context.Context, questionID string) error {
+	// Send request to delete question from the data store
+	deleteErr := tm.triviaStore.Delete(ctx, questionID)
+	if deleteErr != nil && !errors.Is(deleteErr, ErrItemNotFound) {
+		common.LoggerFromContext(ctx, tm.logger).Error("Delete record error", common.QUESTION_ID_FIELD, questionID,
+			common.ERROR_FIELD, deleteErr)
 	}
 
 	return deleteErr
@@ -61,15 +160,39 @@ func (tm *TriviaModel) CfgData() *config.CfgData {
 	return tm.cfgData
 }
 
-func NewTriviaModel() *TriviaModel {
-	log.Print("Creating model object...")
+// NewTriviaModel creates a trivia model that keeps questions in triviaStore, checks free text
+// answers with answerMatcher, answers with messages from messageCatalog and logs with logger
+func NewTriviaModel(triviaStore TriviaStore, messageCatalog *MessageCatalog, answerMatcher *AnswerMatcher,
+	logger *slog.Logger) *TriviaModel {
+	logger.Info("Creating model object")
 	triviaModel := new(TriviaModel)
 
+	// Set logger
+	triviaModel.logger = logger
+
 	// Get config data
 	triviaModel.cfgData = config.NewConfig().LoadCfgData()
 
-	// New model (cacheModel)
-	triviaModel.redisModel = NewRedisModel()
+	// Set data store
+	triviaModel.triviaStore = triviaStore
+
+	// Set message catalog
+	triviaModel.messageCatalog = messageCatalog
+
+	// Set answer matcher
+	triviaModel.answerMatcher = answerMatcher
+
+	// Questions expire when they are not answered in time
+	triviaModel.questionTTL = triviaModel.cfgData.QuestionTTL
+	if triviaModel.questionTTL <= 0 {
+		logger.Warn("Invalid question TTL, using default", "questionttl", triviaModel.cfgData.QuestionTTL)
+		triviaModel.questionTTL = DEFAULT_QUESTION_TTL
+	}
+
+	// Answers are not timed unless deadlines are configured
+	triviaModel.answerDeadlines = NewAnswerDeadlines(triviaModel.cfgData.AnswerDeadline,
+		triviaModel.cfgData.AnswerDeadlines)
+	triviaModel.speedScoring = triviaModel.cfgData.SpeedScoring
 
 	return triviaModel
 }